@@ -6,25 +6,55 @@ import (
 	"time"
 )
 
+// AlertProfileFrequency represent how often CPHalo sends alerts of the alert profile.
+type AlertProfileFrequency string
+
+// Alert profile frequencies supported by CPHalo.
+const (
+	AlertProfileFrequencyInstant   AlertProfileFrequency = "instant"
+	AlertProfileFrequencyEveryHour AlertProfileFrequency = "every_hour"
+	AlertProfileFrequencyEveryDay  AlertProfileFrequency = "every_day"
+	AlertProfileFrequencyEveryWeek AlertProfileFrequency = "every_week"
+)
+
+// AlertProfileRecipient represent a CPHalo alert profile recipient.
+//
+// Either Email or Username is expected to be set.
+type AlertProfileRecipient struct {
+	Email    string `json:"email,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// AlertProfileCriteria represent CPHalo alert profile notification criteria.
+type AlertProfileCriteria struct {
+	Critical    bool     `json:"critical"`
+	NonCritical bool     `json:"non_critical"`
+	Modules     []string `json:"modules,omitempty"`
+	EventTypes  []string `json:"event_types,omitempty"`
+}
+
 // AlertProfile represent a CPHalo alert profile.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#alert-profile-representation
 type AlertProfile struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	GroupID     string    `json:"group_id"`
-	GroupName   string    `json:"group_name"`
-	Description string    `json:"description"`
-	Frequency   string    `json:"frequency"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	CreatedBy   string    `json:"created_by"`
-	UpdatedBy   string    `json:"updated_by"`
-	Shared      bool      `json:"shared"`
+	ID          string                  `json:"id,omitempty"`
+	URL         string                  `json:"url,omitempty"`
+	Name        string                  `json:"name"`
+	GroupID     string                  `json:"group_id,omitempty"`
+	GroupName   string                  `json:"group_name,omitempty"`
+	Description string                  `json:"description"`
+	Frequency   AlertProfileFrequency   `json:"frequency"`
+	Criteria    *AlertProfileCriteria   `json:"criteria,omitempty"`
+	Recipients  []AlertProfileRecipient `json:"recipients,omitempty"`
+	CreatedAt   time.Time               `json:"created_at"`
+	UpdatedAt   time.Time               `json:"updated_at"`
+	CreatedBy   string                  `json:"created_by,omitempty"`
+	UpdatedBy   string                  `json:"updated_by,omitempty"`
+	Shared      bool                    `json:"shared"`
 	UsedBy      []struct {
 		ID   string `json:"id"`
 		Name string `json:"name"`
-	} `json:"used_by"`
+	} `json:"used_by,omitempty"`
 }
 
 // ListAlertProfilesResponse represent a CPHalo alert profile response.
@@ -33,6 +63,20 @@ type ListAlertProfilesResponse struct {
 	AlertProfiles []AlertProfile `json:"alert_profiles"`
 }
 
+// GetAlertProfileResponse represent a get alert profile response.
+type GetAlertProfileResponse struct {
+	AlertProfile AlertProfile `json:"alert_profile"`
+}
+
+// CreateAlertProfileResponse represent a create alert profile response.
+type CreateAlertProfileResponse = GetAlertProfileResponse
+
+// CreateAlertProfileRequest represent a create alert profile request.
+type CreateAlertProfileRequest = GetAlertProfileResponse
+
+// UpdateAlertProfileRequest represent a update alert profile request.
+type UpdateAlertProfileRequest = GetAlertProfileResponse
+
 // ListAlertProfiles lists all defined alert profiles.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#list-alert-profiles
@@ -49,3 +93,71 @@ func (c *Client) ListAlertProfiles() (response ListAlertProfilesResponse, err er
 
 	return response, nil
 }
+
+// GetAlertProfile returns details of the alert profile.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#get-alert-profile-details
+func (c *Client) GetAlertProfile(ID string) (response GetAlertProfileResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "alert_profiles/"+ID, nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// CreateAlertProfile creates a new alert profile.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#create-alert-profile
+func (c *Client) CreateAlertProfile(profile AlertProfile) (response CreateAlertProfileResponse, err error) {
+	req, err := c.newRequest(http.MethodPost, "alert_profiles", nil, CreateAlertProfileRequest{AlertProfile: profile})
+	if err != nil {
+		return response, fmt.Errorf("cannot create new create request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute create request: %v", err)
+	}
+
+	return response, nil
+}
+
+// UpdateAlertProfile updates alert profile.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#update-alert-profile
+func (c *Client) UpdateAlertProfile(profile AlertProfile) error {
+	req, err := c.newRequest(http.MethodPut, "alert_profiles/"+profile.ID, nil, UpdateAlertProfileRequest{AlertProfile: profile})
+	if err != nil {
+		return fmt.Errorf("cannot create new update request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute update request: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteAlertProfile deletes an alert profile.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#delete-alert-profile
+func (c *Client) DeleteAlertProfile(ID string) error {
+	req, err := c.newRequest(http.MethodDelete, "alert_profiles/"+ID, nil, nil)
+	if err != nil {
+		return fmt.Errorf("cannot create new delete request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute delete request: %v", err)
+	}
+
+	return nil
+}
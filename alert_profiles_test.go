@@ -48,3 +48,168 @@ func TestClient_ListAlertProfiles(t *testing.T) {
 		t.Errorf("expected alert profile 0 to have ID %s; got %s", expectedID, resp.AlertProfiles[0].ID)
 	}
 }
+
+func TestClient_GetAlertProfile(t *testing.T) {
+	var err error
+	expectedID := "c25d8d42012211e6a3e669ed2e08b040"
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "alert_profiles_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/alert_profiles/id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.GetAlertProfile("id")
+
+	if err != nil {
+		t.Fatalf("alert profile get failed: %v", err)
+	}
+
+	if resp.AlertProfile.ID != expectedID {
+		t.Errorf("expected alert profile to have ID %s; got %s", expectedID, resp.AlertProfile.ID)
+	}
+
+	if resp.AlertProfile.Frequency != AlertProfileFrequencyInstant {
+		t.Errorf("expected alert profile to have frequency %s; got %s", AlertProfileFrequencyInstant, resp.AlertProfile.Frequency)
+	}
+
+	if resp.AlertProfile.Criteria == nil || !resp.AlertProfile.Criteria.Critical {
+		t.Errorf("expected alert profile to notify about critical issues; got %+v", resp.AlertProfile.Criteria)
+	}
+
+	if len(resp.AlertProfile.Recipients) != 2 {
+		t.Fatalf("expected alert profile to have 2 recipients; got %d", len(resp.AlertProfile.Recipients))
+	}
+
+	if resp.AlertProfile.Recipients[0].Email != "security@example.com" {
+		t.Errorf("expected recipient 0 to have email security@example.com; got %s", resp.AlertProfile.Recipients[0].Email)
+	}
+}
+
+func TestClient_CreateAlertProfile(t *testing.T) {
+	var err error
+	reqBody := CreateAlertProfileRequest{}
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "alert_profiles_get", http.StatusCreated),
+			t,
+			http.MethodPost,
+			"/v1/alert_profiles",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	profile := AlertProfile{
+		Name:       "hello",
+		Frequency:  AlertProfileFrequencyEveryDay,
+		Criteria:   &AlertProfileCriteria{Critical: true, NonCritical: true},
+		Recipients: []AlertProfileRecipient{{Email: "ops@example.com"}},
+	}
+
+	resp, err := client.CreateAlertProfile(profile)
+
+	if err != nil {
+		t.Fatalf("alert profile creating failed: %v", err)
+	}
+
+	expectedID := "c25d8d42012211e6a3e669ed2e08b040"
+	if resp.AlertProfile.ID != expectedID {
+		t.Errorf("expected response to contain ID=%s; got %s", expectedID, resp.AlertProfile.ID)
+	}
+
+	if reqBody.AlertProfile.Frequency != profile.Frequency {
+		t.Errorf("expected request to contain Frequency=%s; got %s", profile.Frequency, reqBody.AlertProfile.Frequency)
+	}
+
+	if reqBody.AlertProfile.Criteria == nil || !reqBody.AlertProfile.Criteria.NonCritical {
+		t.Errorf("expected request to contain criteria %+v; got %+v", profile.Criteria, reqBody.AlertProfile.Criteria)
+	}
+
+	if len(reqBody.AlertProfile.Recipients) != 1 || reqBody.AlertProfile.Recipients[0] != profile.Recipients[0] {
+		t.Errorf("expected request to contain recipients %v; got %v", profile.Recipients, reqBody.AlertProfile.Recipients)
+	}
+}
+
+func TestClient_UpdateAlertProfile(t *testing.T) {
+	var err error
+	reqBody := UpdateAlertProfileRequest{}
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodPut,
+			"/v1/alert_profiles/id",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	profile := AlertProfile{ID: "id", Name: "hello", Frequency: AlertProfileFrequencyEveryWeek}
+
+	err = client.UpdateAlertProfile(profile)
+
+	if err != nil {
+		t.Fatalf("alert profile updating failed: %v", err)
+	}
+
+	if reqBody.AlertProfile.Frequency != profile.Frequency {
+		t.Errorf("expected request to contain Frequency=%s; got %s", profile.Frequency, reqBody.AlertProfile.Frequency)
+	}
+}
+
+func TestClient_DeleteAlertProfile(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodDelete,
+			"/v1/alert_profiles/id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.DeleteAlertProfile("id")
+
+	if err != nil {
+		t.Fatalf("alert profile deletion failed: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// Names of files and directories of a saved snapshot.
+const (
+	ServerGroupsFile       = "server_groups.json"
+	FirewallZonesFile      = "firewall_zones.json"
+	FirewallServicesFile   = "firewall_services.json"
+	FirewallInterfacesFile = "firewall_interfaces.json"
+	FirewallPoliciesDir    = "firewall_policies"
+	AlertProfilesFile      = "alert_profiles.json"
+	CSPAccountsFile        = "csp_accounts.json"
+)
+
+// Save writes the snapshot into the directory as indented JSON files, every
+// firewall policy into its own file in FirewallPoliciesDir.
+//
+// The directory is created when missing. Policy files of a previous snapshot
+// are removed, so the directory can be kept in version control and diffed.
+func (s *Snapshot) Save(dir string) error {
+	s.sort()
+
+	policiesDir := filepath.Join(dir, FirewallPoliciesDir)
+	if err := os.RemoveAll(policiesDir); err != nil {
+		return fmt.Errorf("cannot remove %s: %v", policiesDir, err)
+	}
+	if err := os.MkdirAll(policiesDir, 0o755); err != nil {
+		return fmt.Errorf("cannot create %s: %v", policiesDir, err)
+	}
+
+	files := []struct {
+		name  string
+		value interface{}
+	}{
+		{ServerGroupsFile, s.ServerGroups},
+		{FirewallZonesFile, s.Zones},
+		{FirewallServicesFile, s.Services},
+		{FirewallInterfacesFile, s.Interfaces},
+		{AlertProfilesFile, s.AlertProfiles},
+		{CSPAccountsFile, s.CSPAccounts},
+	}
+
+	for _, f := range files {
+		if err := writeJSON(filepath.Join(dir, f.name), f.value); err != nil {
+			return err
+		}
+	}
+
+	written := map[string]string{}
+	for _, p := range s.Policies {
+		name := policyFileName(p.Name)
+		if other, ok := written[name]; ok {
+			return fmt.Errorf("firewall policies %s and %s have the same file name %s", other, p.Name, name)
+		}
+		written[name] = p.Name
+
+		if err := writeJSON(filepath.Join(policiesDir, name), p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Load reads the snapshot saved in the directory. Missing files are read as
+// empty lists.
+func Load(dir string) (*Snapshot, error) {
+	s := &Snapshot{}
+
+	files := []struct {
+		name  string
+		value interface{}
+	}{
+		{ServerGroupsFile, &s.ServerGroups},
+		{FirewallZonesFile, &s.Zones},
+		{FirewallServicesFile, &s.Services},
+		{FirewallInterfacesFile, &s.Interfaces},
+		{AlertProfilesFile, &s.AlertProfiles},
+		{CSPAccountsFile, &s.CSPAccounts},
+	}
+
+	for _, f := range files {
+		if err := readJSON(filepath.Join(dir, f.name), f.value); err != nil {
+			return nil, err
+		}
+	}
+
+	policies, err := filepath.Glob(filepath.Join(dir, FirewallPoliciesDir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("cannot list firewall policies: %v", err)
+	}
+	sort.Strings(policies)
+
+	for _, path := range policies {
+		var p cphalo.FirewallPolicy
+		if err := readJSON(path, &p); err != nil {
+			return nil, err
+		}
+		s.Policies = append(s.Policies, p)
+	}
+
+	s.sort()
+
+	return s, nil
+}
+
+// policyFileName returns the name of the file of the firewall policy, with
+// characters other than letters, digits, dashes, dots and underscores
+// replaced by underscores.
+func policyFileName(name string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+
+	return sanitized + ".json"
+}
+
+func writeJSON(path string, value interface{}) error {
+	b, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal %s: %v", path, err)
+	}
+
+	if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cannot write %s: %v", path, err)
+	}
+
+	return nil
+}
+
+func readJSON(path string, value interface{}) error {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %v", path, err)
+	}
+
+	if err := json.Unmarshal(b, value); err != nil {
+		return fmt.Errorf("cannot unmarshal %s: %v", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func readTestDir(t *testing.T, dir string) map[string]string {
+	files := map[string]string{}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, _ := filepath.Rel(dir, path)
+		files[rel] = string(b)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("cannot read %s: %v", dir, err)
+	}
+
+	return files
+}
+
+func TestSnapshot_Save(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(dir, FirewallPoliciesDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FirewallPoliciesDir, "deleted.json"), []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := takeTestSnapshot(t)
+	if err := s.Save(dir); err != nil {
+		t.Fatalf("cannot save snapshot: %v", err)
+	}
+
+	files := readTestDir(t, dir)
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+
+	expected := []string{
+		AlertProfilesFile,
+		CSPAccountsFile,
+		FirewallInterfacesFile,
+		filepath.Join(FirewallPoliciesDir, "web___linux.json"),
+		FirewallServicesFile,
+		FirewallZonesFile,
+		ServerGroupsFile,
+	}
+	sort.Strings(names)
+	sort.Strings(expected)
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected files %v; got %v", expected, names)
+	}
+
+	for name, content := range files {
+		if !strings.HasSuffix(content, "\n") || !strings.Contains(content, "\n  ") {
+			t.Errorf("expected indented %s ending with a new line; got %s", name, content)
+		}
+		for _, secret := range []string{"secret", "g-web", "p-web", "svc-ssh", `"id"`, `"url"`, `"created_at"`, `"updated_at"`} {
+			if strings.Contains(content, secret) {
+				t.Errorf("expected %s without %q; got %s", name, secret, content)
+			}
+		}
+	}
+
+	// Another snapshot of the same account is saved identically.
+	again := t.TempDir()
+	if err := takeTestSnapshot(t).Save(again); err != nil {
+		t.Fatalf("cannot save snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(readTestDir(t, again), files) {
+		t.Errorf("expected deterministic files")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	s := takeTestSnapshot(t)
+	if err := s.Save(dir); err != nil {
+		t.Fatalf("cannot save snapshot: %v", err)
+	}
+
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatalf("cannot load snapshot: %v", err)
+	}
+
+	if !reflect.DeepEqual(loaded, s) {
+		t.Errorf("expected loaded snapshot %+v; got %+v", s, loaded)
+	}
+
+	if err := os.Remove(filepath.Join(dir, CSPAccountsFile)); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err = Load(dir); err != nil || len(loaded.CSPAccounts) != 0 {
+		t.Errorf("expected missing file loaded as empty list; got %+v, %v", loaded.CSPAccounts, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ServerGroupsFile), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Errorf("expected error for invalid JSON")
+	}
+}
+
+func TestPolicyFileName(t *testing.T) {
+	if name := policyFileName("web/linux: v2.1"); name != "web_linux__v2.1.json" {
+		t.Errorf("expected web_linux__v2.1.json; got %s", name)
+	}
+}
@@ -0,0 +1,441 @@
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// Report summarizes a restore.
+type Report struct {
+	// Created lists the created objects and attachments, such as
+	// "firewall zone office".
+	Created []string
+	// Skipped lists the objects, which could not be restored, with reasons.
+	Skipped []string
+}
+
+// String returns the summary followed by all created and skipped objects.
+func (r Report) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d created, %d skipped\n", len(r.Created), len(r.Skipped))
+
+	for _, c := range r.Created {
+		fmt.Fprintf(&b, "created %s\n", c)
+	}
+	for _, s := range r.Skipped {
+		fmt.Fprintf(&b, "skipped %s\n", s)
+	}
+
+	return b.String()
+}
+
+// restorer holds IDs of the live objects by their names during a restore.
+type restorer struct {
+	api      API
+	snapshot *Snapshot
+	report   Report
+
+	groups   map[string]cphalo.ServerGroup
+	objects  cphalo.FirewallObjects
+	profiles map[string]string
+	policies map[string]string
+}
+
+// Restore creates the objects of the snapshot missing in the account. Objects
+// are matched by name, server groups by path, and existing objects are never
+// changed.
+//
+// Objects are restored in the order of their dependencies: server groups
+// parents first, firewall zones, services and interfaces, alert profiles,
+// firewall policies with their rules and finally firewall policies and alert
+// profiles of server groups. Policies are assigned only to groups without a
+// policy for the platform. Root server groups and CSP accounts cannot be
+// created and are reported as skipped when missing.
+//
+// Restore stops at the first error, the report lists what was done until then.
+func Restore(api API, s *Snapshot) (Report, error) {
+	r := &restorer{api: api, snapshot: s}
+
+	steps := []func() error{
+		r.restoreServerGroups,
+		r.restoreFirewallObjects,
+		r.restoreAlertProfiles,
+		r.restorePolicies,
+		r.restoreServerGroupAssignments,
+		r.checkCSPAccounts,
+	}
+
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return r.report, err
+		}
+	}
+
+	return r.report, nil
+}
+
+func (r *restorer) created(format string, args ...interface{}) {
+	r.report.Created = append(r.report.Created, fmt.Sprintf(format, args...))
+}
+
+func (r *restorer) skipped(format string, args ...interface{}) {
+	r.report.Skipped = append(r.report.Skipped, fmt.Sprintf(format, args...))
+}
+
+func (r *restorer) restoreServerGroups() error {
+	resp, err := r.api.ListServerGroups()
+	if err != nil {
+		return fmt.Errorf("cannot list server groups: %v", err)
+	}
+	tree := cphalo.NewServerGroupTree(resp.Groups)
+	r.groups = map[string]cphalo.ServerGroup{}
+
+	groups := append([]ServerGroup{}, r.snapshot.ServerGroups...)
+	sort.SliceStable(groups, func(i, j int) bool {
+		return strings.Count(groups[i].Path, "/") < strings.Count(groups[j].Path, "/")
+	})
+
+	for _, g := range groups {
+		if n, ok := tree.Lookup(g.Path); ok {
+			r.groups[g.Path] = n.Group
+			continue
+		}
+
+		i := strings.LastIndex(g.Path, "/")
+		if i < 0 {
+			r.skipped("server group %s: root groups cannot be created", g.Path)
+			continue
+		}
+
+		parent, ok := r.groups[g.Path[:i]]
+		if !ok {
+			r.skipped("server group %s: parent group was not restored", g.Path)
+			continue
+		}
+
+		created, err := r.api.CreateServerGroup(cphalo.ServerGroup{
+			Name:        g.Path[i+1:],
+			Description: g.Description,
+			Tag:         g.Tag,
+			ParentID:    parent.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot create server group %s: %v", g.Path, err)
+		}
+
+		r.groups[g.Path] = created.Group
+		r.created("server group %s", g.Path)
+	}
+
+	return nil
+}
+
+func (r *restorer) restoreFirewallObjects() error {
+	zones, err := r.api.ListFirewallZones()
+	if err != nil {
+		return fmt.Errorf("cannot list firewall zones: %v", err)
+	}
+	r.objects.Zones = zones.Zones
+
+	for _, z := range r.snapshot.Zones {
+		if _, ok := r.objects.Zone(z.Name); ok {
+			continue
+		}
+		if z.System {
+			r.skipped("firewall zone %s: system zones cannot be created", z.Name)
+			continue
+		}
+
+		created, err := r.api.CreateFirewallZone(cphalo.FirewallZone{Name: z.Name, IPAddress: z.IPAddress, Description: z.Description})
+		if err != nil {
+			return fmt.Errorf("cannot create firewall zone %s: %v", z.Name, err)
+		}
+
+		r.objects.Zones = append(r.objects.Zones, created.Zone)
+		r.created("firewall zone %s", z.Name)
+	}
+
+	services, err := r.api.ListFirewallServices()
+	if err != nil {
+		return fmt.Errorf("cannot list firewall services: %v", err)
+	}
+	r.objects.Services = services.Services
+
+	for _, s := range r.snapshot.Services {
+		if _, ok := r.objects.Service(s.Name); ok {
+			continue
+		}
+		if s.System {
+			r.skipped("firewall service %s: system services cannot be created", s.Name)
+			continue
+		}
+
+		created, err := r.api.CreateFirewallService(cphalo.FirewallService{Name: s.Name, Protocol: s.Protocol, Port: s.Port})
+		if err != nil {
+			return fmt.Errorf("cannot create firewall service %s: %v", s.Name, err)
+		}
+
+		r.objects.Services = append(r.objects.Services, created.Service)
+		r.created("firewall service %s", s.Name)
+	}
+
+	interfaces, err := r.api.ListFirewallInterfaces()
+	if err != nil {
+		return fmt.Errorf("cannot list firewall interfaces: %v", err)
+	}
+	r.objects.Interfaces = interfaces.Interfaces
+
+	for _, i := range r.snapshot.Interfaces {
+		if _, ok := r.objects.Interface(i.Name); ok {
+			continue
+		}
+		if i.System {
+			r.skipped("firewall interface %s: system interfaces cannot be created", i.Name)
+			continue
+		}
+
+		created, err := r.api.CreateFirewallInterface(cphalo.FirewallInterface{Name: i.Name})
+		if err != nil {
+			return fmt.Errorf("cannot create firewall interface %s: %v", i.Name, err)
+		}
+
+		r.objects.Interfaces = append(r.objects.Interfaces, created.Interface)
+		r.created("firewall interface %s", i.Name)
+	}
+
+	return nil
+}
+
+func (r *restorer) restoreAlertProfiles() error {
+	resp, err := r.api.ListAlertProfiles()
+	if err != nil {
+		return fmt.Errorf("cannot list alert profiles: %v", err)
+	}
+
+	r.profiles = map[string]string{}
+	for _, p := range resp.AlertProfiles {
+		r.profiles[p.Name] = p.ID
+	}
+
+	for _, p := range r.snapshot.AlertProfiles {
+		if _, ok := r.profiles[p.Name]; ok {
+			continue
+		}
+
+		created, err := r.api.CreateAlertProfile(cphalo.AlertProfile{
+			Name:        p.Name,
+			Description: p.Description,
+			Frequency:   p.Frequency,
+			Criteria:    p.Criteria,
+			Recipients:  p.Recipients,
+			Shared:      p.Shared,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot create alert profile %s: %v", p.Name, err)
+		}
+
+		r.profiles[p.Name] = created.AlertProfile.ID
+		r.created("alert profile %s", p.Name)
+	}
+
+	return nil
+}
+
+func (r *restorer) restorePolicies() error {
+	resp, err := r.api.ListFirewallPolicies()
+	if err != nil {
+		return fmt.Errorf("cannot list firewall policies: %v", err)
+	}
+
+	r.policies = map[string]string{}
+	for _, p := range resp.Policies {
+		r.policies[p.Name] = p.ID
+	}
+
+	for _, p := range r.snapshot.Policies {
+		if _, ok := r.policies[p.Name]; ok {
+			continue
+		}
+
+		tmpl, err := r.policyTemplate(p)
+		if err != nil {
+			return fmt.Errorf("firewall policy %s: %v", p.Name, err)
+		}
+
+		created, err := r.api.CreateFirewallPolicyFromTemplate(tmpl)
+		if err != nil {
+			return fmt.Errorf("cannot create firewall policy %s: %v", p.Name, err)
+		}
+
+		r.policies[p.Name] = created.ID
+		r.created("firewall policy %s", p.Name)
+	}
+
+	return nil
+}
+
+// policyTemplate returns the policy as a template with the live definitions
+// of the zones, services and interfaces its rules refer, so the template does
+// not conflict with objects changed since the snapshot. Server groups are
+// referred by their live IDs looked up by path.
+func (r *restorer) policyTemplate(p cphalo.FirewallPolicy) (cphalo.FirewallPolicyTemplate, error) {
+	tmpl := cphalo.FirewallPolicyTemplate{Policy: p}
+	tmpl.Policy.FirewallRules = nil
+	added := map[string]bool{}
+
+	for i, rule := range p.FirewallRules {
+		for _, st := range []**cphalo.FirewallRuleSourceTarget{&rule.FirewallSource, &rule.FirewallTarget} {
+			if *st == nil || (*st).Kind != cphalo.FirewallEndpointGroup || (*st).Name == cphalo.FirewallAllActiveServers {
+				continue
+			}
+
+			g, ok := r.groups[(*st).GetID()]
+			if !ok {
+				return tmpl, fmt.Errorf("rule %d refers missing server group %s", i+1, (*st).GetID())
+			}
+			*st = &cphalo.FirewallRuleSourceTarget{ID: g.ID, Kind: cphalo.FirewallEndpointGroup}
+		}
+		tmpl.Policy.FirewallRules = append(tmpl.Policy.FirewallRules, rule)
+
+		for _, st := range []*cphalo.FirewallRuleSourceTarget{rule.FirewallSource, rule.FirewallTarget} {
+			if st == nil || st.Kind != cphalo.FirewallEndpointZone || added["zone/"+st.Name] {
+				continue
+			}
+
+			z, ok := r.objects.Zone(st.Name)
+			if !ok {
+				return tmpl, fmt.Errorf("rule %d refers missing zone %s", i+1, st.Name)
+			}
+			tmpl.Objects.Zones = append(tmpl.Objects.Zones, cphalo.FirewallTemplateZone{Name: z.Name, IPAddress: z.IPAddress.IPList(), Description: z.Description})
+			added["zone/"+st.Name] = true
+		}
+
+		if s := rule.FirewallService; s != nil && !added["service/"+s.Name] {
+			live, ok := r.objects.Service(s.Name)
+			if !ok {
+				return tmpl, fmt.Errorf("rule %d refers missing service %s", i+1, s.Name)
+			}
+			tmpl.Objects.Services = append(tmpl.Objects.Services, cphalo.FirewallService{Name: live.Name, Protocol: live.Protocol, Port: live.Port})
+			added["service/"+s.Name] = true
+		}
+
+		if iface := rule.FirewallInterface; iface != nil && !added["interface/"+iface.Name] {
+			live, ok := r.objects.Interface(iface.Name)
+			if !ok {
+				return tmpl, fmt.Errorf("rule %d refers missing interface %s", i+1, iface.Name)
+			}
+			tmpl.Objects.Interfaces = append(tmpl.Objects.Interfaces, cphalo.FirewallInterface{Name: live.Name})
+			added["interface/"+iface.Name] = true
+		}
+	}
+
+	return tmpl, nil
+}
+
+func (r *restorer) restoreServerGroupAssignments() error {
+	for _, g := range r.snapshot.ServerGroups {
+		group, ok := r.groups[g.Path]
+		if !ok {
+			continue
+		}
+
+		if err := r.restoreServerGroupPolicies(g, group.ID); err != nil {
+			return err
+		}
+
+		attached := map[string]bool{}
+		for _, id := range group.AlertProfileIDs {
+			attached[id] = true
+		}
+
+		for _, name := range g.AlertProfiles {
+			id, ok := r.profiles[name]
+			if !ok {
+				r.skipped("alert profile %s of server group %s: alert profile not found", name, g.Path)
+				continue
+			}
+			if attached[id] {
+				continue
+			}
+
+			if err := r.api.AttachServerGroupAlertProfile(group.ID, id); err != nil {
+				return fmt.Errorf("cannot attach alert profile %s to server group %s: %v", name, g.Path, err)
+			}
+			r.created("alert profile %s of server group %s", name, g.Path)
+		}
+	}
+
+	return nil
+}
+
+func (r *restorer) restoreServerGroupPolicies(g ServerGroup, groupID string) error {
+	if g.LinuxFirewallPolicy == "" && g.WindowsFirewallPolicy == "" {
+		return nil
+	}
+
+	resp, err := r.api.GetServerGroupFirewallPolicy(groupID)
+	if err != nil {
+		return fmt.Errorf("cannot get firewall policies of server group %s: %v", g.Path, err)
+	}
+
+	update := resp.Group
+	update.GroupID = groupID
+	var assigned []string
+
+	assign := func(platform, name string, current *cphalo.NullableString) {
+		if name == "" || *current != "" {
+			return
+		}
+
+		id, ok := r.policies[name]
+		if !ok {
+			r.skipped("%s firewall policy %s of server group %s: firewall policy not found", platform, name, g.Path)
+			return
+		}
+
+		*current = cphalo.NullableString(id)
+		assigned = append(assigned, fmt.Sprintf("%s firewall policy %s of server group %s", platform, name, g.Path))
+	}
+
+	assign("linux", g.LinuxFirewallPolicy, &update.LinuxFirewallPolicyID)
+	assign("windows", g.WindowsFirewallPolicy, &update.WindowsFirewallPolicyID)
+
+	if len(assigned) == 0 {
+		return nil
+	}
+
+	if err := r.api.UpdateServerGroupFirewallPolicy(update); err != nil {
+		return fmt.Errorf("cannot update firewall policies of server group %s: %v", g.Path, err)
+	}
+	r.report.Created = append(r.report.Created, assigned...)
+
+	return nil
+}
+
+func (r *restorer) checkCSPAccounts() error {
+	if len(r.snapshot.CSPAccounts) == 0 {
+		return nil
+	}
+
+	resp, err := r.api.ListCSPAccounts()
+	if err != nil {
+		return fmt.Errorf("cannot list CSP accounts: %v", err)
+	}
+
+	existing := map[string]bool{}
+	for _, a := range resp.CSPAccounts {
+		existing[a.CSPAccountType+"/"+a.CSPAccountID] = true
+	}
+
+	for _, a := range r.snapshot.CSPAccounts {
+		if !existing[a.CSPAccountType+"/"+a.CSPAccountID] {
+			r.skipped("CSP account %s %s: credentials are not part of backups", a.CSPAccountType, a.CSPAccountID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package backup
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// newEmptyAccount returns an account with the root group and system objects only.
+func newEmptyAccount() *memAccount {
+	return &memAccount{
+		groups:   []cphalo.ServerGroup{{ID: "root", Name: "example.com"}},
+		assigned: map[string]cphalo.ServerGroupFirewallPolicy{},
+		zones: []cphalo.FirewallZone{
+			{ID: "any", Name: "any", IPAddress: cphalo.MustParseIPSet("0.0.0.0/0"), System: true},
+		},
+		services: []cphalo.FirewallService{
+			{ID: "ssh", Name: "ssh", Protocol: "TCP", Port: "22", System: true},
+		},
+		interfaces: []cphalo.FirewallInterface{
+			{ID: "eth0", Name: "eth0", System: true},
+		},
+	}
+}
+
+func TestRestore(t *testing.T) {
+	account := newEmptyAccount()
+	account.groups = append(account.groups, cphalo.ServerGroup{ID: "other-canary", Name: "canary", ParentID: "root"})
+
+	report, err := Restore(account, takeTestSnapshot(t))
+	if err != nil {
+		t.Fatalf("cannot restore: %v", err)
+	}
+
+	expectedCalls := []string{
+		"create group web in root",
+		"create group canary in new-1",
+		"create zone office",
+		"create service http",
+		"create alert profile oncall",
+		"create alert profile security",
+		"create policy web / linux",
+		"assign new-1 linux=new-7 windows=",
+		"attach new-5 to new-1",
+		"attach new-6 to new-1",
+	}
+	if !reflect.DeepEqual(account.calls, expectedCalls) {
+		t.Errorf("expected calls:\n%s\ngot:\n%s", strings.Join(expectedCalls, "\n"), strings.Join(account.calls, "\n"))
+	}
+
+	tmpl := account.templates[0]
+	if len(tmpl.Objects.Zones) != 1 || tmpl.Objects.Zones[0].Name != "office" ||
+		len(tmpl.Objects.Services) != 2 || len(tmpl.Objects.Interfaces) != 1 {
+		t.Errorf("expected template with objects the rules refer; got %+v", tmpl.Objects)
+	}
+
+	source := tmpl.Policy.FirewallRules[1].FirewallSource
+	if *source != (cphalo.FirewallRuleSourceTarget{ID: "new-2", Kind: cphalo.FirewallEndpointGroup}) {
+		t.Errorf("expected rule from the restored example.com/web/canary group; got %+v", source)
+	}
+
+	if len(report.Created) != 10 {
+		t.Errorf("expected 10 created objects; got %v", report.Created)
+	}
+
+	expectedSkipped := []string{"CSP account aws 123456789012: credentials are not part of backups"}
+	if !reflect.DeepEqual(report.Skipped, expectedSkipped) {
+		t.Errorf("expected skipped %v; got %v", expectedSkipped, report.Skipped)
+	}
+
+	if !strings.HasPrefix(report.String(), "10 created, 1 skipped\ncreated server group example.com/web\n") {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+}
+
+func TestRestore_Existing(t *testing.T) {
+	account := newPopulatedAccount()
+	account.assigned["g-web"] = cphalo.ServerGroupFirewallPolicy{}
+	account.assigned["g-canary"] = cphalo.ServerGroupFirewallPolicy{LinuxFirewallPolicyID: "p-web"}
+
+	s := takeTestSnapshot(t)
+	s.ServerGroups[1].WindowsFirewallPolicy = "web / linux"
+
+	report, err := Restore(account, s)
+	if err != nil {
+		t.Fatalf("cannot restore: %v", err)
+	}
+
+	expectedCalls := []string{"assign g-web linux=p-web windows=p-web"}
+	if !reflect.DeepEqual(account.calls, expectedCalls) {
+		t.Errorf("expected only the missing assignment; got %v", account.calls)
+	}
+
+	if len(report.Created) != 2 || len(report.Skipped) != 0 {
+		t.Errorf("expected 2 assignments; got %+v", report)
+	}
+}
+
+func TestRestore_Skipped(t *testing.T) {
+	account := newEmptyAccount()
+	account.groups = nil
+	account.zones = nil
+
+	s := takeTestSnapshot(t)
+	s.Policies = nil
+	s.CSPAccounts = nil
+
+	report, err := Restore(account, s)
+	if err != nil {
+		t.Fatalf("cannot restore: %v", err)
+	}
+
+	expectedSkipped := []string{
+		"server group example.com: root groups cannot be created",
+		"server group example.com/web: parent group was not restored",
+		"server group example.com/web/canary: parent group was not restored",
+		"firewall zone any: system zones cannot be created",
+	}
+	if !reflect.DeepEqual(report.Skipped, expectedSkipped) {
+		t.Errorf("expected skipped:\n%s\ngot:\n%s", strings.Join(expectedSkipped, "\n"), strings.Join(report.Skipped, "\n"))
+	}
+}
+
+func TestRestore_MissingObject(t *testing.T) {
+	s := takeTestSnapshot(t)
+	s.Services = s.Services[:0]
+
+	account := newEmptyAccount()
+	account.services = nil
+
+	if _, err := Restore(account, s); err == nil || !strings.Contains(err.Error(), "missing service") {
+		t.Errorf("expected error for missing service; got %v", err)
+	}
+}
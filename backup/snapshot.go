@@ -0,0 +1,253 @@
+// Package backup takes snapshots of a CPHalo account configuration, saves
+// them into directories of JSON files and restores them.
+//
+// Snapshots refer to objects by names and server group paths instead of IDs,
+// so they can be restored into the same or another account and compared
+// between points in time.
+package backup
+
+import (
+	"fmt"
+	"sort"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// API lists every kind of object a snapshot covers. Restores additionally
+// create the missing ones, assign policies and attach alert profiles.
+type API interface {
+	ListServerGroups() (cphalo.ListServerGroupsResponse, error)
+	CreateServerGroup(group cphalo.ServerGroup) (cphalo.CreateServerGroupResponse, error)
+	GetServerGroupFirewallPolicy(ID string) (cphalo.GetServerGroupFirewallPolicyResponse, error)
+	UpdateServerGroupFirewallPolicy(group cphalo.ServerGroupFirewallPolicy) error
+	AttachServerGroupAlertProfile(groupID, profileID string) error
+
+	ListFirewallZones() (cphalo.ListFirewallZonesResponse, error)
+	CreateFirewallZone(zone cphalo.FirewallZone) (cphalo.CreateFirewallZoneResponse, error)
+
+	ListFirewallServices() (cphalo.ListFirewallServicesResponse, error)
+	CreateFirewallService(service cphalo.FirewallService) (cphalo.CreateFirewallServiceResponse, error)
+
+	ListFirewallInterfaces() (cphalo.ListFirewallInterfacesResponse, error)
+	CreateFirewallInterface(fwInterface cphalo.FirewallInterface) (cphalo.CreateFirewallInterfaceResponse, error)
+
+	ListFirewallPolicies() (cphalo.ListFirewallPoliciesResponse, error)
+	ListFirewallRules(policyID string) (cphalo.ListFirewallRulesResponse, error)
+	CreateFirewallPolicyFromTemplate(tmpl cphalo.FirewallPolicyTemplate) (cphalo.FirewallPolicy, error)
+
+	ListAlertProfiles() (cphalo.ListAlertProfilesResponse, error)
+	CreateAlertProfile(profile cphalo.AlertProfile) (cphalo.CreateAlertProfileResponse, error)
+
+	ListCSPAccounts() (cphalo.ListCSPAccountsResponse, error)
+}
+
+// Snapshot is the configuration of a CPHalo account without IDs, URLs,
+// timestamps and secrets. Lists are ordered by name, server groups by path.
+type Snapshot struct {
+	ServerGroups  []ServerGroup
+	Zones         []cphalo.FirewallZone
+	Services      []cphalo.FirewallService
+	Interfaces    []cphalo.FirewallInterface
+	Policies      []cphalo.FirewallPolicy
+	AlertProfiles []AlertProfile
+	CSPAccounts   []CSPAccount
+}
+
+// ServerGroup is a server group with its firewall policies and alert profiles
+// referred by name.
+type ServerGroup struct {
+	Path                  string   `json:"path"`
+	Description           string   `json:"description,omitempty"`
+	Tag                   string   `json:"tag,omitempty"`
+	LinuxFirewallPolicy   string   `json:"linux_firewall_policy,omitempty"`
+	WindowsFirewallPolicy string   `json:"windows_firewall_policy,omitempty"`
+	AlertProfiles         []string `json:"alert_profiles,omitempty"`
+}
+
+// AlertProfile is an alert profile without its ID, owning group, timestamps
+// and authors.
+type AlertProfile struct {
+	Name        string                         `json:"name"`
+	Description string                         `json:"description,omitempty"`
+	Frequency   cphalo.AlertProfileFrequency   `json:"frequency"`
+	Criteria    *cphalo.AlertProfileCriteria   `json:"criteria,omitempty"`
+	Recipients  []cphalo.AlertProfileRecipient `json:"recipients,omitempty"`
+	Shared      bool                           `json:"shared"`
+}
+
+// CSPAccount is a CSP account without its ID, secrets and scan results.
+type CSPAccount struct {
+	CSPAccountType     string `json:"csp_account_type"`
+	CSPRegionType      string `json:"csp_region_type,omitempty"`
+	CSPAccountID       string `json:"csp_account_id"`
+	CSPAccountAlias    string `json:"csp_account_alias,omitempty"`
+	AccountDisplayName string `json:"account_display_name,omitempty"`
+	MonitoringState    string `json:"monitoring_state,omitempty"`
+	AzureDirectoryID   string `json:"azure_directory_id,omitempty"`
+	AzureApplicationID string `json:"azure_application_id,omitempty"`
+	AWSRoleArn         string `json:"aws_role_arn,omitempty"`
+}
+
+// Take reads the configuration of the account into a snapshot.
+//
+// Rules of firewall policies refer zones, services and interfaces by name,
+// see cphalo.NewFirewallPolicyTemplate, and server groups by path. Secrets of
+// CSP accounts are left out.
+func Take(api API) (*Snapshot, error) {
+	s := &Snapshot{}
+
+	zones, err := api.ListFirewallZones()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list firewall zones: %v", err)
+	}
+	services, err := api.ListFirewallServices()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list firewall services: %v", err)
+	}
+	interfaces, err := api.ListFirewallInterfaces()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list firewall interfaces: %v", err)
+	}
+	objects := cphalo.FirewallObjects{Zones: zones.Zones, Services: services.Services, Interfaces: interfaces.Interfaces}
+
+	for _, z := range zones.Zones {
+		s.Zones = append(s.Zones, cphalo.FirewallZone{Name: z.Name, IPAddress: z.IPAddress, Description: z.Description, System: z.System})
+	}
+	for _, svc := range services.Services {
+		s.Services = append(s.Services, cphalo.FirewallService{Name: svc.Name, Protocol: svc.Protocol, Port: svc.Port, System: svc.System})
+	}
+	for _, i := range interfaces.Interfaces {
+		s.Interfaces = append(s.Interfaces, cphalo.FirewallInterface{Name: i.Name, System: i.System})
+	}
+
+	groups, err := api.ListServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list server groups: %v", err)
+	}
+	tree := cphalo.NewServerGroupTree(groups.Groups)
+
+	policies, err := api.ListFirewallPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list firewall policies: %v", err)
+	}
+
+	policyNames := map[string]string{}
+	for _, p := range policies.Policies {
+		policyNames[p.ID] = p.Name
+
+		rules, err := api.ListFirewallRules(p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list rules of firewall policy %s: %v", p.Name, err)
+		}
+
+		p.FirewallRules = nil
+		for _, r := range rules.Rules {
+			r.FirewallSource = groupPathEndpoint(r.FirewallSource, tree)
+			r.FirewallTarget = groupPathEndpoint(r.FirewallTarget, tree)
+			p.FirewallRules = append(p.FirewallRules, r)
+		}
+
+		tmpl, err := cphalo.NewFirewallPolicyTemplate(p, objects)
+		if err != nil {
+			return nil, fmt.Errorf("firewall policy %s: %v", p.Name, err)
+		}
+		s.Policies = append(s.Policies, tmpl.Policy)
+	}
+
+	profiles, err := api.ListAlertProfiles()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list alert profiles: %v", err)
+	}
+
+	profileNames := map[string]string{}
+	for _, p := range profiles.AlertProfiles {
+		profileNames[p.ID] = p.Name
+		s.AlertProfiles = append(s.AlertProfiles, AlertProfile{
+			Name:        p.Name,
+			Description: p.Description,
+			Frequency:   p.Frequency,
+			Criteria:    p.Criteria,
+			Recipients:  p.Recipients,
+			Shared:      p.Shared,
+		})
+	}
+
+	err = tree.Walk(func(n *cphalo.ServerGroupNode) error {
+		g := ServerGroup{Path: n.Path(), Description: n.Group.Description, Tag: n.Group.Tag}
+
+		assigned, err := api.GetServerGroupFirewallPolicy(n.Group.ID)
+		if err != nil {
+			return fmt.Errorf("cannot get firewall policies of server group %s: %v", g.Path, err)
+		}
+		g.LinuxFirewallPolicy = policyNames[string(assigned.Group.LinuxFirewallPolicyID)]
+		g.WindowsFirewallPolicy = policyNames[string(assigned.Group.WindowsFirewallPolicyID)]
+
+		for _, id := range n.Group.AlertProfileIDs {
+			if name, ok := profileNames[id]; ok {
+				g.AlertProfiles = append(g.AlertProfiles, name)
+			}
+		}
+		sort.Strings(g.AlertProfiles)
+
+		s.ServerGroups = append(s.ServerGroups, g)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := api.ListCSPAccounts()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list CSP accounts: %v", err)
+	}
+
+	for _, a := range accounts.CSPAccounts {
+		s.CSPAccounts = append(s.CSPAccounts, CSPAccount{
+			CSPAccountType:     a.CSPAccountType,
+			CSPRegionType:      a.CSPRegionType,
+			CSPAccountID:       a.CSPAccountID,
+			CSPAccountAlias:    a.CSPAccountAlias,
+			AccountDisplayName: a.AccountDisplayName,
+			MonitoringState:    a.MonitoringState,
+			AzureDirectoryID:   a.AzureDirectoryID,
+			AzureApplicationID: a.AzureApplicationID,
+			AWSRoleArn:         a.AWSRoleArn,
+		})
+	}
+
+	s.sort()
+
+	return s, nil
+}
+
+// groupPathEndpoint returns the endpoint referring a server group by its path,
+// as names of server groups are unique only among siblings.
+func groupPathEndpoint(st *cphalo.FirewallRuleSourceTarget, tree *cphalo.ServerGroupTree) *cphalo.FirewallRuleSourceTarget {
+	if st == nil || st.Kind != cphalo.FirewallEndpointGroup || st.ID == "" {
+		return st
+	}
+
+	if n, ok := tree.Group(st.ID); ok {
+		named := *st
+		named.Name = n.Path()
+		return &named
+	}
+
+	return st
+}
+
+func (s *Snapshot) sort() {
+	sort.SliceStable(s.ServerGroups, func(i, j int) bool { return s.ServerGroups[i].Path < s.ServerGroups[j].Path })
+	sort.SliceStable(s.Zones, func(i, j int) bool { return s.Zones[i].Name < s.Zones[j].Name })
+	sort.SliceStable(s.Services, func(i, j int) bool { return s.Services[i].Name < s.Services[j].Name })
+	sort.SliceStable(s.Interfaces, func(i, j int) bool { return s.Interfaces[i].Name < s.Interfaces[j].Name })
+	sort.SliceStable(s.Policies, func(i, j int) bool { return s.Policies[i].Name < s.Policies[j].Name })
+	sort.SliceStable(s.AlertProfiles, func(i, j int) bool { return s.AlertProfiles[i].Name < s.AlertProfiles[j].Name })
+	sort.SliceStable(s.CSPAccounts, func(i, j int) bool {
+		a, b := s.CSPAccounts[i], s.CSPAccounts[j]
+		if a.CSPAccountType != b.CSPAccountType {
+			return a.CSPAccountType < b.CSPAccountType
+		}
+		return a.CSPAccountID < b.CSPAccountID
+	})
+}
@@ -0,0 +1,252 @@
+package backup
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// memAccount is an in-memory account recording created objects.
+type memAccount struct {
+	groups     []cphalo.ServerGroup
+	assigned   map[string]cphalo.ServerGroupFirewallPolicy
+	zones      []cphalo.FirewallZone
+	services   []cphalo.FirewallService
+	interfaces []cphalo.FirewallInterface
+	policies   []cphalo.FirewallPolicy
+	profiles   []cphalo.AlertProfile
+	accounts   []cphalo.CSPAccount
+
+	templates []cphalo.FirewallPolicyTemplate
+	calls     []string
+	nextID    int
+}
+
+func (m *memAccount) newID() string {
+	m.nextID++
+	return fmt.Sprintf("new-%d", m.nextID)
+}
+
+func (m *memAccount) ListServerGroups() (cphalo.ListServerGroupsResponse, error) {
+	return cphalo.ListServerGroupsResponse{Count: len(m.groups), Groups: m.groups}, nil
+}
+
+func (m *memAccount) CreateServerGroup(group cphalo.ServerGroup) (cphalo.CreateServerGroupResponse, error) {
+	group.ID = m.newID()
+	m.groups = append(m.groups, group)
+	m.calls = append(m.calls, "create group "+group.Name+" in "+group.ParentID)
+	return cphalo.CreateServerGroupResponse{Group: group}, nil
+}
+
+func (m *memAccount) GetServerGroupFirewallPolicy(ID string) (cphalo.GetServerGroupFirewallPolicyResponse, error) {
+	group := m.assigned[ID]
+	group.GroupID = ID
+	return cphalo.GetServerGroupFirewallPolicyResponse{Group: group}, nil
+}
+
+func (m *memAccount) UpdateServerGroupFirewallPolicy(group cphalo.ServerGroupFirewallPolicy) error {
+	m.assigned[group.GroupID] = group
+	m.calls = append(m.calls, fmt.Sprintf("assign %s linux=%s windows=%s", group.GroupID, group.LinuxFirewallPolicyID, group.WindowsFirewallPolicyID))
+	return nil
+}
+
+func (m *memAccount) AttachServerGroupAlertProfile(groupID, profileID string) error {
+	m.calls = append(m.calls, "attach "+profileID+" to "+groupID)
+	return nil
+}
+
+func (m *memAccount) ListFirewallZones() (cphalo.ListFirewallZonesResponse, error) {
+	return cphalo.ListFirewallZonesResponse{Count: len(m.zones), Zones: m.zones}, nil
+}
+
+func (m *memAccount) CreateFirewallZone(zone cphalo.FirewallZone) (cphalo.CreateFirewallZoneResponse, error) {
+	zone.ID = m.newID()
+	m.zones = append(m.zones, zone)
+	m.calls = append(m.calls, "create zone "+zone.Name)
+	return cphalo.CreateFirewallZoneResponse{Zone: zone}, nil
+}
+
+func (m *memAccount) ListFirewallServices() (cphalo.ListFirewallServicesResponse, error) {
+	return cphalo.ListFirewallServicesResponse{Count: len(m.services), Services: m.services}, nil
+}
+
+func (m *memAccount) CreateFirewallService(service cphalo.FirewallService) (cphalo.CreateFirewallServiceResponse, error) {
+	service.ID = m.newID()
+	m.services = append(m.services, service)
+	m.calls = append(m.calls, "create service "+service.Name)
+	return cphalo.CreateFirewallServiceResponse{Service: service}, nil
+}
+
+func (m *memAccount) ListFirewallInterfaces() (cphalo.ListFirewallInterfacesResponse, error) {
+	return cphalo.ListFirewallInterfacesResponse{Count: len(m.interfaces), Interfaces: m.interfaces}, nil
+}
+
+func (m *memAccount) CreateFirewallInterface(fwInterface cphalo.FirewallInterface) (cphalo.CreateFirewallInterfaceResponse, error) {
+	fwInterface.ID = m.newID()
+	m.interfaces = append(m.interfaces, fwInterface)
+	m.calls = append(m.calls, "create interface "+fwInterface.Name)
+	return cphalo.CreateFirewallInterfaceResponse{Interface: fwInterface}, nil
+}
+
+func (m *memAccount) ListFirewallPolicies() (cphalo.ListFirewallPoliciesResponse, error) {
+	var policies []cphalo.FirewallPolicy
+	for _, p := range m.policies {
+		p.FirewallRules = nil
+		policies = append(policies, p)
+	}
+	return cphalo.ListFirewallPoliciesResponse{Count: len(policies), Policies: policies}, nil
+}
+
+func (m *memAccount) ListFirewallRules(policyID string) (cphalo.ListFirewallRulesResponse, error) {
+	for _, p := range m.policies {
+		if p.ID == policyID {
+			return cphalo.ListFirewallRulesResponse{Count: len(p.FirewallRules), Rules: p.FirewallRules}, nil
+		}
+	}
+	return cphalo.ListFirewallRulesResponse{}, fmt.Errorf("policy %s not found", policyID)
+}
+
+func (m *memAccount) CreateFirewallPolicyFromTemplate(tmpl cphalo.FirewallPolicyTemplate) (cphalo.FirewallPolicy, error) {
+	policy := tmpl.Policy
+	policy.ID = m.newID()
+	m.policies = append(m.policies, policy)
+	m.templates = append(m.templates, tmpl)
+	m.calls = append(m.calls, "create policy "+policy.Name)
+	return policy, nil
+}
+
+func (m *memAccount) ListAlertProfiles() (cphalo.ListAlertProfilesResponse, error) {
+	return cphalo.ListAlertProfilesResponse{Count: len(m.profiles), AlertProfiles: m.profiles}, nil
+}
+
+func (m *memAccount) CreateAlertProfile(profile cphalo.AlertProfile) (cphalo.CreateAlertProfileResponse, error) {
+	profile.ID = m.newID()
+	m.profiles = append(m.profiles, profile)
+	m.calls = append(m.calls, "create alert profile "+profile.Name)
+	return cphalo.CreateAlertProfileResponse{AlertProfile: profile}, nil
+}
+
+func (m *memAccount) ListCSPAccounts() (cphalo.ListCSPAccountsResponse, error) {
+	return cphalo.ListCSPAccountsResponse{Count: len(m.accounts), CSPAccounts: m.accounts}, nil
+}
+
+// newPopulatedAccount returns an account with a group tree, a policy with rules
+// referring all kinds of objects, alert profiles and a CSP account.
+func newPopulatedAccount() *memAccount {
+	return &memAccount{
+		groups: []cphalo.ServerGroup{
+			{ID: "g-web", Name: "web", ParentID: "g-root", Tag: "web", AlertProfileIDs: []string{"ap-2", "ap-1"}},
+			{ID: "g-root", Name: "example.com", URL: "https://api/groups/g-root"},
+			{ID: "g-canary", Name: "canary", ParentID: "g-web", Description: "canary servers"},
+		},
+		assigned: map[string]cphalo.ServerGroupFirewallPolicy{
+			"g-web": {LinuxFirewallPolicyID: "p-web"},
+		},
+		zones: []cphalo.FirewallZone{
+			{ID: "z-office", Name: "office", IPAddress: cphalo.MustParseIPSet("10.0.0.0/8")},
+			{ID: "z-any", Name: "any", IPAddress: cphalo.MustParseIPSet("0.0.0.0/0"), System: true},
+		},
+		services: []cphalo.FirewallService{
+			{ID: "svc-ssh", Name: "ssh", Protocol: "TCP", Port: "22", System: true},
+			{ID: "svc-http", Name: "http", Protocol: "TCP", Port: "80"},
+		},
+		interfaces: []cphalo.FirewallInterface{
+			{ID: "i-eth0", Name: "eth0", System: true},
+		},
+		policies: []cphalo.FirewallPolicy{
+			{
+				ID:       "p-web",
+				URL:      "https://api/firewall_policies/p-web",
+				Name:     "web / linux",
+				Platform: "linux",
+				FirewallRules: []cphalo.FirewallRule{
+					{
+						ID:                "r-2",
+						Chain:             cphalo.FirewallChainInput,
+						Action:            cphalo.FirewallActionAccept,
+						Active:            cphalo.Bool(true),
+						Position:          2,
+						FirewallService:   &cphalo.FirewallService{ID: "svc-http"},
+						FirewallSource:    &cphalo.FirewallRuleSourceTarget{ID: "g-canary", Kind: cphalo.FirewallEndpointGroup},
+						FirewallInterface: &cphalo.FirewallInterface{ID: "i-eth0"},
+					},
+					{
+						ID:              "r-1",
+						Chain:           cphalo.FirewallChainInput,
+						Action:          cphalo.FirewallActionAccept,
+						Active:          cphalo.Bool(true),
+						Position:        1,
+						FirewallService: &cphalo.FirewallService{ID: "svc-ssh"},
+						FirewallSource:  &cphalo.FirewallRuleSourceTarget{ID: "z-office", Kind: cphalo.FirewallEndpointZone},
+					},
+				},
+			},
+		},
+		profiles: []cphalo.AlertProfile{
+			{ID: "ap-2", Name: "security", CreatedBy: "admin", CreatedAt: time.Date(2019, 1, 31, 11, 25, 6, 0, time.UTC), Frequency: cphalo.AlertProfileFrequencyInstant},
+			{ID: "ap-1", Name: "oncall", GroupID: "g-root", Frequency: cphalo.AlertProfileFrequencyEveryHour},
+		},
+		accounts: []cphalo.CSPAccount{
+			{ID: "csp-1", CSPAccountType: "aws", CSPAccountID: "123456789012", AWSRoleArn: "arn:aws:iam::123456789012:role/halo", AWSExternalID: "secret-id", AWSSecret: "secret", ScanStatus: "done"},
+		},
+	}
+}
+
+func takeTestSnapshot(t *testing.T) *Snapshot {
+	s, err := Take(newPopulatedAccount())
+	if err != nil {
+		t.Fatalf("cannot take snapshot: %v", err)
+	}
+
+	return s
+}
+
+func TestTake(t *testing.T) {
+	s := takeTestSnapshot(t)
+
+	expectedGroups := []ServerGroup{
+		{Path: "example.com"},
+		{Path: "example.com/web", Tag: "web", LinuxFirewallPolicy: "web / linux", AlertProfiles: []string{"oncall", "security"}},
+		{Path: "example.com/web/canary", Description: "canary servers"},
+	}
+	if !reflect.DeepEqual(s.ServerGroups, expectedGroups) {
+		t.Errorf("expected server groups %+v; got %+v", expectedGroups, s.ServerGroups)
+	}
+
+	if len(s.Zones) != 2 || s.Zones[0].Name != "any" || s.Zones[0].ID != "" || !s.Zones[0].System {
+		t.Errorf("expected zones ordered by name without IDs; got %+v", s.Zones)
+	}
+
+	if len(s.Policies) != 1 {
+		t.Fatalf("expected 1 policy; got %d", len(s.Policies))
+	}
+
+	p := s.Policies[0]
+	if p.ID != "" || p.URL != "" || len(p.FirewallRules) != 2 {
+		t.Fatalf("expected policy without ID and URL with 2 rules; got %+v", p)
+	}
+
+	first, second := p.FirewallRules[0], p.FirewallRules[1]
+	if first.ID != "" || first.FirewallSource.Name != "office" || first.FirewallSource.ID != "" || first.FirewallService.Name != "ssh" {
+		t.Errorf("expected first rule from office zone to ssh referred by name; got %+v", first)
+	}
+	if second.FirewallSource.Name != "example.com/web/canary" || second.FirewallSource.ID != "" || second.FirewallInterface.Name != "eth0" {
+		t.Errorf("expected second rule from canary group on eth0 referred by path; got %+v", second)
+	}
+
+	expectedProfiles := []AlertProfile{
+		{Name: "oncall", Frequency: cphalo.AlertProfileFrequencyEveryHour},
+		{Name: "security", Frequency: cphalo.AlertProfileFrequencyInstant},
+	}
+	if !reflect.DeepEqual(s.AlertProfiles, expectedProfiles) {
+		t.Errorf("expected alert profiles %+v ordered by name; got %+v", expectedProfiles, s.AlertProfiles)
+	}
+
+	expectedAccount := CSPAccount{CSPAccountType: "aws", CSPAccountID: "123456789012", AWSRoleArn: "arn:aws:iam::123456789012:role/halo"}
+	if len(s.CSPAccounts) != 1 || s.CSPAccounts[0] != expectedAccount {
+		t.Errorf("expected CSP account %+v without secrets; got %+v", expectedAccount, s.CSPAccounts)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 )
 
@@ -30,6 +31,15 @@ type Client struct {
 	maxAuthTries int
 
 	client *http.Client
+
+	hooksMu    sync.Mutex
+	writeHooks []*writeHook
+}
+
+// writeHook is a hook registered by onWrite, compared by its address when
+// unregistered.
+type writeHook struct {
+	fn func(req *http.Request)
 }
 
 // NewClient creates a new CPHalo Client
@@ -84,3 +94,37 @@ func (c *Client) newRequest(method string, rsc string, params map[string]string,
 
 	return req, nil
 }
+
+// onWrite registers the hook called after every request, which is not a GET,
+// executed by the client, and returns the function unregistering it.
+func (c *Client) onWrite(hook func(req *http.Request)) (unregister func()) {
+	h := &writeHook{fn: hook}
+
+	c.hooksMu.Lock()
+	c.writeHooks = append(c.writeHooks, h)
+	c.hooksMu.Unlock()
+
+	return func() {
+		c.hooksMu.Lock()
+		defer c.hooksMu.Unlock()
+
+		for i, registered := range c.writeHooks {
+			if registered == h {
+				c.writeHooks = append(c.writeHooks[:i:i], c.writeHooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// runWriteHooks calls the registered write hooks. The hooks are called
+// without holding the lock, so they can make requests themselves.
+func (c *Client) runWriteHooks(req *http.Request) {
+	c.hooksMu.Lock()
+	hooks := c.writeHooks
+	c.hooksMu.Unlock()
+
+	for _, h := range hooks {
+		h.fn(req)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -41,6 +42,43 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
+func TestClient_onWrite(t *testing.T) {
+	client := NewClient("", "", nil)
+	req, _ := http.NewRequest(http.MethodPut, DefaultBaseURL, nil)
+
+	var calls []string
+	unregisterFirst := client.onWrite(func(*http.Request) { calls = append(calls, "first") })
+	unregisterSecond := client.onWrite(func(*http.Request) { calls = append(calls, "second") })
+
+	unregisterFirst()
+	unregisterFirst()
+	client.runWriteHooks(req)
+
+	if strings.Join(calls, ",") != "second" {
+		t.Errorf("expected only the second hook to be called; got %v", calls)
+	}
+
+	unregisterSecond()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			client.onWrite(func(*http.Request) {})()
+		}()
+		go func() {
+			defer wg.Done()
+			client.runWriteHooks(req)
+		}()
+	}
+	wg.Wait()
+
+	if len(client.writeHooks) != 0 {
+		t.Errorf("expected hooks registered concurrently to be unregistered; got %d hooks", len(client.writeHooks))
+	}
+}
+
 func authTestHandler(next http.Handler, t *testing.T) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasPrefix(r.RequestURI, "/oauth") {
@@ -116,3 +154,38 @@ func requestValidatorTestHandler(next http.Handler, t *testing.T, expectedMethod
 
 	return authTestHandler(http.HandlerFunc(fn), t)
 }
+
+func routesTestHandler(t *testing.T, routes map[string]http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + r.RequestURI
+		next, ok := routes[route]
+
+		if !ok {
+			t.Errorf("unexpected request %s", route)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return authTestHandler(http.HandlerFunc(fn), t)
+}
+
+func bodyRecorderTestHandler(t *testing.T, next http.Handler, body interface{}) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+
+		if err != nil {
+			t.Fatalf("reading body failed: %v", err)
+		}
+
+		if err := json.Unmarshal(b, body); err != nil {
+			t.Fatalf("unmarshalling body failed: %v", err)
+		}
+
+		next.ServeHTTP(w, r)
+	}
+
+	return http.HandlerFunc(fn)
+}
@@ -0,0 +1,220 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ConfigurationCheck represent a single check of a CPHalo configuration rule.
+//
+// Only the fields relevant for the given Type are expected to be set.
+type ConfigurationCheck struct {
+	Type                   string `json:"type,omitempty"`
+	Description            string `json:"description,omitempty"`
+	Path                   string `json:"path,omitempty"`
+	File                   string `json:"file,omitempty"`
+	ConfigKey              string `json:"config_key,omitempty"`
+	ConfigKeyValueDelim    string `json:"config_key_value_delimiter,omitempty"`
+	Value                  string `json:"value,omitempty"`
+	Pattern                string `json:"pattern,omitempty"`
+	Process                string `json:"process,omitempty"`
+	Package                string `json:"package,omitempty"`
+	Version                string `json:"version,omitempty"`
+	Owner                  string `json:"owner,omitempty"`
+	Group                  string `json:"group,omitempty"`
+	Permissions            string `json:"permissions,omitempty"`
+	Port                   string `json:"port,omitempty"`
+	Protocol               string `json:"protocol,omitempty"`
+	Presence               bool   `json:"presence,omitempty"`
+	CaseInsensitive        bool   `json:"case_insensitive,omitempty"`
+	AllowMultipleMatches   bool   `json:"allow_multiple_matches,omitempty"`
+	RecursiveSearch        bool   `json:"recursive,omitempty"`
+	IgnoreMissingTargets   bool   `json:"ignore_missing_targets,omitempty"`
+	RequireExactValueMatch bool   `json:"require_exact_value_match,omitempty"`
+}
+
+// ConfigurationRule represent a CPHalo configuration rule.
+type ConfigurationRule struct {
+	Name        string               `json:"name,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Comment     string               `json:"comment,omitempty"`
+	Active      bool                 `json:"active"`
+	Critical    bool                 `json:"critical"`
+	Tags        []string             `json:"tags,omitempty"`
+	Checks      []ConfigurationCheck `json:"checks,omitempty"`
+}
+
+// ConfigurationPolicy represent a CPHalo configuration (CSM) policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#configuration-policies
+type ConfigurationPolicy struct {
+	ID          string              `json:"id,omitempty"`
+	URL         string              `json:"url,omitempty"`
+	Name        string              `json:"name,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Platform    string              `json:"platform,omitempty"`
+	Shared      StringableBool      `json:"shared"`
+	Template    bool                `json:"template,omitempty"`
+	Rules       []ConfigurationRule `json:"rules,omitempty"`
+}
+
+// ListConfigurationPoliciesResponse represent a list of configuration policies response.
+type ListConfigurationPoliciesResponse struct {
+	Count    int                   `json:"count"`
+	Policies []ConfigurationPolicy `json:"policies"`
+}
+
+// GetConfigurationPolicyResponse represent a get configuration policy response.
+type GetConfigurationPolicyResponse struct {
+	Policy ConfigurationPolicy `json:"policy"`
+}
+
+// CreateConfigurationPolicyResponse represent a create configuration policy response.
+type CreateConfigurationPolicyResponse = GetConfigurationPolicyResponse
+
+// CreateConfigurationPolicyRequest represent a create configuration policy request.
+type CreateConfigurationPolicyRequest = GetConfigurationPolicyResponse
+
+// UpdateConfigurationPolicyRequest represent a update configuration policy request.
+type UpdateConfigurationPolicyRequest = GetConfigurationPolicyResponse
+
+// ImportConfigurationPolicyRequest represent an import configuration policy request.
+type ImportConfigurationPolicyRequest struct {
+	Policy struct {
+		PolicyFile string `json:"policy_file"`
+	} `json:"policy"`
+}
+
+// ImportConfigurationPolicyResponse represent an import configuration policy response.
+type ImportConfigurationPolicyResponse = GetConfigurationPolicyResponse
+
+// ListConfigurationPolicies lists all configuration policies.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#list-configuration-policies
+func (c *Client) ListConfigurationPolicies() (response ListConfigurationPoliciesResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "policies", nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute request: %v", err)
+	}
+
+	return response, nil
+}
+
+// GetConfigurationPolicy returns details of the configuration policy including its rules.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#get-configuration-policy-details
+func (c *Client) GetConfigurationPolicy(ID string) (response GetConfigurationPolicyResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "policies/"+ID, nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// CreateConfigurationPolicy creates a new configuration policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#create-configuration-policy
+func (c *Client) CreateConfigurationPolicy(policy ConfigurationPolicy) (response CreateConfigurationPolicyResponse, err error) {
+	req, err := c.newRequest(http.MethodPost, "policies", nil, CreateConfigurationPolicyRequest{Policy: policy})
+	if err != nil {
+		return response, fmt.Errorf("cannot create new create request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute create request: %v", err)
+	}
+
+	return response, nil
+}
+
+// UpdateConfigurationPolicy updates configuration policy.
+//
+// The rules of the policy are replaced by the provided ones.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#update-configuration-policy
+func (c *Client) UpdateConfigurationPolicy(policy ConfigurationPolicy) error {
+	req, err := c.newRequest(http.MethodPut, "policies/"+policy.ID, nil, UpdateConfigurationPolicyRequest{Policy: policy})
+	if err != nil {
+		return fmt.Errorf("cannot create new update request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute update request: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteConfigurationPolicy deletes a configuration policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#delete-configuration-policy
+func (c *Client) DeleteConfigurationPolicy(ID string) error {
+	req, err := c.newRequest(http.MethodDelete, "policies/"+ID, nil, nil)
+	if err != nil {
+		return fmt.Errorf("cannot create new delete request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute delete request: %v", err)
+	}
+
+	return nil
+}
+
+// ExportConfigurationPolicy returns the configuration policy in CPHalo JSON export format.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#export-configuration-policy
+func (c *Client) ExportConfigurationPolicy(ID string) (json.RawMessage, error) {
+	var response json.RawMessage
+
+	req, err := c.newRequest(http.MethodGet, "policies/"+ID+"/export", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create new export request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return nil, fmt.Errorf("cannot execute export request: %v", err)
+	}
+
+	return response, nil
+}
+
+// ImportConfigurationPolicy creates a new configuration policy from CPHalo JSON export format.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#import-configuration-policy
+func (c *Client) ImportConfigurationPolicy(data []byte) (response ImportConfigurationPolicyResponse, err error) {
+	if !json.Valid(data) {
+		return response, fmt.Errorf("policy file is not a valid JSON")
+	}
+
+	reqData := ImportConfigurationPolicyRequest{}
+	reqData.Policy.PolicyFile = string(data)
+
+	req, err := c.newRequest(http.MethodPost, "policies/import", nil, reqData)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new import request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute import request: %v", err)
+	}
+
+	return response, nil
+}
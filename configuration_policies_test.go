@@ -0,0 +1,312 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClient_ListConfigurationPolicies(t *testing.T) {
+	var err error
+	expectedResults := 2
+	expectedID := "0a4ce7a4ee5b11e89d0d15ea2eff2521"
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "configuration_policies_list", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/policies",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.ListConfigurationPolicies()
+
+	if err != nil {
+		t.Fatalf("configuration policies list failed: %v", err)
+	}
+
+	if resp.Count != expectedResults {
+		t.Errorf("expected count to be %d; got %d", expectedResults, resp.Count)
+	}
+
+	if len(resp.Policies) != expectedResults {
+		t.Errorf("expected %d configuration policies; got %d", expectedResults, len(resp.Policies))
+	}
+
+	if resp.Policies[0].ID != expectedID {
+		t.Errorf("expected configuration policy 0 to have ID %s; got %s", expectedID, resp.Policies[0].ID)
+	}
+}
+
+func TestClient_GetConfigurationPolicy(t *testing.T) {
+	var err error
+	expectedID := "0a4ce7a4ee5b11e89d0d15ea2eff2521"
+	expectedChecks := 2
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "configuration_policies_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/policies/id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.GetConfigurationPolicy("id")
+
+	if err != nil {
+		t.Fatalf("configuration policy get failed: %v", err)
+	}
+
+	if resp.Policy.ID != expectedID {
+		t.Errorf("expected configuration policy to have ID %s; got %s", expectedID, resp.Policy.ID)
+	}
+
+	if len(resp.Policy.Rules) != 1 {
+		t.Fatalf("expected configuration policy to have 1 rule; got %d", len(resp.Policy.Rules))
+	}
+
+	rule := resp.Policy.Rules[0]
+
+	if !rule.Critical {
+		t.Errorf("expected rule to be critical")
+	}
+
+	if len(rule.Checks) != expectedChecks {
+		t.Fatalf("expected rule to have %d checks; got %d", expectedChecks, len(rule.Checks))
+	}
+
+	if rule.Checks[0].ConfigKey != "PermitRootLogin" {
+		t.Errorf("expected check 0 to have ConfigKey=PermitRootLogin; got %s", rule.Checks[0].ConfigKey)
+	}
+
+	if !rule.Checks[1].Presence {
+		t.Errorf("expected check 1 to require presence")
+	}
+}
+
+func TestClient_CreateConfigurationPolicy(t *testing.T) {
+	var err error
+	reqBody := CreateConfigurationPolicyRequest{}
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "configuration_policies_get", http.StatusCreated),
+			t,
+			http.MethodPost,
+			"/v1/policies",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	policy := ConfigurationPolicy{
+		Name:     "hello",
+		Platform: "linux",
+		Rules: []ConfigurationRule{
+			{
+				Name:   "sshd running",
+				Active: true,
+				Checks: []ConfigurationCheck{{Type: "process_presence", Process: "sshd", Presence: true}},
+			},
+		},
+	}
+
+	resp, err := client.CreateConfigurationPolicy(policy)
+
+	if err != nil {
+		t.Fatalf("configuration policy creating failed: %v", err)
+	}
+
+	expectedID := "0a4ce7a4ee5b11e89d0d15ea2eff2521"
+	if resp.Policy.ID != expectedID {
+		t.Errorf("expected response to contain ID=%s; got %s", expectedID, resp.Policy.ID)
+	}
+
+	if reqBody.Policy.Name != policy.Name {
+		t.Errorf("expected request to contain Name=%s; got %s", policy.Name, reqBody.Policy.Name)
+	}
+
+	if len(reqBody.Policy.Rules) != 1 || len(reqBody.Policy.Rules[0].Checks) != 1 {
+		t.Fatalf("expected request to contain 1 rule with 1 check; got %+v", reqBody.Policy.Rules)
+	}
+
+	if reqBody.Policy.Rules[0].Checks[0].Process != "sshd" {
+		t.Errorf("expected request check to contain Process=sshd; got %s", reqBody.Policy.Rules[0].Checks[0].Process)
+	}
+}
+
+func TestClient_UpdateConfigurationPolicy(t *testing.T) {
+	var err error
+	reqBody := UpdateConfigurationPolicyRequest{}
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodPut,
+			"/v1/policies/id",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	policy := ConfigurationPolicy{
+		ID:          "id",
+		Name:        "hello",
+		Description: "hai",
+	}
+
+	err = client.UpdateConfigurationPolicy(policy)
+
+	if err != nil {
+		t.Fatalf("configuration policy updating failed: %v", err)
+	}
+
+	if reqBody.Policy.Description != policy.Description {
+		t.Errorf("expected request to contain Description=%s; got %s", policy.Description, reqBody.Policy.Description)
+	}
+}
+
+func TestClient_DeleteConfigurationPolicy(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodDelete,
+			"/v1/policies/id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.DeleteConfigurationPolicy("id")
+
+	if err != nil {
+		t.Fatalf("configuration policy deletion failed: %v", err)
+	}
+}
+
+func TestClient_ExportConfigurationPolicy(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "configuration_policies_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/policies/id/export",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	data, err := client.ExportConfigurationPolicy("id")
+
+	if err != nil {
+		t.Fatalf("configuration policy export failed: %v", err)
+	}
+
+	exported := GetConfigurationPolicyResponse{}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("cannot unmarshal exported policy: %v", err)
+	}
+
+	if exported.Policy.Name != "CIS Ubuntu 18.04" {
+		t.Errorf("expected exported policy to have name %s; got %s", "CIS Ubuntu 18.04", exported.Policy.Name)
+	}
+}
+
+func TestClient_ImportConfigurationPolicy(t *testing.T) {
+	var err error
+	reqBody := ImportConfigurationPolicyRequest{}
+	policyFile := `{"policy":{"name":"imported","platform":"linux"}}`
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "configuration_policies_get", http.StatusCreated),
+			t,
+			http.MethodPost,
+			"/v1/policies/import",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	if _, err = client.ImportConfigurationPolicy([]byte("{invalid")); err == nil {
+		t.Errorf("expected import of invalid JSON to fail")
+	}
+
+	resp, err := client.ImportConfigurationPolicy([]byte(policyFile))
+
+	if err != nil {
+		t.Fatalf("configuration policy import failed: %v", err)
+	}
+
+	if reqBody.Policy.PolicyFile != policyFile {
+		t.Errorf("expected request to contain policy file %s; got %s", policyFile, reqBody.Policy.PolicyFile)
+	}
+
+	expectedID := "0a4ce7a4ee5b11e89d0d15ea2eff2521"
+	if resp.Policy.ID != expectedID {
+		t.Errorf("expected response to contain ID=%s; got %s", expectedID, resp.Policy.ID)
+	}
+}
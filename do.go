@@ -9,7 +9,14 @@ import (
 
 // Do executes the request CPHalo API.
 func (c *Client) Do(req *http.Request, v interface{}) (*http.Response, error) {
-	return c.doTries(req, v, 0)
+	resp, err := c.doTries(req, v, 0)
+
+	// a failed write may have still changed something
+	if req.Method != http.MethodGet {
+		c.runWriteHooks(req)
+	}
+
+	return resp, err
 }
 
 func (c *Client) doTries(req *http.Request, v interface{}, tries int) (*http.Response, error) {
@@ -0,0 +1,184 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// EffectiveFirewallEndpoint represent an expanded firewall rule source or target.
+type EffectiveFirewallEndpoint struct {
+	Kind        FirewallEndpointKind `json:"type"`
+	ID          string               `json:"id,omitempty"`
+	Name        string               `json:"name,omitempty"`
+	IPAddresses []string             `json:"ip_addresses,omitempty"`
+}
+
+// EffectiveFirewallRule represent a firewall rule with all references expanded.
+//
+// Nil Source, Target and empty Protocol, Ports and Interface mean any.
+type EffectiveFirewallRule struct {
+	ID               string                     `json:"id"`
+	Position         int                        `json:"position"`
+	Chain            FirewallChain              `json:"chain"`
+	Action           FirewallAction             `json:"action"`
+	ConnectionStates string                     `json:"connection_states,omitempty"`
+	Source           *EffectiveFirewallEndpoint `json:"source,omitempty"`
+	Target           *EffectiveFirewallEndpoint `json:"target,omitempty"`
+	Service          string                     `json:"service,omitempty"`
+	Protocol         string                     `json:"protocol,omitempty"`
+	Ports            []string                   `json:"ports,omitempty"`
+	Interface        string                     `json:"interface,omitempty"`
+	Log              bool                       `json:"log,omitempty"`
+	LogPrefix        string                     `json:"log_prefix,omitempty"`
+	Comment          string                     `json:"comment,omitempty"`
+}
+
+// ServerEffectiveFirewall represent the firewall policy applied to a server.
+//
+// Group is the group the policy is assigned to, which is the server group itself
+// or its ancestor, when Inherited is set. Group and Policy are empty when no
+// policy applies.
+type ServerEffectiveFirewall struct {
+	Server    Server                  `json:"server"`
+	Group     ServerGroup             `json:"group"`
+	Inherited bool                    `json:"inherited"`
+	Policy    FirewallPolicy          `json:"policy"`
+	Rules     []EffectiveFirewallRule `json:"rules"`
+}
+
+// EffectiveFirewall returns the firewall policy applied to the server with its
+// active rules and all zones, services and interfaces expanded.
+//
+// Groups without a firewall policy for the server platform inherit it from the
+// nearest ancestor group with one.
+func (c *Client) EffectiveFirewall(serverID string) (response ServerEffectiveFirewall, err error) {
+	server, err := c.GetServer(serverID)
+	if err != nil {
+		return response, fmt.Errorf("cannot get server %s: %v", serverID, err)
+	}
+	response.Server = server.Server
+	response.Rules = []EffectiveFirewallRule{}
+
+	windows := strings.EqualFold(server.Server.Platform, "windows")
+	visited := map[string]bool{}
+
+	var policyID string
+
+	for groupID := server.Server.GroupID; groupID != "" && policyID == ""; {
+		if visited[groupID] {
+			return response, fmt.Errorf("server group %s is its own ancestor", groupID)
+		}
+		visited[groupID] = true
+
+		group, err := c.getServerGroupWithFirewallPolicy(groupID)
+		if err != nil {
+			return response, fmt.Errorf("cannot get server group %s: %v", groupID, err)
+		}
+
+		policyID = string(group.Group.LinuxFirewallPolicyID)
+		if windows {
+			policyID = string(group.Group.WindowsFirewallPolicyID)
+		}
+
+		if policyID != "" {
+			response.Group = group.Group.ServerGroup
+			response.Inherited = groupID != server.Server.GroupID
+		}
+
+		groupID = group.Group.ParentID
+	}
+
+	if policyID == "" {
+		return response, nil
+	}
+
+	policy, err := c.GetFirewallPolicy(policyID)
+	if err != nil {
+		return response, fmt.Errorf("cannot get firewall policy %s: %v", policyID, err)
+	}
+
+	rules, err := c.ListFirewallRules(policyID)
+	if err != nil {
+		return response, fmt.Errorf("cannot list rules of firewall policy %s: %v", policyID, err)
+	}
+
+	objects, err := c.ListFirewallObjects()
+	if err != nil {
+		return response, err
+	}
+
+	response.Policy = policy.Policy
+	response.Policy.FirewallRules = rules.Rules
+
+	for _, r := range resolveFirewallRules(response.Policy, objects) {
+		if !r.rule.GetActive() {
+			continue
+		}
+
+		if len(r.missing) > 0 {
+			return response, fmt.Errorf("rule %d references missing %s", r.position, strings.Join(r.missing, ", "))
+		}
+
+		response.Rules = append(response.Rules, EffectiveFirewallRule{
+			ID:               r.rule.ID,
+			Position:         r.position,
+			Chain:            r.rule.Chain,
+			Action:           r.rule.Action,
+			ConnectionStates: r.rule.ConnectionStates,
+			Source:           effectiveEndpoint(r.rule.FirewallSource, objects),
+			Target:           effectiveEndpoint(r.rule.FirewallTarget, objects),
+			Service:          r.service,
+			Protocol:         r.protocol,
+			Ports:            formatPorts(r.ports),
+			Interface:        r.iface,
+			Log:              r.rule.GetLog(),
+			LogPrefix:        r.rule.LogPrefix,
+			Comment:          r.rule.Comment,
+		})
+	}
+
+	return response, nil
+}
+
+// serverGroupWithFirewallPolicy represent a server group with its firewall
+// policies, both of which CPHalo returns from the same request.
+type serverGroupWithFirewallPolicy struct {
+	Group struct {
+		ServerGroup
+		ServerGroupFirewallPolicy
+	} `json:"group"`
+}
+
+func (c *Client) getServerGroupWithFirewallPolicy(ID string) (response serverGroupWithFirewallPolicy, err error) {
+	req, err := c.newRequest(http.MethodGet, "groups/"+ID, nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+func effectiveEndpoint(st *FirewallRuleSourceTarget, objects FirewallObjects) *EffectiveFirewallEndpoint {
+	if st == nil {
+		return nil
+	}
+
+	e := &EffectiveFirewallEndpoint{Kind: st.Kind, ID: st.ID, Name: st.Name}
+
+	if st.Kind == FirewallEndpointZone {
+		if zone, ok := objects.Zone(st.GetID()); ok {
+			e.Name = zone.Name
+			e.IPAddresses = zone.IPAddress.zoneList()
+		} else if st.IPAddress != "" {
+			e.IPAddresses = strings.Split(st.IPAddress, ",")
+		}
+	}
+
+	return e
+}
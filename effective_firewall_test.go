@@ -0,0 +1,140 @@
+package cphalo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClient_EffectiveFirewall(t *testing.T) {
+	var err error
+
+	groupRequests := map[string]int{}
+	countGroupRequests := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			groupRequests[r.RequestURI]++
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/servers/3958fe0c08e511e7819335b35e8ba368":                          jsonResponseTestHandler(t, "server_get", http.StatusOK),
+			"GET /v1/groups/b864e2204f72012f94c9404038a8a7aa":                           countGroupRequests(jsonResponseTestHandler(t, "server_groups_get_child", http.StatusOK)),
+			"GET /v1/groups/0962bfa087bc01323e360670140ec224":                           countGroupRequests(jsonResponseTestHandler(t, "server_groups_get", http.StatusOK)),
+			"GET /v1/firewall_policies/291f1420abe7013295e406ba9a9c633c":                jsonResponseTestHandler(t, "firewall_policies_get", http.StatusOK),
+			"GET /v1/firewall_policies/291f1420abe7013295e406ba9a9c633c/firewall_rules": jsonResponseTestHandler(t, "firewall_rules_list_expanded", http.StatusOK),
+			"GET /v1/firewall_zones":                                                    jsonResponseTestHandler(t, "firewall_zones_list", http.StatusOK),
+			"GET /v1/firewall_services":                                                 jsonResponseTestHandler(t, "firewall_services_list", http.StatusOK),
+			"GET /v1/firewall_interfaces":                                               jsonResponseTestHandler(t, "firewall_interfaces_list", http.StatusOK),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.EffectiveFirewall("3958fe0c08e511e7819335b35e8ba368")
+
+	if err != nil {
+		t.Fatalf("effective firewall failed: %v", err)
+	}
+
+	if resp.Group.ID != "0962bfa087bc01323e360670140ec224" || !resp.Inherited {
+		t.Errorf("expected policy inherited from group 0962bfa087bc01323e360670140ec224; got %s (inherited %v)", resp.Group.ID, resp.Inherited)
+	}
+
+	for uri, count := range groupRequests {
+		if count != 1 {
+			t.Errorf("expected %s to be requested once; got %d", uri, count)
+		}
+	}
+
+	if resp.Policy.Name != "thebestfirewall" {
+		t.Errorf("expected policy thebestfirewall; got %s", resp.Policy.Name)
+	}
+
+	if len(resp.Rules) != 1 {
+		t.Fatalf("expected 1 active rule; got %d", len(resp.Rules))
+	}
+
+	rule := resp.Rules[0]
+
+	if rule.Source == nil || rule.Source.Name != "any" || len(rule.Source.IPAddresses) != 1 || rule.Source.IPAddresses[0] != "0.0.0.0/0" {
+		t.Errorf("expected source zone any with 0.0.0.0/0; got %+v", rule.Source)
+	}
+
+	if rule.Service != "ssh" || rule.Protocol != "TCP" || len(rule.Ports) != 1 || rule.Ports[0] != "22" {
+		t.Errorf("expected service ssh on TCP/22; got %s %s %v", rule.Service, rule.Protocol, rule.Ports)
+	}
+
+	if rule.Interface != "eth1" {
+		t.Errorf("expected interface eth1; got %s", rule.Interface)
+	}
+}
+
+func TestClient_EffectiveFirewall_NoPolicy(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/servers/3958fe0c08e511e7819335b35e8ba368": jsonResponseTestHandler(t, "server_get", http.StatusOK),
+			"GET /v1/groups/b864e2204f72012f94c9404038a8a7aa":  jsonResponseTestHandler(t, "server_groups_get_child", http.StatusOK),
+			"GET /v1/groups/0962bfa087bc01323e360670140ec224":  jsonResponseTestHandler(t, "server_groups_get_without_firewall_policies", http.StatusOK),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.EffectiveFirewall("3958fe0c08e511e7819335b35e8ba368")
+
+	if err != nil {
+		t.Fatalf("effective firewall failed: %v", err)
+	}
+
+	if resp.Group.ID != "" || resp.Inherited {
+		t.Errorf("expected no group without policy; got %s (inherited %v)", resp.Group.ID, resp.Inherited)
+	}
+
+	if resp.Policy.ID != "" || len(resp.Rules) != 0 {
+		t.Errorf("expected no policy and rules; got %s with %d rules", resp.Policy.ID, len(resp.Rules))
+	}
+}
+
+func TestClient_EffectiveFirewall_GroupCycle(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/servers/3958fe0c08e511e7819335b35e8ba368": jsonResponseTestHandler(t, "server_get", http.StatusOK),
+			"GET /v1/groups/b864e2204f72012f94c9404038a8a7aa":  jsonResponseTestHandler(t, "server_groups_get_child", http.StatusOK),
+			"GET /v1/groups/0962bfa087bc01323e360670140ec224":  jsonResponseTestHandler(t, "server_groups_get_child", http.StatusOK),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	// the child group fixture points to 0962bfa087bc01323e360670140ec224 as its parent, so the walk loops
+	_, err = client.EffectiveFirewall("3958fe0c08e511e7819335b35e8ba368")
+
+	if err == nil {
+		t.Errorf("expected group cycle error")
+	}
+}
@@ -0,0 +1,156 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// FIMBaselineStatusApproved is the status of an approved FIM baseline.
+const FIMBaselineStatusApproved = "Approved"
+
+// FIMBaseline represent a CPHalo FIM baseline of a FIM policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#fim-baselines
+type FIMBaseline struct {
+	ID             string    `json:"id,omitempty"`
+	URL            string    `json:"url,omitempty"`
+	ServerID       string    `json:"server_id,omitempty"`
+	ServerHostname string    `json:"server_hostname,omitempty"`
+	Status         string    `json:"status,omitempty"`
+	Comment        string    `json:"comment,omitempty"`
+	Expires        int       `json:"expires,omitempty"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+	EffectiveAt    time.Time `json:"effective_at,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at,omitempty"`
+}
+
+// ListFIMBaselinesResponse represent a list of FIM baselines response.
+type ListFIMBaselinesResponse struct {
+	Count     int           `json:"count"`
+	Baselines []FIMBaseline `json:"baselines"`
+}
+
+// GetFIMBaselineResponse represent a get FIM baseline response.
+type GetFIMBaselineResponse struct {
+	Baseline FIMBaseline `json:"baseline"`
+}
+
+// RequestFIMBaselineResponse represent a request FIM baseline response.
+type RequestFIMBaselineResponse = GetFIMBaselineResponse
+
+// RequestFIMBaselineRequest represent a request for a new FIM baseline.
+type RequestFIMBaselineRequest struct {
+	Baseline struct {
+		ServerID string `json:"server_id"`
+		Expires  int    `json:"expires,omitempty"`
+		Comment  string `json:"comment,omitempty"`
+	} `json:"baseline"`
+}
+
+// ApproveFIMBaselineRequest represent a request for FIM baseline approval.
+type ApproveFIMBaselineRequest struct {
+	Baseline struct {
+		Status string `json:"status"`
+	} `json:"baseline"`
+}
+
+// ListFIMBaselines lists all baselines of the FIM policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#list-fim-baselines
+func (c *Client) ListFIMBaselines(policyID string) (response ListFIMBaselinesResponse, err error) {
+	url := fmt.Sprintf("fim_policies/%s/baselines", policyID)
+	req, err := c.newRequest(http.MethodGet, url, nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute request: %v", err)
+	}
+
+	return response, nil
+}
+
+// GetFIMBaseline returns details of the FIM baseline.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#get-fim-baseline-details
+func (c *Client) GetFIMBaseline(policyID, baselineID string) (response GetFIMBaselineResponse, err error) {
+	url := fmt.Sprintf("fim_policies/%s/baselines/%s", policyID, baselineID)
+	req, err := c.newRequest(http.MethodGet, url, nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// RequestFIMBaseline requests a new FIM baseline of the policy to be taken from the given server.
+//
+// The expires is a number of days after which the baseline expires, zero means never.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#request-fim-baseline
+func (c *Client) RequestFIMBaseline(policyID, serverID string, expires int, comment string) (response RequestFIMBaselineResponse, err error) {
+	reqData := RequestFIMBaselineRequest{}
+	reqData.Baseline.ServerID = serverID
+	reqData.Baseline.Expires = expires
+	reqData.Baseline.Comment = comment
+
+	url := fmt.Sprintf("fim_policies/%s/baselines", policyID)
+	req, err := c.newRequest(http.MethodPost, url, nil, reqData)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new baseline request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute baseline request: %v", err)
+	}
+
+	return response, nil
+}
+
+// ApproveFIMBaseline approves the FIM baseline, so it is used for monitoring.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#approve-fim-baseline
+func (c *Client) ApproveFIMBaseline(policyID, baselineID string) error {
+	reqData := ApproveFIMBaselineRequest{}
+	reqData.Baseline.Status = FIMBaselineStatusApproved
+
+	url := fmt.Sprintf("fim_policies/%s/baselines/%s", policyID, baselineID)
+	req, err := c.newRequest(http.MethodPut, url, nil, reqData)
+	if err != nil {
+		return fmt.Errorf("cannot create new approve request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute approve request: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteFIMBaseline deletes a FIM baseline.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#delete-fim-baseline
+func (c *Client) DeleteFIMBaseline(policyID, baselineID string) error {
+	url := fmt.Sprintf("fim_policies/%s/baselines/%s", policyID, baselineID)
+	req, err := c.newRequest(http.MethodDelete, url, nil, nil)
+	if err != nil {
+		return fmt.Errorf("cannot create new delete request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute delete request: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package cphalo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClient_ListFIMBaselines(t *testing.T) {
+	var err error
+	expectedResults := 2
+	expectedID := "c4b2a0a0ee5b11e89d0d15ea2eff2521"
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "fim_baselines_list", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/fim_policies/123/baselines",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.ListFIMBaselines("123")
+
+	if err != nil {
+		t.Fatalf("FIM baselines list failed: %v", err)
+	}
+
+	if resp.Count != expectedResults {
+		t.Errorf("expected count to be %d; got %d", expectedResults, resp.Count)
+	}
+
+	if len(resp.Baselines) != expectedResults {
+		t.Errorf("expected %d FIM baselines; got %d", expectedResults, len(resp.Baselines))
+	}
+
+	if resp.Baselines[0].ID != expectedID {
+		t.Errorf("expected FIM baseline 0 to have ID %s; got %s", expectedID, resp.Baselines[0].ID)
+	}
+}
+
+func TestClient_GetFIMBaseline(t *testing.T) {
+	var err error
+	expectedID := "d5c3b1b1ee5b11e89d0d15ea2eff2521"
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "fim_baselines_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/fim_policies/123/baselines/id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.GetFIMBaseline("123", "id")
+
+	if err != nil {
+		t.Fatalf("FIM baseline get failed: %v", err)
+	}
+
+	if resp.Baseline.ID != expectedID {
+		t.Errorf("expected FIM baseline to have ID %s; got %s", expectedID, resp.Baseline.ID)
+	}
+
+	if resp.Baseline.Expires != 90 {
+		t.Errorf("expected FIM baseline to expire in 90 days; got %d", resp.Baseline.Expires)
+	}
+}
+
+func TestClient_RequestFIMBaseline(t *testing.T) {
+	var err error
+	reqBody := RequestFIMBaselineRequest{}
+	serverID := "3958fe0c08e511e7819335b35e8ba368"
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "fim_baselines_get", http.StatusAccepted),
+			t,
+			http.MethodPost,
+			"/v1/fim_policies/123/baselines",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.RequestFIMBaseline("123", serverID, 90, "golden image")
+
+	if err != nil {
+		t.Fatalf("FIM baseline request failed: %v", err)
+	}
+
+	if resp.Baseline.Status != "Pending" {
+		t.Errorf("expected FIM baseline to be Pending; got %s", resp.Baseline.Status)
+	}
+
+	if reqBody.Baseline.ServerID != serverID {
+		t.Errorf("expected request to contain ServerID=%s; got %s", serverID, reqBody.Baseline.ServerID)
+	}
+
+	if reqBody.Baseline.Expires != 90 {
+		t.Errorf("expected request to contain Expires=90; got %d", reqBody.Baseline.Expires)
+	}
+
+	if reqBody.Baseline.Comment != "golden image" {
+		t.Errorf("expected request to contain Comment=golden image; got %s", reqBody.Baseline.Comment)
+	}
+}
+
+func TestClient_ApproveFIMBaseline(t *testing.T) {
+	var err error
+	reqBody := ApproveFIMBaselineRequest{}
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodPut,
+			"/v1/fim_policies/123/baselines/id",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.ApproveFIMBaseline("123", "id")
+
+	if err != nil {
+		t.Fatalf("FIM baseline approval failed: %v", err)
+	}
+
+	if reqBody.Baseline.Status != FIMBaselineStatusApproved {
+		t.Errorf("expected request to contain Status=%s; got %s", FIMBaselineStatusApproved, reqBody.Baseline.Status)
+	}
+}
+
+func TestClient_DeleteFIMBaseline(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodDelete,
+			"/v1/fim_policies/123/baselines/id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.DeleteFIMBaseline("123", "id")
+
+	if err != nil {
+		t.Fatalf("FIM baseline deletion failed: %v", err)
+	}
+}
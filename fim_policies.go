@@ -0,0 +1,140 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// FIMPattern represent a CPHalo FIM inclusion or exclusion pattern.
+type FIMPattern struct {
+	Pattern string `json:"pattern"`
+}
+
+// FIMRule represent a CPHalo FIM rule, which monitors a single target.
+type FIMRule struct {
+	Target      string       `json:"target"`
+	Description string       `json:"description,omitempty"`
+	Recurse     bool         `json:"recurse"`
+	Critical    bool         `json:"critical"`
+	Alert       bool         `json:"alert"`
+	Inclusions  []FIMPattern `json:"inclusions,omitempty"`
+	Exclusions  []FIMPattern `json:"exclusions,omitempty"`
+}
+
+// FIMPolicy represent a CPHalo file integrity monitoring policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#fim-policies
+type FIMPolicy struct {
+	ID          string         `json:"id,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Platform    string         `json:"platform,omitempty"`
+	Shared      StringableBool `json:"shared"`
+	Rules       []FIMRule      `json:"rules,omitempty"`
+}
+
+// ListFIMPoliciesResponse represent a list of FIM policies response.
+type ListFIMPoliciesResponse struct {
+	Count    int         `json:"count"`
+	Policies []FIMPolicy `json:"fim_policies"`
+}
+
+// GetFIMPolicyResponse represent a get FIM policy response.
+type GetFIMPolicyResponse struct {
+	Policy FIMPolicy `json:"fim_policy"`
+}
+
+// CreateFIMPolicyResponse represent a create FIM policy response.
+type CreateFIMPolicyResponse = GetFIMPolicyResponse
+
+// CreateFIMPolicyRequest represent a create FIM policy request.
+type CreateFIMPolicyRequest = GetFIMPolicyResponse
+
+// UpdateFIMPolicyRequest represent a update FIM policy request.
+type UpdateFIMPolicyRequest = GetFIMPolicyResponse
+
+// ListFIMPolicies lists all FIM policies.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#list-fim-policies
+func (c *Client) ListFIMPolicies() (response ListFIMPoliciesResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "fim_policies", nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute request: %v", err)
+	}
+
+	return response, nil
+}
+
+// GetFIMPolicy returns details of the FIM policy including its rules.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#get-fim-policy-details
+func (c *Client) GetFIMPolicy(ID string) (response GetFIMPolicyResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "fim_policies/"+ID, nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// CreateFIMPolicy creates a new FIM policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#create-fim-policy
+func (c *Client) CreateFIMPolicy(policy FIMPolicy) (response CreateFIMPolicyResponse, err error) {
+	req, err := c.newRequest(http.MethodPost, "fim_policies", nil, CreateFIMPolicyRequest{Policy: policy})
+	if err != nil {
+		return response, fmt.Errorf("cannot create new create request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute create request: %v", err)
+	}
+
+	return response, nil
+}
+
+// UpdateFIMPolicy updates FIM policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#update-fim-policy
+func (c *Client) UpdateFIMPolicy(policy FIMPolicy) error {
+	req, err := c.newRequest(http.MethodPut, "fim_policies/"+policy.ID, nil, UpdateFIMPolicyRequest{Policy: policy})
+	if err != nil {
+		return fmt.Errorf("cannot create new update request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute update request: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteFIMPolicy deletes a FIM policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#delete-fim-policy
+func (c *Client) DeleteFIMPolicy(ID string) error {
+	req, err := c.newRequest(http.MethodDelete, "fim_policies/"+ID, nil, nil)
+	if err != nil {
+		return fmt.Errorf("cannot create new delete request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute delete request: %v", err)
+	}
+
+	return nil
+}
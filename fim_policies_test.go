@@ -0,0 +1,227 @@
+package cphalo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClient_ListFIMPolicies(t *testing.T) {
+	var err error
+	expectedResults := 2
+	expectedID := "ab50af103f8211e5a1e7193241212e97"
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "fim_policies_list", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/fim_policies",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.ListFIMPolicies()
+
+	if err != nil {
+		t.Fatalf("FIM policies list failed: %v", err)
+	}
+
+	if resp.Count != expectedResults {
+		t.Errorf("expected count to be %d; got %d", expectedResults, resp.Count)
+	}
+
+	if len(resp.Policies) != expectedResults {
+		t.Errorf("expected %d FIM policies; got %d", expectedResults, len(resp.Policies))
+	}
+
+	if resp.Policies[0].ID != expectedID {
+		t.Errorf("expected FIM policy 0 to have ID %s; got %s", expectedID, resp.Policies[0].ID)
+	}
+
+	if resp.Policies[1].Shared {
+		t.Errorf("expected FIM policy 1 not to be shared")
+	}
+}
+
+func TestClient_GetFIMPolicy(t *testing.T) {
+	var err error
+	expectedID := "ab50af103f8211e5a1e7193241212e97"
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "fim_policies_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/fim_policies/id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.GetFIMPolicy("id")
+
+	if err != nil {
+		t.Fatalf("FIM policy get failed: %v", err)
+	}
+
+	if resp.Policy.ID != expectedID {
+		t.Errorf("expected FIM policy to have ID %s; got %s", expectedID, resp.Policy.ID)
+	}
+
+	if len(resp.Policy.Rules) != 1 {
+		t.Fatalf("expected FIM policy to have 1 rule; got %d", len(resp.Policy.Rules))
+	}
+
+	rule := resp.Policy.Rules[0]
+
+	if rule.Target != "/etc" {
+		t.Errorf("expected rule target /etc; got %s", rule.Target)
+	}
+
+	if len(rule.Inclusions) != 1 || rule.Inclusions[0].Pattern != "*.conf" {
+		t.Errorf("expected rule to include *.conf; got %v", rule.Inclusions)
+	}
+
+	if len(rule.Exclusions) != 2 {
+		t.Errorf("expected rule to have 2 exclusions; got %d", len(rule.Exclusions))
+	}
+}
+
+func TestClient_CreateFIMPolicy(t *testing.T) {
+	var err error
+	reqBody := CreateFIMPolicyRequest{}
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "fim_policies_get", http.StatusCreated),
+			t,
+			http.MethodPost,
+			"/v1/fim_policies",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	policy := FIMPolicy{
+		Name:     "hello",
+		Platform: "linux",
+		Rules: []FIMRule{
+			{
+				Target:     "/usr/bin",
+				Recurse:    true,
+				Alert:      true,
+				Exclusions: []FIMPattern{{Pattern: "*.tmp"}},
+			},
+		},
+	}
+
+	resp, err := client.CreateFIMPolicy(policy)
+
+	if err != nil {
+		t.Fatalf("FIM policy creating failed: %v", err)
+	}
+
+	expectedID := "ab50af103f8211e5a1e7193241212e97"
+	if resp.Policy.ID != expectedID {
+		t.Errorf("expected response to contain ID=%s; got %s", expectedID, resp.Policy.ID)
+	}
+
+	if len(reqBody.Policy.Rules) != 1 {
+		t.Fatalf("expected request to contain 1 rule; got %d", len(reqBody.Policy.Rules))
+	}
+
+	if reqBody.Policy.Rules[0].Target != "/usr/bin" {
+		t.Errorf("expected request rule to contain Target=/usr/bin; got %s", reqBody.Policy.Rules[0].Target)
+	}
+
+	if len(reqBody.Policy.Rules[0].Exclusions) != 1 {
+		t.Errorf("expected request rule to contain 1 exclusion; got %d", len(reqBody.Policy.Rules[0].Exclusions))
+	}
+}
+
+func TestClient_UpdateFIMPolicy(t *testing.T) {
+	var err error
+	reqBody := UpdateFIMPolicyRequest{}
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodPut,
+			"/v1/fim_policies/id",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	policy := FIMPolicy{ID: "id", Name: "hello", Description: "hai"}
+
+	err = client.UpdateFIMPolicy(policy)
+
+	if err != nil {
+		t.Fatalf("FIM policy updating failed: %v", err)
+	}
+
+	if reqBody.Policy.Description != policy.Description {
+		t.Errorf("expected request to contain Description=%s; got %s", policy.Description, reqBody.Policy.Description)
+	}
+}
+
+func TestClient_DeleteFIMPolicy(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodDelete,
+			"/v1/fim_policies/id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.DeleteFIMPolicy("id")
+
+	if err != nil {
+		t.Fatalf("FIM policy deletion failed: %v", err)
+	}
+}
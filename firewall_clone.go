@@ -0,0 +1,431 @@
+package cphalo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FirewallPolicyTemplate represent a firewall policy with the zones, services
+// and interfaces its rules use, independent of any CPHalo account.
+//
+// Rules refer to zones, services, interfaces and server groups by name, so the
+// same template can be created in any account. Texts can contain Go templates,
+// see Render.
+type FirewallPolicyTemplate struct {
+	Policy  FirewallPolicy          `json:"policy"`
+	Objects FirewallTemplateObjects `json:"objects"`
+}
+
+// FirewallTemplateObjects represent zones, services and interfaces of
+// a firewall policy template.
+type FirewallTemplateObjects struct {
+	Zones      []FirewallTemplateZone
+	Services   []FirewallService
+	Interfaces []FirewallInterface
+}
+
+// FirewallTemplateZone represent a firewall zone of a template. Its addresses
+// are kept as written, as they can contain Go templates, and are parsed when
+// the zone is created.
+type FirewallTemplateZone struct {
+	Name        string `json:"name,omitempty"`
+	IPAddress   IPList `json:"ip_address,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Zone returns the firewall zone with the parsed addresses.
+func (z FirewallTemplateZone) Zone() (zone FirewallZone, err error) {
+	ips, err := z.IPAddress.Parse()
+	if err != nil {
+		return zone, fmt.Errorf("zone %s: %v", z.Name, err)
+	}
+
+	return FirewallZone{Name: z.Name, IPAddress: ips, Description: z.Description}, nil
+}
+
+// newFirewallTemplateObjects returns the objects for a template, addresses of
+// zones are written in the firewall zone format.
+func newFirewallTemplateObjects(objects FirewallObjects) (tmpl FirewallTemplateObjects) {
+	for _, z := range objects.Zones {
+		tmpl.Zones = append(tmpl.Zones, FirewallTemplateZone{Name: z.Name, IPAddress: z.IPAddress.zoneList(), Description: z.Description})
+	}
+	tmpl.Services = objects.Services
+	tmpl.Interfaces = objects.Interfaces
+
+	return tmpl
+}
+
+// FirewallPolicyTemplate returns the firewall policy with its rules as a template.
+func (c *Client) FirewallPolicyTemplate(policyID string) (tmpl FirewallPolicyTemplate, err error) {
+	policy, err := c.GetFirewallPolicy(policyID)
+	if err != nil {
+		return tmpl, fmt.Errorf("cannot get firewall policy %s: %v", policyID, err)
+	}
+
+	rules, err := c.ListFirewallRules(policyID)
+	if err != nil {
+		return tmpl, fmt.Errorf("cannot list rules of firewall policy %s: %v", policyID, err)
+	}
+
+	objects, err := c.ListFirewallObjects()
+	if err != nil {
+		return tmpl, err
+	}
+
+	policy.Policy.FirewallRules = rules.Rules
+
+	return NewFirewallPolicyTemplate(policy.Policy, objects)
+}
+
+// NewFirewallPolicyTemplate returns the policy as a template with the objects
+// its rules use. IDs and URLs are left out and rules refer objects by name.
+//
+// Server groups are referred by name when known, users and user groups other
+// than all GhostPorts users stay specific to the account.
+func NewFirewallPolicyTemplate(policy FirewallPolicy, objects FirewallObjects) (tmpl FirewallPolicyTemplate, err error) {
+	tmpl.Policy = FirewallPolicy{
+		Name:                  policy.Name,
+		Platform:              policy.Platform,
+		Description:           policy.Description,
+		Shared:                policy.Shared,
+		IgnoreForwardingRules: policy.IgnoreForwardingRules,
+	}
+
+	added := map[string]bool{}
+
+	for i, r := range orderedFirewallRules(policy) {
+		r.ID = ""
+		r.URL = ""
+		r.Position = i + 1
+
+		if r.ConnectionStates == "ANY" {
+			r.ConnectionStates = ""
+		}
+
+		if r.FirewallSource, err = tmpl.endpoint(r.FirewallSource, objects, added); err != nil {
+			return tmpl, fmt.Errorf("rule %d source: %v", i+1, err)
+		}
+
+		if r.FirewallTarget, err = tmpl.endpoint(r.FirewallTarget, objects, added); err != nil {
+			return tmpl, fmt.Errorf("rule %d target: %v", i+1, err)
+		}
+
+		if r.FirewallService != nil {
+			s, ok := objects.Service(refName(r.FirewallService.ID, r.FirewallService.Name))
+			if !ok {
+				return tmpl, fmt.Errorf("rule %d references missing service %s", i+1, refName(r.FirewallService.Name, r.FirewallService.ID))
+			}
+
+			if !added["service/"+s.Name] {
+				tmpl.Objects.Services = append(tmpl.Objects.Services, FirewallService{Name: s.Name, Protocol: s.Protocol, Port: s.Port})
+				added["service/"+s.Name] = true
+			}
+			r.FirewallService = &FirewallService{Name: s.Name}
+		}
+
+		if r.FirewallInterface != nil {
+			iface, ok := objects.Interface(refName(r.FirewallInterface.ID, r.FirewallInterface.Name))
+			if !ok {
+				return tmpl, fmt.Errorf("rule %d references missing interface %s", i+1, refName(r.FirewallInterface.Name, r.FirewallInterface.ID))
+			}
+
+			if !added["interface/"+iface.Name] {
+				tmpl.Objects.Interfaces = append(tmpl.Objects.Interfaces, FirewallInterface{Name: iface.Name})
+				added["interface/"+iface.Name] = true
+			}
+			r.FirewallInterface = &FirewallInterface{Name: iface.Name}
+		}
+
+		tmpl.Policy.FirewallRules = append(tmpl.Policy.FirewallRules, r)
+	}
+
+	return tmpl, nil
+}
+
+func (t *FirewallPolicyTemplate) endpoint(st *FirewallRuleSourceTarget, objects FirewallObjects, added map[string]bool) (*FirewallRuleSourceTarget, error) {
+	if st == nil {
+		return nil, nil
+	}
+
+	switch st.Kind {
+	case FirewallEndpointZone:
+		zone, ok := objects.Zone(st.GetID())
+		if !ok {
+			if st.Name == "" || st.IPAddress == "" {
+				return nil, fmt.Errorf("missing zone %s", st.GetID())
+			}
+
+			ips, err := parseZoneAddresses(st.IPAddress)
+			if err != nil {
+				return nil, fmt.Errorf("zone %s: %v", st.Name, err)
+			}
+			zone = FirewallZone{Name: st.Name, IPAddress: ips}
+		}
+
+		if !added["zone/"+zone.Name] {
+			t.Objects.Zones = append(t.Objects.Zones, FirewallTemplateZone{Name: zone.Name, IPAddress: zone.IPAddress.zoneList(), Description: zone.Description})
+			added["zone/"+zone.Name] = true
+		}
+
+		return &FirewallRuleSourceTarget{Name: zone.Name, Kind: st.Kind}, nil
+	case FirewallEndpointGroup:
+		if st.GetID() == FirewallAllActiveServers {
+			return &FirewallRuleSourceTarget{Name: FirewallAllActiveServers, Kind: st.Kind}, nil
+		}
+		if st.Name != "" {
+			return &FirewallRuleSourceTarget{Name: st.Name, Kind: st.Kind}, nil
+		}
+	case FirewallEndpointUserGroup:
+		if st.GetID() == FirewallAllGhostPortsUsers {
+			return &FirewallRuleSourceTarget{Name: FirewallAllGhostPortsUsers, Kind: st.Kind}, nil
+		}
+	}
+
+	return &FirewallRuleSourceTarget{ID: st.ID, Name: st.Name, Kind: st.Kind}, nil
+}
+
+// CloneFirewallPolicy copies the firewall policy with all its rules under
+// the new name.
+//
+// The copy is created by the target client, which may be authenticated to
+// another CPHalo account, or by the same client when target is nil. Zones,
+// services, interfaces and server groups are remapped by name, see
+// CreateFirewallPolicyFromTemplate. Rules referring server groups without
+// a name, users or user groups by ID cannot be copied to another client.
+func (c *Client) CloneFirewallPolicy(policyID, name string, target *Client) (policy FirewallPolicy, err error) {
+	tmpl, err := c.FirewallPolicyTemplate(policyID)
+	if err != nil {
+		return policy, err
+	}
+
+	tmpl.Policy.Name = name
+
+	if target == nil {
+		target = c
+	}
+
+	if target != c {
+		for i, r := range tmpl.Policy.FirewallRules {
+			for _, st := range []*FirewallRuleSourceTarget{r.FirewallSource, r.FirewallTarget} {
+				if st != nil && st.Kind != FirewallEndpointZone && st.ID != "" {
+					return policy, fmt.Errorf("rule %d refers %s %s specific to the source account", i+1, st.Kind, st.ID)
+				}
+			}
+		}
+	}
+
+	return target.CreateFirewallPolicyFromTemplate(tmpl)
+}
+
+// CreateFirewallPolicyFromTemplate creates the firewall policy of the template
+// with its rules.
+//
+// Zones, services and interfaces with the same name and definition are reused,
+// the missing ones are created. An object with the same name and a different
+// definition, or a missing server group, results in an error before anything
+// is created. When creating an object, the policy or any of its rules fails,
+// everything created so far, including the rules, is deleted.
+func (c *Client) CreateFirewallPolicyFromTemplate(tmpl FirewallPolicyTemplate) (policy FirewallPolicy, err error) {
+	existing, err := c.ListFirewallObjects()
+	if err != nil {
+		return policy, err
+	}
+
+	var zones []FirewallZone
+	for _, tz := range tmpl.Objects.Zones {
+		z, err := tz.Zone()
+		if err != nil {
+			return policy, err
+		}
+
+		if e, ok := existing.Zone(z.Name); ok && e.Name == z.Name && !e.IPAddress.Equal(z.IPAddress) {
+			return policy, fmt.Errorf("zone %s already exists with addresses %s", z.Name, e.IPAddress)
+		}
+		zones = append(zones, z)
+	}
+
+	for _, s := range tmpl.Objects.Services {
+		if e, ok := existing.Service(s.Name); ok && e.Name == s.Name && (e.Protocol.Normalize() != s.Protocol.Normalize() || e.Port != s.Port) {
+			return policy, fmt.Errorf("service %s already exists with protocol %s and port %s", s.Name, e.Protocol, e.Port)
+		}
+	}
+
+	ids, err := c.firewallTemplateGroupIDs(tmpl)
+	if err != nil {
+		return policy, err
+	}
+
+	var created []firewallTemplateObject
+	defer func() {
+		if err != nil {
+			policy = FirewallPolicy{}
+			err = c.deleteFirewallTemplateObjects(created, err)
+		}
+	}()
+
+	for _, z := range zones {
+		if e, ok := existing.Zone(z.Name); ok && e.Name == z.Name {
+			ids["zone/"+z.Name] = e.ID
+			continue
+		}
+
+		resp, err := c.CreateFirewallZone(z)
+		if err != nil {
+			return policy, fmt.Errorf("cannot create zone %s: %v", z.Name, err)
+		}
+		ids["zone/"+z.Name] = resp.Zone.ID
+		created = append(created, firewallTemplateObject{"zone", z.Name, resp.Zone.ID, c.DeleteFirewallZone})
+	}
+
+	for _, s := range tmpl.Objects.Services {
+		if e, ok := existing.Service(s.Name); ok && e.Name == s.Name {
+			ids["service/"+s.Name] = e.ID
+			continue
+		}
+
+		resp, err := c.CreateFirewallService(s)
+		if err != nil {
+			return policy, fmt.Errorf("cannot create service %s: %v", s.Name, err)
+		}
+		ids["service/"+s.Name] = resp.Service.ID
+		created = append(created, firewallTemplateObject{"service", s.Name, resp.Service.ID, c.DeleteFirewallService})
+	}
+
+	for _, i := range tmpl.Objects.Interfaces {
+		if e, ok := existing.Interface(i.Name); ok && e.Name == i.Name {
+			ids["interface/"+i.Name] = e.ID
+			continue
+		}
+
+		resp, err := c.CreateFirewallInterface(i)
+		if err != nil {
+			return policy, fmt.Errorf("cannot create interface %s: %v", i.Name, err)
+		}
+		ids["interface/"+i.Name] = resp.Interface.ID
+		created = append(created, firewallTemplateObject{"interface", i.Name, resp.Interface.ID, c.DeleteFirewallInterface})
+	}
+
+	policy = tmpl.Policy
+	policy.FirewallRules = nil
+
+	resp, err := c.CreateFirewallPolicy(policy)
+	if err != nil {
+		return policy, fmt.Errorf("cannot create policy %s: %v", policy.Name, err)
+	}
+	policy = resp.Policy
+	policy.FirewallRules = nil
+	created = append(created, firewallTemplateObject{"policy", policy.Name, policy.ID, c.DeleteFirewallPolicy})
+
+	policyID := policy.ID
+	deleteRule := func(ID string) error {
+		return c.DeleteFirewallRule(policyID, ID)
+	}
+
+	for i, r := range tmpl.Policy.FirewallRules {
+		r.Position = i + 1
+
+		r.FirewallSource = templateEndpoint(r.FirewallSource, ids)
+		r.FirewallTarget = templateEndpoint(r.FirewallTarget, ids)
+
+		if r.FirewallService != nil {
+			r.FirewallService = &FirewallService{ID: ids["service/"+r.FirewallService.Name]}
+		}
+		if r.FirewallInterface != nil {
+			r.FirewallInterface = &FirewallInterface{ID: ids["interface/"+r.FirewallInterface.Name]}
+		}
+
+		rule, err := c.CreateFirewallRule(policy.ID, r)
+		if err != nil {
+			return policy, fmt.Errorf("cannot create rule %d of policy %s: %v", i+1, policy.Name, err)
+		}
+		policy.FirewallRules = append(policy.FirewallRules, rule.Rule)
+		created = append(created, firewallTemplateObject{"rule", strconv.Itoa(i + 1), rule.Rule.ID, deleteRule})
+	}
+
+	return policy, nil
+}
+
+// firewallTemplateObject represent an object created from a firewall policy
+// template, which is deleted when creating the rest fails.
+type firewallTemplateObject struct {
+	kind   string
+	name   string
+	id     string
+	delete func(ID string) error
+}
+
+// deleteFirewallTemplateObjects deletes the created objects in reverse order
+// and adds the ones, which cannot be deleted, to the error.
+func (c *Client) deleteFirewallTemplateObjects(created []firewallTemplateObject, err error) error {
+	var left []string
+
+	for i := len(created) - 1; i >= 0; i-- {
+		o := created[i]
+		if derr := o.delete(o.id); derr != nil {
+			left = append(left, fmt.Sprintf("%s %s (%s): %v", o.kind, o.name, o.id, derr))
+		}
+	}
+
+	if len(left) > 0 {
+		return fmt.Errorf("%v; cannot delete created %s", err, strings.Join(left, ", "))
+	}
+
+	return err
+}
+
+// firewallTemplateGroupIDs looks up IDs of server groups rules of the template
+// refer by name. Groups are listed only when there are such rules.
+func (c *Client) firewallTemplateGroupIDs(tmpl FirewallPolicyTemplate) (map[string]string, error) {
+	ids := map[string]string{}
+
+	var groups []ServerGroup
+	listed := false
+
+	for _, r := range tmpl.Policy.FirewallRules {
+		for _, st := range []*FirewallRuleSourceTarget{r.FirewallSource, r.FirewallTarget} {
+			if st == nil || st.Kind != FirewallEndpointGroup || st.ID != "" || st.Name == "" || st.Name == FirewallAllActiveServers {
+				continue
+			}
+
+			if !listed {
+				resp, err := c.ListServerGroups()
+				if err != nil {
+					return nil, fmt.Errorf("cannot list server groups: %v", err)
+				}
+				groups = resp.Groups
+				listed = true
+			}
+
+			found := false
+			for _, g := range groups {
+				if g.Name == st.Name {
+					ids["group/"+st.Name] = g.ID
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return nil, fmt.Errorf("server group %s not found", st.Name)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+func templateEndpoint(st *FirewallRuleSourceTarget, ids map[string]string) *FirewallRuleSourceTarget {
+	if st == nil {
+		return nil
+	}
+
+	switch {
+	case st.Kind == FirewallEndpointZone:
+		return &FirewallRuleSourceTarget{ID: ids["zone/"+st.Name], Kind: st.Kind}
+	case st.Kind == FirewallEndpointGroup && st.ID == "" && st.Name != FirewallAllActiveServers:
+		return &FirewallRuleSourceTarget{ID: ids["group/"+st.Name], Kind: st.Kind}
+	}
+
+	return st
+}
@@ -0,0 +1,324 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewFirewallPolicyTemplate(t *testing.T) {
+	policy := FirewallPolicy{
+		ID:       "p1",
+		Name:     "web",
+		Platform: "linux",
+		FirewallRules: []FirewallRule{
+			{ID: "r2", Position: 2, Chain: "OUTPUT", Action: "ACCEPT", FirewallTarget: &FirewallRuleSourceTarget{ID: "g1", Name: "graphite", Kind: "Group"}},
+			{
+				ID:                "r1",
+				Position:          1,
+				Chain:             "INPUT",
+				Action:            "ACCEPT",
+				ConnectionStates:  "ANY",
+				FirewallSource:    &FirewallRuleSourceTarget{ID: "z-office", Kind: "FirewallZone"},
+				FirewallService:   &FirewallService{ID: "s-ssh"},
+				FirewallInterface: &FirewallInterface{ID: "i-eth1"},
+			},
+			{ID: "r3", Position: 3, Chain: "INPUT", Action: "DROP", FirewallSource: &FirewallRuleSourceTarget{Name: FirewallAllActiveServers, Kind: "Group"}, FirewallService: &FirewallService{Name: "ssh"}},
+		},
+	}
+
+	tmpl, err := NewFirewallPolicyTemplate(policy, testFirewallObjects())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if tmpl.Policy.ID != "" || tmpl.Policy.Name != "web" || len(tmpl.Policy.FirewallRules) != 3 {
+		t.Fatalf("unexpected policy %+v", tmpl.Policy)
+	}
+
+	first := tmpl.Policy.FirewallRules[0]
+	if first.ID != "" || first.ConnectionStates != "" || *first.FirewallSource != (FirewallRuleSourceTarget{Name: "office", Kind: "FirewallZone"}) ||
+		first.FirewallService.Name != "ssh" || first.FirewallService.ID != "" || first.FirewallInterface.Name != "eth1" {
+		t.Errorf("expected first rule to refer objects by name; got %+v", first)
+	}
+
+	if target := tmpl.Policy.FirewallRules[1].FirewallTarget; *target != (FirewallRuleSourceTarget{Name: "graphite", Kind: "Group"}) {
+		t.Errorf("expected group to be referred by name; got %+v", target)
+	}
+
+	expectedObjects := FirewallTemplateObjects{
+		Zones:      []FirewallTemplateZone{{Name: "office", IPAddress: IPList{"10.0.0.0/8", "192.168.1.1"}}},
+		Services:   []FirewallService{{Name: "ssh", Protocol: "TCP", Port: "22"}},
+		Interfaces: []FirewallInterface{{Name: "eth1"}},
+	}
+	if !reflect.DeepEqual(tmpl.Objects, expectedObjects) {
+		t.Errorf("expected objects %+v; got %+v", expectedObjects, tmpl.Objects)
+	}
+
+	policy.FirewallRules[0].FirewallService = &FirewallService{ID: "s-missing"}
+	if _, err := NewFirewallPolicyTemplate(policy, testFirewallObjects()); err == nil {
+		t.Error("expected error for missing service")
+	}
+}
+
+func TestClient_CloneFirewallPolicy(t *testing.T) {
+	var err error
+
+	source := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117":                jsonResponseTestHandler(t, "firewall_policies_get", http.StatusOK),
+			"GET /v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117/firewall_rules": jsonResponseTestHandler(t, "firewall_rules_list_expanded", http.StatusOK),
+			"GET /v1/firewall_zones":      jsonResponseTestHandler(t, "firewall_zones_list", http.StatusOK),
+			"GET /v1/firewall_services":   jsonResponseTestHandler(t, "firewall_services_list", http.StatusOK),
+			"GET /v1/firewall_interfaces": jsonResponseTestHandler(t, "firewall_interfaces_list", http.StatusOK),
+		}),
+	)
+	defer source.Close()
+
+	var (
+		created FirewallPolicy
+		rules   []FirewallRule
+	)
+
+	recorder := func(next http.Handler, fn func(GetFirewallRuleResponse, GetFirewallPolicyResponse)) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("reading body failed: %v", err)
+			}
+
+			var rule GetFirewallRuleResponse
+			var policy GetFirewallPolicyResponse
+			if err := json.Unmarshal(b, &rule); err != nil {
+				t.Fatalf("unmarshalling body failed: %v", err)
+			}
+			if err := json.Unmarshal(b, &policy); err != nil {
+				t.Fatalf("unmarshalling body failed: %v", err)
+			}
+
+			fn(rule, policy)
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	target := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/firewall_zones":      jsonResponseTestHandler(t, "firewall_zones_list", http.StatusOK),
+			"GET /v1/firewall_services":   jsonResponseTestHandler(t, "firewall_services_list", http.StatusOK),
+			"GET /v1/firewall_interfaces": jsonResponseTestHandler(t, "firewall_interfaces_list", http.StatusOK),
+			"POST /v1/firewall_policies": recorder(jsonResponseTestHandler(t, "firewall_policies_get", http.StatusCreated), func(_ GetFirewallRuleResponse, p GetFirewallPolicyResponse) {
+				created = p.Policy
+			}),
+			"POST /v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117/firewall_rules": recorder(jsonResponseTestHandler(t, "firewall_rules_get", http.StatusCreated), func(r GetFirewallRuleResponse, _ GetFirewallPolicyResponse) {
+				rules = append(rules, r.Rule)
+			}),
+		}),
+	)
+	defer target.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(source.URL)
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", source.URL, err)
+	}
+
+	other := NewClient("", "", nil)
+	other.baseURL, err = url.Parse(target.URL)
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", target.URL, err)
+	}
+
+	policy, err := client.CloneFirewallPolicy("be28b106ee5b11e8a7s1017da54e9117", "thebestfirewall-copy", other)
+	if err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+
+	if policy.ID != "be28b106ee5b11e8a7s1017da54e9117" || len(policy.FirewallRules) != 2 {
+		t.Errorf("expected created policy with 2 rules; got %+v", policy)
+	}
+
+	if created.Name != "thebestfirewall-copy" || created.Platform != "linux" || created.ID != "" || len(created.FirewallRules) != 0 {
+		t.Errorf("unexpected created policy %+v", created)
+	}
+
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 created rules; got %d", len(rules))
+	}
+
+	first := rules[0]
+	if first.Position != 1 || first.LogPrefix != "ssh" || first.Comment != "ssh from anywhere" ||
+		first.FirewallSource.ID != "ea81ec609956012ee2db40989asd0980" ||
+		first.FirewallService.ID != "ea3fe1309956012ee2989ea98as989as" ||
+		first.FirewallInterface.ID != "eab41be09956012ee2db4087123ad87s" {
+		t.Errorf("unexpected first rule %+v", first)
+	}
+
+	if second := rules[1]; second.Position != 2 || second.GetActive() || second.Chain != "OUTPUT" || second.Action != "DROP" {
+		t.Errorf("unexpected second rule %+v", second)
+	}
+}
+
+func TestClient_CreateFirewallPolicyFromTemplate_Groups(t *testing.T) {
+	var err error
+	var rule GetFirewallRuleResponse
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/firewall_zones":      jsonResponseTestHandler(t, "firewall_zones_list", http.StatusOK),
+			"GET /v1/firewall_services":   jsonResponseTestHandler(t, "firewall_services_list", http.StatusOK),
+			"GET /v1/firewall_interfaces": jsonResponseTestHandler(t, "firewall_interfaces_list", http.StatusOK),
+			"GET /v1/groups":              jsonResponseTestHandler(t, "server_groups_list", http.StatusOK),
+			"POST /v1/firewall_policies":  jsonResponseTestHandler(t, "firewall_policies_get", http.StatusCreated),
+			"POST /v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117/firewall_rules": bodyRecorderTestHandler(t, jsonResponseTestHandler(t, "firewall_rules_get", http.StatusCreated), &rule),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	tmpl := FirewallPolicyTemplate{
+		Policy: FirewallPolicy{
+			Name: "monitoring",
+			FirewallRules: []FirewallRule{
+				{Chain: "INPUT", Action: "ACCEPT", FirewallSource: &FirewallRuleSourceTarget{Name: "graphite", Kind: "Group"}},
+			},
+		},
+	}
+
+	if _, err := client.CreateFirewallPolicyFromTemplate(tmpl); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if *rule.Rule.FirewallSource != (FirewallRuleSourceTarget{ID: "9981f162c2d611e680b17f1fb185c564", Kind: "Group"}) {
+		t.Errorf("expected group to be remapped by name; got %+v", rule.Rule.FirewallSource)
+	}
+
+	tmpl.Policy.FirewallRules[0].FirewallSource.Name = "missing"
+
+	if _, err := client.CreateFirewallPolicyFromTemplate(tmpl); err == nil || !strings.Contains(err.Error(), "server group missing not found") {
+		t.Errorf("expected missing group error; got %v", err)
+	}
+}
+
+func TestClient_CreateFirewallPolicyFromTemplate_Cleanup(t *testing.T) {
+	var err error
+	var deleted []string
+
+	deleteRecorder := func(w http.ResponseWriter, r *http.Request) {
+		deleted = append(deleted, r.RequestURI)
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	rules := 0
+	createRule := func(w http.ResponseWriter, r *http.Request) {
+		rules++
+		if rules > 1 {
+			jsonResponseTestHandler(t, "", http.StatusUnprocessableEntity).ServeHTTP(w, r)
+			return
+		}
+		jsonResponseTestHandler(t, "firewall_rules_get", http.StatusCreated).ServeHTTP(w, r)
+	}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/firewall_zones":      jsonResponseTestHandler(t, "firewall_zones_list", http.StatusOK),
+			"GET /v1/firewall_services":   jsonResponseTestHandler(t, "firewall_services_list", http.StatusOK),
+			"GET /v1/firewall_interfaces": jsonResponseTestHandler(t, "firewall_interfaces_list", http.StatusOK),
+			"POST /v1/firewall_zones":     jsonResponseTestHandler(t, "firewall_zones_get", http.StatusCreated),
+			"POST /v1/firewall_policies":  jsonResponseTestHandler(t, "firewall_policies_get", http.StatusCreated),
+			"POST /v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117/firewall_rules":                                    http.HandlerFunc(createRule),
+			"DELETE /v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117/firewall_rules/jtap810cee5b11e89d0d15ea2eff2521": http.HandlerFunc(deleteRecorder),
+			"DELETE /v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117":                                                 http.HandlerFunc(deleteRecorder),
+			"DELETE /v1/firewall_zones/ea81ec609956012ee2db40989asd0980":                                                    http.HandlerFunc(deleteRecorder),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	tmpl := FirewallPolicyTemplate{
+		Policy: FirewallPolicy{
+			Name: "office",
+			FirewallRules: []FirewallRule{
+				{Chain: "INPUT", Action: "ACCEPT", FirewallSource: &FirewallRuleSourceTarget{Name: "office", Kind: FirewallEndpointZone}},
+				{Chain: "INPUT", Action: "DROP"},
+			},
+		},
+		Objects: FirewallTemplateObjects{
+			Zones: []FirewallTemplateZone{{Name: "office", IPAddress: IPList{"10.0.0.0/8"}}},
+		},
+	}
+
+	policy, err := client.CreateFirewallPolicyFromTemplate(tmpl)
+	if err == nil || !strings.Contains(err.Error(), "cannot create rule 2") {
+		t.Fatalf("expected rule creation error; got %v", err)
+	}
+
+	if policy.ID != "" {
+		t.Errorf("expected no policy; got %+v", policy)
+	}
+
+	expected := []string{
+		"/v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117/firewall_rules/jtap810cee5b11e89d0d15ea2eff2521",
+		"/v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117",
+		"/v1/firewall_zones/ea81ec609956012ee2db40989asd0980",
+	}
+	if strings.Join(deleted, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected created rule, policy and zone to be deleted %v; got %v", expected, deleted)
+	}
+}
+
+func TestClient_CloneFirewallPolicy_AccountSpecificEndpoint(t *testing.T) {
+	var err error
+
+	source := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117": jsonResponseTestHandler(t, "firewall_policies_get", http.StatusOK),
+			"GET /v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117/firewall_rules": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode(ListFirewallRulesResponse{
+					Count: 1,
+					Rules: []FirewallRule{
+						{ID: "r1", Chain: "INPUT", Action: "ACCEPT", Position: 1, FirewallSource: &FirewallRuleSourceTarget{ID: "9981f162c2d611e680b17f1fb185c564", Kind: FirewallEndpointGroup}},
+					},
+				})
+			}),
+			"GET /v1/firewall_zones":      jsonResponseTestHandler(t, "firewall_zones_list", http.StatusOK),
+			"GET /v1/firewall_services":   jsonResponseTestHandler(t, "firewall_services_list", http.StatusOK),
+			"GET /v1/firewall_interfaces": jsonResponseTestHandler(t, "firewall_interfaces_list", http.StatusOK),
+		}),
+	)
+	defer source.Close()
+
+	target := httptest.NewServer(routesTestHandler(t, map[string]http.Handler{}))
+	defer target.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(source.URL)
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", source.URL, err)
+	}
+
+	other := NewClient("", "", nil)
+	other.baseURL, err = url.Parse(target.URL)
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", target.URL, err)
+	}
+
+	_, err = client.CloneFirewallPolicy("be28b106ee5b11e8a7s1017da54e9117", "copy", other)
+	if err == nil || !strings.Contains(err.Error(), "Group 9981f162c2d611e680b17f1fb185c564 specific to the source account") {
+		t.Errorf("expected error for group referred by ID; got %v", err)
+	}
+}
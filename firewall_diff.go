@@ -0,0 +1,374 @@
+package cphalo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FirewallRuleChangeType represent a kind of firewall rule change.
+type FirewallRuleChangeType string
+
+// Kinds of firewall rule changes.
+const (
+	FirewallRuleAdded    FirewallRuleChangeType = "added"
+	FirewallRuleRemoved  FirewallRuleChangeType = "removed"
+	FirewallRuleModified FirewallRuleChangeType = "modified"
+	FirewallRuleMoved    FirewallRuleChangeType = "moved"
+)
+
+// FirewallRuleChange represent a change of a single firewall rule.
+//
+// Positions are zero for rules missing on the respective side.
+type FirewallRuleChange struct {
+	Type        FirewallRuleChangeType `json:"type"`
+	Rule        string                 `json:"rule"`
+	OldRuleID   string                 `json:"old_rule_id,omitempty"`
+	NewRuleID   string                 `json:"new_rule_id,omitempty"`
+	OldPosition int                    `json:"old_position,omitempty"`
+	NewPosition int                    `json:"new_position,omitempty"`
+	Changes     []string               `json:"changes,omitempty"`
+}
+
+// FirewallPolicyDiff represent differences between two firewall policies.
+//
+// It renders as unified text by String, as Markdown by Markdown and
+// marshals into JSON.
+type FirewallPolicyDiff struct {
+	OldName string               `json:"old_name"`
+	NewName string               `json:"new_name"`
+	Policy  []string             `json:"policy,omitempty"`
+	Rules   []FirewallRuleChange `json:"rules,omitempty"`
+}
+
+// Empty reports whether the policies are equal.
+func (d FirewallPolicyDiff) Empty() bool {
+	return len(d.Policy) == 0 && len(d.Rules) == 0
+}
+
+// DiffFirewallPolicies compares two firewall policies or their snapshots.
+//
+// Rules are paired by their content with zones, services and interfaces
+// resolved to names using the objects of the respective side, so policies from
+// different accounts or with recreated rules can be compared. Rules matching the
+// same traffic with different action, state, logging or comment are modified,
+// paired rules, which changed their relative order, are moved.
+func DiffFirewallPolicies(old, new FirewallPolicy, oldObjects, newObjects FirewallObjects) FirewallPolicyDiff {
+	d := FirewallPolicyDiff{OldName: old.Name, NewName: new.Name}
+
+	attr := func(name string, from, to interface{}) {
+		if fmt.Sprint(from) != fmt.Sprint(to) {
+			d.Policy = append(d.Policy, fmt.Sprintf("%s: %q => %q", name, fmt.Sprint(from), fmt.Sprint(to)))
+		}
+	}
+
+	attr("name", old.Name, new.Name)
+	attr("platform", old.Platform, new.Platform)
+	attr("description", old.Description, new.Description)
+	attr("shared", bool(old.Shared), bool(new.Shared))
+	attr("ignore_forwarding_rules", old.IgnoreForwardingRules, new.IgnoreForwardingRules)
+
+	oldRules := describeFirewallRules(resolveFirewallRules(old, oldObjects))
+	newRules := describeFirewallRules(resolveFirewallRules(new, newObjects))
+
+	pairs := map[int]int{}
+	pairedOld := map[int]bool{}
+
+	pair := func(key func(describedRule) string) {
+		queues := map[string][]int{}
+		for i, r := range oldRules {
+			if !pairedOld[i] {
+				queues[key(r)] = append(queues[key(r)], i)
+			}
+		}
+
+		for j, r := range newRules {
+			if _, ok := pairs[j]; ok {
+				continue
+			}
+			if q := queues[key(r)]; len(q) > 0 {
+				pairs[j], pairedOld[q[0]] = q[0], true
+				queues[key(r)] = q[1:]
+			}
+		}
+	}
+
+	pair(func(r describedRule) string { return r.match + "|" + r.attributes })
+	pair(func(r describedRule) string { return r.match })
+
+	var order []int
+	var paired []int
+	for j := range newRules {
+		if i, ok := pairs[j]; ok {
+			order = append(order, i)
+			paired = append(paired, j)
+		}
+	}
+	kept := longestIncreasingSubsequence(order)
+
+	for k, j := range paired {
+		o, n := oldRules[order[k]], newRules[j]
+
+		change := FirewallRuleChange{
+			Rule:        n.String(),
+			OldRuleID:   o.rule.ID,
+			NewRuleID:   n.rule.ID,
+			OldPosition: o.position,
+			NewPosition: n.position,
+			Changes:     o.diff(n),
+		}
+
+		switch {
+		case len(change.Changes) > 0:
+			change.Type = FirewallRuleModified
+			if !kept[k] {
+				change.Changes = append(change.Changes, fmt.Sprintf("position: %d => %d", o.position, n.position))
+			}
+		case !kept[k]:
+			change.Type = FirewallRuleMoved
+		default:
+			continue
+		}
+
+		d.Rules = append(d.Rules, change)
+	}
+
+	for i, o := range oldRules {
+		if !pairedOld[i] {
+			d.Rules = append(d.Rules, FirewallRuleChange{Type: FirewallRuleRemoved, Rule: o.String(), OldRuleID: o.rule.ID, OldPosition: o.position})
+		}
+	}
+
+	for j, n := range newRules {
+		if _, ok := pairs[j]; !ok {
+			d.Rules = append(d.Rules, FirewallRuleChange{Type: FirewallRuleAdded, Rule: n.String(), NewRuleID: n.rule.ID, NewPosition: n.position})
+		}
+	}
+
+	sort.SliceStable(d.Rules, func(i, j int) bool {
+		return d.Rules[i].sortPosition() < d.Rules[j].sortPosition()
+	})
+
+	return d
+}
+
+func (c FirewallRuleChange) sortPosition() float64 {
+	if c.Type == FirewallRuleRemoved {
+		return float64(c.OldPosition) - 0.5
+	}
+
+	return float64(c.NewPosition)
+}
+
+// String renders the diff as unified text.
+func (d FirewallPolicyDiff) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", d.OldName, d.NewName)
+
+	if len(d.Policy) > 0 {
+		b.WriteString("@@ policy @@\n")
+		for _, p := range d.Policy {
+			fmt.Fprintf(&b, "~ %s\n", p)
+		}
+	}
+
+	if len(d.Rules) > 0 {
+		b.WriteString("@@ rules @@\n")
+	}
+
+	for _, c := range d.Rules {
+		switch c.Type {
+		case FirewallRuleAdded:
+			fmt.Fprintf(&b, "+ [%d] %s\n", c.NewPosition, c.Rule)
+		case FirewallRuleRemoved:
+			fmt.Fprintf(&b, "- [%d] %s\n", c.OldPosition, c.Rule)
+		case FirewallRuleModified:
+			fmt.Fprintf(&b, "~ [%d] %s\n", c.NewPosition, c.Rule)
+			for _, ch := range c.Changes {
+				fmt.Fprintf(&b, "    %s\n", ch)
+			}
+		case FirewallRuleMoved:
+			fmt.Fprintf(&b, "> [%d] %s (moved from %d)\n", c.NewPosition, c.Rule, c.OldPosition)
+		}
+	}
+
+	return b.String()
+}
+
+// Markdown renders the diff as a Markdown table suitable for merge request comments.
+func (d FirewallPolicyDiff) Markdown() string {
+	var b strings.Builder
+
+	if d.OldName == d.NewName {
+		fmt.Fprintf(&b, "### Firewall policy `%s`\n\n", d.NewName)
+	} else {
+		fmt.Fprintf(&b, "### Firewall policy `%s` → `%s`\n\n", d.OldName, d.NewName)
+	}
+
+	if d.Empty() {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+
+	for _, p := range d.Policy {
+		fmt.Fprintf(&b, "- %s\n", markdownEscape(p))
+	}
+
+	if len(d.Policy) > 0 && len(d.Rules) > 0 {
+		b.WriteString("\n")
+	}
+
+	if len(d.Rules) == 0 {
+		return b.String()
+	}
+
+	b.WriteString("| Change | Position | Rule | Details |\n")
+	b.WriteString("|---|---|---|---|\n")
+
+	for _, c := range d.Rules {
+		var position string
+		switch c.Type {
+		case FirewallRuleAdded:
+			position = fmt.Sprintf("%d", c.NewPosition)
+		case FirewallRuleRemoved:
+			position = fmt.Sprintf("%d", c.OldPosition)
+		default:
+			position = fmt.Sprintf("%d → %d", c.OldPosition, c.NewPosition)
+		}
+
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", c.Type, position, markdownEscape(c.Rule), markdownEscape(strings.Join(c.Changes, "<br>")))
+	}
+
+	return b.String()
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "`", "\\`", "\n", " ").Replace(s)
+}
+
+// describedRule is a resolved rule with its matches and attributes described by names.
+type describedRule struct {
+	resolvedRule
+	match      string
+	attributes string
+}
+
+func describeFirewallRules(rules []resolvedRule) []describedRule {
+	var described []describedRule
+
+	for _, r := range rules {
+		d := describedRule{resolvedRule: r}
+
+		parts := []string{string(r.rule.Chain), "from " + describeEndpoint(r.source), "to " + describeEndpoint(r.target)}
+		if r.rule.FirewallService != nil {
+			service := "service " + r.service
+			if r.protocol != "" {
+				service += " (" + r.protocol
+				if r.ports != nil {
+					service += "/" + strings.Join(formatPorts(r.ports), ",")
+				}
+				service += ")"
+			}
+			parts = append(parts, service)
+		}
+		if r.iface != "" {
+			parts = append(parts, "on "+r.iface)
+		}
+		if states := normalizedConnectionStates(r.rule.ConnectionStates); states != "" {
+			parts = append(parts, "states "+states)
+		}
+
+		d.match = strings.Join(parts, " ")
+		d.attributes = fmt.Sprintf("%s|%t|%t|%s|%s", r.rule.Action, r.rule.GetActive(), r.rule.GetLog(), r.rule.LogPrefix, r.rule.Comment)
+
+		described = append(described, d)
+	}
+
+	return described
+}
+
+func describeEndpoint(e *ruleEndpoint) string {
+	if e == nil {
+		return "any"
+	}
+
+	switch e.kind {
+	case FirewallEndpointZone:
+		return "zone " + e.name
+	case FirewallEndpointGroup:
+		return "group " + e.name
+	case FirewallEndpointUser:
+		return "user " + e.name
+	case FirewallEndpointUserGroup:
+		return "user group " + e.name
+	}
+
+	return string(e.kind) + " " + e.name
+}
+
+// String describes the rule in a single line.
+func (r describedRule) String() string {
+	parts := strings.SplitN(r.match, " ", 2)
+	s := parts[0] + " " + string(r.rule.Action)
+	if len(parts) > 1 {
+		s += " " + parts[1]
+	}
+	if !r.rule.GetActive() {
+		s += " (inactive)"
+	}
+
+	return s
+}
+
+// diff lists attribute changes between paired rules.
+func (r describedRule) diff(to describedRule) []string {
+	var changes []string
+
+	attr := func(name string, from, to interface{}) {
+		if fmt.Sprint(from) != fmt.Sprint(to) {
+			changes = append(changes, fmt.Sprintf("%s: %q => %q", name, fmt.Sprint(from), fmt.Sprint(to)))
+		}
+	}
+
+	attr("action", r.rule.Action, to.rule.Action)
+	attr("active", r.rule.GetActive(), to.rule.GetActive())
+	attr("log", r.rule.GetLog(), to.rule.GetLog())
+	attr("log_prefix", r.rule.LogPrefix, to.rule.LogPrefix)
+	attr("comment", r.rule.Comment, to.rule.Comment)
+
+	return changes
+}
+
+// longestIncreasingSubsequence marks elements of the sequence, which form
+// its longest strictly increasing subsequence.
+func longestIncreasingSubsequence(seq []int) []bool {
+	kept := make([]bool, len(seq))
+
+	// tails[k] is the index of the smallest tail of increasing subsequences of length k+1
+	var tails []int
+	prev := make([]int, len(seq))
+
+	for i, v := range seq {
+		k := sort.Search(len(tails), func(k int) bool { return seq[tails[k]] >= v })
+
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			kept[i] = true
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,138 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiffFirewallPolicies(t *testing.T) {
+	zone := func(id string) *FirewallRuleSourceTarget {
+		return &FirewallRuleSourceTarget{ID: id, Kind: "FirewallZone"}
+	}
+
+	old := FirewallPolicy{
+		Name: "web",
+		FirewallRules: []FirewallRule{
+			{ID: "a", Position: 1, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: zone("z-office"), FirewallService: &FirewallService{ID: "s-ssh"}},
+			{ID: "b", Position: 2, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: &FirewallService{ID: "s-web"}},
+			{ID: "c", Position: 3, Chain: "INPUT", Action: "DROP", Active: Bool(true), FirewallSource: zone("z-vpn")},
+			{ID: "d", Position: 4, Chain: "OUTPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: &FirewallService{ID: "s-dns"}},
+		},
+	}
+
+	// new policy lives in another account, so objects have different IDs but equal names
+	newObjects := testFirewallObjects()
+	for i := range newObjects.Zones {
+		newObjects.Zones[i].ID = "new-" + newObjects.Zones[i].ID
+	}
+	for i := range newObjects.Services {
+		newObjects.Services[i].ID = "new-" + newObjects.Services[i].ID
+	}
+
+	new := FirewallPolicy{
+		Name:        "web",
+		Description: "public web",
+		FirewallRules: []FirewallRule{
+			{ID: "C", Position: 1, Chain: "INPUT", Action: "DROP", Active: Bool(true), FirewallSource: zone("new-z-vpn")},
+			{ID: "A", Position: 2, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: zone("new-z-office"), FirewallService: &FirewallService{ID: "new-s-ssh"}},
+			{ID: "B", Position: 3, Chain: "INPUT", Action: "REJECT", Active: Bool(true), FirewallService: &FirewallService{ID: "new-s-web"}, Comment: "closed"},
+			{ID: "E", Position: 4, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: &FirewallService{ID: "new-s-db"}, ConnectionStates: "NEW"},
+		},
+	}
+
+	d := DiffFirewallPolicies(old, new, testFirewallObjects(), newObjects)
+
+	expected := `--- web
++++ web
+@@ policy @@
+~ description: "" => "public web"
+@@ rules @@
+> [1] INPUT DROP from zone vpn to any (moved from 3)
+~ [3] INPUT REJECT from any to any service web (TCP/80,443)
+    action: "ACCEPT" => "REJECT"
+    comment: "" => "closed"
+- [4] OUTPUT ACCEPT from any to any service dns (UDP/53)
++ [4] INPUT ACCEPT from any to any service postgres (TCP/5432) states NEW
+`
+
+	if d.String() != expected {
+		t.Errorf("expected diff:\n%s\ngot:\n%s", expected, d.String())
+	}
+
+	md := d.Markdown()
+	for _, s := range []string{
+		"### Firewall policy `web`",
+		"- description: \"\" => \"public web\"",
+		"| moved | 3 → 1 | INPUT DROP from zone vpn to any |  |",
+		"| modified | 2 → 3 | INPUT REJECT from any to any service web (TCP/80,443) | action: \"ACCEPT\" => \"REJECT\"<br>comment: \"\" => \"closed\" |",
+		"| removed | 4 | OUTPUT ACCEPT",
+		"| added | 4 | INPUT ACCEPT",
+	} {
+		if !strings.Contains(md, s) {
+			t.Errorf("expected markdown to contain %q; got\n%s", s, md)
+		}
+	}
+
+	out, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("cannot marshal diff: %v", err)
+	}
+
+	var decoded FirewallPolicyDiff
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("cannot unmarshal diff: %v", err)
+	}
+
+	if len(decoded.Rules) != 4 || decoded.Rules[0].Type != FirewallRuleMoved || decoded.Rules[0].OldRuleID != "c" || decoded.Rules[0].NewRuleID != "C" {
+		t.Errorf("unexpected decoded diff %+v", decoded)
+	}
+}
+
+func TestDiffFirewallPolicies_Equal(t *testing.T) {
+	policy := FirewallPolicy{
+		Name: "web",
+		FirewallRules: []FirewallRule{
+			{ID: "a", Chain: "INPUT", Action: "ACCEPT", Active: Bool(true)},
+			{ID: "b", Chain: "INPUT", Action: "ACCEPT", Active: Bool(true)},
+		},
+	}
+
+	d := DiffFirewallPolicies(policy, policy, testFirewallObjects(), testFirewallObjects())
+
+	if !d.Empty() {
+		t.Errorf("expected empty diff; got\n%s", d)
+	}
+
+	if md := d.Markdown(); !strings.Contains(md, "No changes.") {
+		t.Errorf("expected markdown to report no changes; got %s", md)
+	}
+}
+
+func TestLongestIncreasingSubsequence(t *testing.T) {
+	tests := []struct {
+		seq      []int
+		expected string
+	}{
+		{nil, ""},
+		{[]int{0, 1, 2}, "111"},
+		{[]int{2, 0, 1}, "011"},
+		{[]int{3, 0, 1, 2, 4}, "01111"},
+		{[]int{4, 3, 2, 1}, "0001"},
+	}
+
+	for _, tt := range tests {
+		var got string
+		for _, k := range longestIncreasingSubsequence(tt.seq) {
+			if k {
+				got += "1"
+			} else {
+				got += "0"
+			}
+		}
+
+		if got != tt.expected {
+			t.Errorf("%v: expected %s; got %s", tt.seq, tt.expected, got)
+		}
+	}
+}
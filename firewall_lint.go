@@ -0,0 +1,170 @@
+package cphalo
+
+import (
+	"fmt"
+	"sort"
+)
+
+// LintSeverity represent a severity of a firewall lint finding.
+type LintSeverity string
+
+// Severities of firewall lint findings.
+const (
+	LintSeverityError   LintSeverity = "error"
+	LintSeverityWarning LintSeverity = "warning"
+	LintSeverityInfo    LintSeverity = "info"
+)
+
+// Codes of firewall lint findings.
+const (
+	LintCodeShadowedRule      = "shadowed-rule"
+	LintCodeRedundantRule     = "redundant-rule"
+	LintCodeDuplicateRule     = "duplicate-rule"
+	LintCodeOpenSensitivePort = "open-sensitive-port"
+	LintCodeUnusedLogPrefix   = "unused-log-prefix"
+	LintCodeMissingReference  = "missing-reference"
+	LintCodeInvalidReference  = "invalid-reference"
+)
+
+// DefaultSensitivePorts are ports, which should not be accepted from any source.
+var DefaultSensitivePorts = []int{
+	21, 22, 23, 135, 139, 445, 1433, 2375, 3306, 3389, 5432, 5900, 6379, 9200, 11211, 27017,
+}
+
+// LintFinding represent a single problem found in a firewall policy.
+type LintFinding struct {
+	Severity        LintSeverity `json:"severity"`
+	Code            string       `json:"code"`
+	RuleID          string       `json:"rule_id,omitempty"`
+	Position        int          `json:"position"`
+	RelatedRuleID   string       `json:"related_rule_id,omitempty"`
+	RelatedPosition int          `json:"related_position,omitempty"`
+	Message         string       `json:"message"`
+}
+
+// String returns the finding in a single-line human-readable form.
+func (f LintFinding) String() string {
+	return fmt.Sprintf("%s: rule %d: %s (%s)", f.Severity, f.Position, f.Message, f.Code)
+}
+
+// FirewallLinter checks firewall policies for mistakes.
+type FirewallLinter struct {
+	// SensitivePorts are ports, which should not be accepted from any source.
+	// DefaultSensitivePorts are used when empty.
+	SensitivePorts []int
+}
+
+// LintFirewallPolicy checks the firewall policy using the default linter settings.
+func LintFirewallPolicy(policy FirewallPolicy, objects FirewallObjects) []LintFinding {
+	return FirewallLinter{}.Lint(policy, objects)
+}
+
+// Lint checks the firewall policy rules and returns findings ordered by rule position.
+//
+// References of rules are resolved using objects, falling back to details embedded
+// in the rules. Rules with unresolved references are not checked for shadowing.
+func (l FirewallLinter) Lint(policy FirewallPolicy, objects FirewallObjects) []LintFinding {
+	var findings []LintFinding
+
+	rules := resolveFirewallRules(policy, objects)
+
+	for i, r := range rules {
+		finding := func(severity LintSeverity, code, format string, args ...interface{}) LintFinding {
+			return LintFinding{
+				Severity: severity,
+				Code:     code,
+				RuleID:   r.rule.ID,
+				Position: r.position,
+				Message:  fmt.Sprintf(format, args...),
+			}
+		}
+
+		for _, m := range r.missing {
+			findings = append(findings, finding(LintSeverityError, LintCodeMissingReference, "references missing %s", m))
+		}
+
+		for _, m := range r.invalid {
+			findings = append(findings, finding(LintSeverityWarning, LintCodeInvalidReference, "references invalid %s", m))
+		}
+
+		if r.rule.LogPrefix != "" && !r.rule.GetLog() {
+			findings = append(findings, finding(LintSeverityInfo, LintCodeUnusedLogPrefix, "log prefix %q is set but logging is disabled", r.rule.LogPrefix))
+		}
+
+		if !r.rule.GetActive() || len(r.missing) > 0 || len(r.invalid) > 0 {
+			continue
+		}
+
+		if port, ok := l.exposedPort(r); ok {
+			findings = append(findings, finding(LintSeverityWarning, LintCodeOpenSensitivePort, "accepts sensitive port %d from any source", port))
+		}
+
+		for _, prev := range rules[:i] {
+			if !prev.rule.GetActive() || len(prev.missing) > 0 || len(prev.invalid) > 0 || !prev.covers(r) {
+				continue
+			}
+
+			var f LintFinding
+			switch {
+			case prev.rule.Action != r.rule.Action:
+				f = finding(LintSeverityError, LintCodeShadowedRule, "is shadowed by rule %d with action %s, so it never applies", prev.position, prev.rule.Action)
+			case r.covers(prev):
+				f = finding(LintSeverityWarning, LintCodeDuplicateRule, "duplicates rule %d", prev.position)
+			default:
+				f = finding(LintSeverityWarning, LintCodeRedundantRule, "is redundant, rule %d already matches all its traffic", prev.position)
+			}
+			f.RelatedRuleID = prev.rule.ID
+			f.RelatedPosition = prev.position
+
+			findings = append(findings, f)
+			break
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Position < findings[j].Position
+	})
+
+	return findings
+}
+
+// exposedPort returns the first sensitive port the rule accepts from any source.
+func (l FirewallLinter) exposedPort(r resolvedRule) (int, bool) {
+	if r.rule.Chain != FirewallChainInput || r.rule.Action != FirewallActionAccept {
+		return 0, false
+	}
+
+	if r.source != nil && !(r.source.kind == FirewallEndpointZone && isAnyAddress(r.source.addrs)) {
+		return 0, false
+	}
+
+	switch r.protocol {
+	case "", "ANY", "TCP", "UDP":
+	default:
+		return 0, false
+	}
+
+	ports := l.SensitivePorts
+	if len(ports) == 0 {
+		ports = DefaultSensitivePorts
+	}
+
+	for _, p := range ports {
+		if portsContain(r.ports, p) {
+			return p, true
+		}
+	}
+
+	return 0, false
+}
+
+// HasLintErrors reports whether any of the findings is an error.
+func HasLintErrors(findings []LintFinding) bool {
+	for _, f := range findings {
+		if f.Severity == LintSeverityError {
+			return true
+		}
+	}
+
+	return false
+}
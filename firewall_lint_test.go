@@ -0,0 +1,117 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLintFirewallPolicy(t *testing.T) {
+	zone := func(id string) *FirewallRuleSourceTarget {
+		return &FirewallRuleSourceTarget{ID: id, Kind: "FirewallZone"}
+	}
+
+	policy := FirewallPolicy{
+		Name: "web",
+		FirewallRules: []FirewallRule{
+			{ID: "r1", Position: 1, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: zone("z-office"), FirewallService: &FirewallService{ID: "s-ssh"}},
+			{ID: "r2", Position: 2, Chain: "INPUT", Action: "DROP", Active: Bool(true), FirewallSource: zone("z-vpn"), FirewallService: &FirewallService{ID: "s-ssh"}},
+			{ID: "r3", Position: 3, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: zone("z-office"), FirewallService: &FirewallService{ID: "s-ssh"}},
+			{ID: "r4", Position: 4, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: &FirewallService{ID: "s-web"}},
+			{ID: "r5", Position: 5, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: zone("z-office"), FirewallService: &FirewallService{ID: "s-http"}},
+			{ID: "r6", Position: 6, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: zone("z-any"), FirewallService: &FirewallService{ID: "s-db"}},
+			{ID: "r7", Position: 7, Chain: "OUTPUT", Action: "ACCEPT", Active: Bool(true), LogPrefix: "out"},
+			{ID: "r8", Position: 8, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: zone("z-gone"), FirewallInterface: &FirewallInterface{ID: "i-gone"}},
+			{ID: "r9", Position: 9, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: zone("z-empty")},
+			{ID: "r10", Position: 10, Chain: "INPUT", Action: "DROP", Active: Bool(false)},
+			{ID: "r11", Position: 11, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: zone("z-vpn"), FirewallService: &FirewallService{ID: "s-dns"}},
+		},
+	}
+
+	findings := LintFirewallPolicy(policy, testFirewallObjects())
+
+	expected := []struct {
+		code     string
+		severity LintSeverity
+		position int
+		related  int
+	}{
+		{LintCodeShadowedRule, LintSeverityError, 2, 1},
+		{LintCodeDuplicateRule, LintSeverityWarning, 3, 1},
+		{LintCodeRedundantRule, LintSeverityWarning, 5, 4},
+		{LintCodeOpenSensitivePort, LintSeverityWarning, 6, 0},
+		{LintCodeUnusedLogPrefix, LintSeverityInfo, 7, 0},
+		{LintCodeMissingReference, LintSeverityError, 8, 0},
+		{LintCodeMissingReference, LintSeverityError, 8, 0},
+		{LintCodeInvalidReference, LintSeverityWarning, 9, 0},
+	}
+
+	if len(findings) != len(expected) {
+		for _, f := range findings {
+			t.Log(f)
+		}
+		t.Fatalf("expected %d findings; got %d", len(expected), len(findings))
+	}
+
+	for i, e := range expected {
+		f := findings[i]
+		if f.Code != e.code || f.Severity != e.severity || f.Position != e.position || f.RelatedPosition != e.related {
+			t.Errorf("expected finding %d to be %s %s at %d related to %d; got %s", i, e.severity, e.code, e.position, e.related, f)
+		}
+	}
+
+	if !HasLintErrors(findings) {
+		t.Errorf("expected findings to contain errors")
+	}
+
+	out, err := json.Marshal(findings[0])
+	if err != nil {
+		t.Fatalf("cannot marshal finding: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("cannot unmarshal finding: %v", err)
+	}
+
+	if decoded["severity"] != "error" || decoded["code"] != LintCodeShadowedRule || decoded["related_rule_id"] != "r1" {
+		t.Errorf("unexpected finding JSON %s", out)
+	}
+}
+
+func TestFirewallLinter_SensitivePorts(t *testing.T) {
+	policy := FirewallPolicy{
+		FirewallRules: []FirewallRule{
+			{Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: &FirewallService{ID: "s-http"}},
+		},
+	}
+
+	if findings := LintFirewallPolicy(policy, testFirewallObjects()); len(findings) != 0 {
+		t.Errorf("expected no findings with default sensitive ports; got %v", findings)
+	}
+
+	findings := FirewallLinter{SensitivePorts: []int{80}}.Lint(policy, testFirewallObjects())
+
+	if len(findings) != 1 || findings[0].Code != LintCodeOpenSensitivePort || findings[0].Position != 1 {
+		t.Errorf("expected open sensitive port finding at position 1; got %v", findings)
+	}
+}
+
+func TestLintFirewallPolicy_AnyProtocol(t *testing.T) {
+	objects := testFirewallObjects()
+	objects.Services = append(objects.Services, FirewallService{ID: "s-any", Name: "all", Protocol: "ANY", Port: "80"})
+
+	policy := FirewallPolicy{
+		FirewallRules: []FirewallRule{
+			{ID: "r1", Position: 1, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "z-any", Kind: "FirewallZone"}, FirewallService: &FirewallService{ID: "s-any"}},
+			{ID: "r2", Position: 2, Chain: "INPUT", Action: "DROP", Active: Bool(true), FirewallService: &FirewallService{ID: "s-ssh"}},
+		},
+	}
+
+	findings := LintFirewallPolicy(policy, objects)
+
+	if len(findings) != 2 ||
+		findings[0].Code != LintCodeOpenSensitivePort || findings[0].Position != 1 ||
+		findings[1].Code != LintCodeShadowedRule || findings[1].Position != 2 || findings[1].RelatedPosition != 1 {
+		t.Errorf("expected open sensitive port at 1 and rule 2 shadowed by rule 1; got %v", findings)
+	}
+}
@@ -0,0 +1,329 @@
+package cphalo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// FirewallObjects represent CPHalo firewall zones, services and interfaces,
+// which firewall rules refer to.
+type FirewallObjects struct {
+	Zones      []FirewallZone
+	Services   []FirewallService
+	Interfaces []FirewallInterface
+}
+
+// ListFirewallObjects lists all firewall zones, services and interfaces.
+func (c *Client) ListFirewallObjects() (objects FirewallObjects, err error) {
+	zones, err := c.ListFirewallZones()
+	if err != nil {
+		return objects, fmt.Errorf("cannot list firewall zones: %v", err)
+	}
+
+	services, err := c.ListFirewallServices()
+	if err != nil {
+		return objects, fmt.Errorf("cannot list firewall services: %v", err)
+	}
+
+	interfaces, err := c.ListFirewallInterfaces()
+	if err != nil {
+		return objects, fmt.Errorf("cannot list firewall interfaces: %v", err)
+	}
+
+	objects.Zones = zones.Zones
+	objects.Services = services.Services
+	objects.Interfaces = interfaces.Interfaces
+
+	return objects, nil
+}
+
+// Zone returns the firewall zone with the given ID. Zones are matched
+// by name when no zone has such ID, so rules referring zones by name can be
+// resolved too.
+func (o FirewallObjects) Zone(ID string) (FirewallZone, bool) {
+	if ID == "" {
+		return FirewallZone{}, false
+	}
+
+	for _, z := range o.Zones {
+		if z.ID == ID {
+			return z, true
+		}
+	}
+
+	for _, z := range o.Zones {
+		if z.Name == ID {
+			return z, true
+		}
+	}
+
+	return FirewallZone{}, false
+}
+
+// Service returns the firewall service with the given ID. Services are
+// matched by name when no service has such ID.
+func (o FirewallObjects) Service(ID string) (FirewallService, bool) {
+	if ID == "" {
+		return FirewallService{}, false
+	}
+
+	for _, s := range o.Services {
+		if s.ID == ID {
+			return s, true
+		}
+	}
+
+	for _, s := range o.Services {
+		if s.Name == ID {
+			return s, true
+		}
+	}
+
+	return FirewallService{}, false
+}
+
+// Interface returns the firewall interface with the given ID. Interfaces
+// are matched by name when no interface has such ID.
+func (o FirewallObjects) Interface(ID string) (FirewallInterface, bool) {
+	if ID == "" {
+		return FirewallInterface{}, false
+	}
+
+	for _, i := range o.Interfaces {
+		if i.ID == ID {
+			return i, true
+		}
+	}
+
+	for _, i := range o.Interfaces {
+		if i.Name == ID {
+			return i, true
+		}
+	}
+
+	return FirewallInterface{}, false
+}
+
+// orderedFirewallRules returns policy rules in the order of evaluation.
+// Rules without position follow the positioned ones in their listed order.
+func orderedFirewallRules(policy FirewallPolicy) []FirewallRule {
+	rules := append([]FirewallRule{}, policy.FirewallRules...)
+
+	key := func(r FirewallRule) int {
+		if r.Position == 0 {
+			return math.MaxInt32
+		}
+		return r.Position
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		return key(rules[i]) < key(rules[j])
+	})
+
+	return rules
+}
+
+// ruleEndpoint is a rule source or target resolved for matching, nil
+// meaning any endpoint.
+type ruleEndpoint struct {
+	kind  FirewallEndpointKind
+	id    string
+	name  string
+	addrs IPSet
+}
+
+// covers reports whether the endpoint e matches everything matched by o.
+func (e *ruleEndpoint) covers(o *ruleEndpoint) bool {
+	switch {
+	case e == nil:
+		return true
+	case e.kind == FirewallEndpointZone && isAnyAddress(e.addrs):
+		return true
+	case o == nil:
+		return false
+	case e.kind == FirewallEndpointZone && o.kind == FirewallEndpointZone:
+		return addrsCover(e.addrs, o.addrs)
+	case e.kind == FirewallEndpointGroup && e.id == FirewallAllActiveServers:
+		return o.kind == FirewallEndpointGroup
+	case e.kind == FirewallEndpointUserGroup && e.id == FirewallAllGhostPortsUsers:
+		return o.kind == FirewallEndpointUser || o.kind == FirewallEndpointUserGroup
+	}
+
+	return e.kind == o.kind && e.id == o.id
+}
+
+// String returns a human-readable form of the endpoint.
+func (e *ruleEndpoint) String() string {
+	if e == nil {
+		return "any"
+	}
+
+	return string(e.kind) + " " + e.name
+}
+
+// resolvedRule is a firewall rule with its references resolved for matching.
+type resolvedRule struct {
+	rule     FirewallRule
+	position int
+	states   map[string]bool
+	service  string
+	protocol string
+	ports    []PortRange
+	iface    string
+	source   *ruleEndpoint
+	target   *ruleEndpoint
+	missing  []string
+	invalid  []string
+}
+
+// resolveFirewallRules resolves rules of the policy in the order of evaluation.
+func resolveFirewallRules(policy FirewallPolicy, objects FirewallObjects) []resolvedRule {
+	var resolved []resolvedRule
+
+	for i, r := range orderedFirewallRules(policy) {
+		position := r.Position
+		if position == 0 {
+			position = i + 1
+		}
+		resolved = append(resolved, resolveFirewallRule(r, position, objects))
+	}
+
+	return resolved
+}
+
+func resolveFirewallRule(r FirewallRule, position int, objects FirewallObjects) resolvedRule {
+	rr := resolvedRule{rule: r, position: position}
+
+	for _, s := range strings.Split(r.ConnectionStates, ",") {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s == "" || s == "ANY" {
+			continue
+		}
+		if rr.states == nil {
+			rr.states = map[string]bool{}
+		}
+		rr.states[s] = true
+	}
+
+	rr.source = rr.resolveEndpoint(r.FirewallSource, objects)
+	rr.target = rr.resolveEndpoint(r.FirewallTarget, objects)
+
+	if r.FirewallService != nil {
+		service, ok := objects.Service(refName(r.FirewallService.ID, r.FirewallService.Name))
+		if !ok && r.FirewallService.Protocol != "" {
+			service, ok = *r.FirewallService, true
+		}
+
+		if !ok {
+			rr.service = refName(r.FirewallService.Name, r.FirewallService.ID)
+			rr.missing = append(rr.missing, "service "+refName(r.FirewallService.ID, r.FirewallService.Name))
+		} else {
+			var err error
+			rr.service = service.Name
+			rr.protocol = string(service.Protocol.Normalize())
+			if service.Protocol.HasPorts() {
+				if rr.ports, err = ParsePortRanges(service.Port); err != nil {
+					rr.invalid = append(rr.invalid, fmt.Sprintf("service %s: %v", service.Name, err))
+				}
+			}
+		}
+	}
+
+	if r.FirewallInterface != nil {
+		iface, ok := objects.Interface(refName(r.FirewallInterface.ID, r.FirewallInterface.Name))
+
+		if !ok {
+			rr.missing = append(rr.missing, "interface "+refName(r.FirewallInterface.ID, r.FirewallInterface.Name))
+			rr.iface = r.FirewallInterface.Name
+		} else {
+			rr.iface = iface.Name
+		}
+	}
+
+	return rr
+}
+
+func (rr *resolvedRule) resolveEndpoint(st *FirewallRuleSourceTarget, objects FirewallObjects) *ruleEndpoint {
+	if st == nil {
+		return nil
+	}
+
+	e := &ruleEndpoint{kind: st.Kind, id: st.GetID(), name: refName(st.Name, st.GetID())}
+
+	if st.Kind != FirewallEndpointZone {
+		return e
+	}
+
+	zone, ok := objects.Zone(st.GetID())
+	if !ok && st.IPAddress != "" {
+		set, err := parseZoneAddresses(st.IPAddress)
+		if err != nil {
+			rr.invalid = append(rr.invalid, fmt.Sprintf("zone %s: %v", e.name, err))
+			return e
+		}
+		zone, ok = FirewallZone{Name: e.name, IPAddress: set}, true
+	}
+
+	if !ok {
+		rr.missing = append(rr.missing, "zone "+e.name)
+		return e
+	}
+
+	e.name = zone.Name
+	e.addrs = zone.IPAddress
+
+	if e.addrs.IsEmpty() {
+		rr.invalid = append(rr.invalid, fmt.Sprintf("zone %s has no addresses", zone.Name))
+	}
+
+	return e
+}
+
+// covers reports whether the rule r matches every packet matched by o.
+func (r resolvedRule) covers(o resolvedRule) bool {
+	if r.rule.Chain != o.rule.Chain {
+		return false
+	}
+
+	if r.iface != "" && r.iface != o.iface {
+		return false
+	}
+
+	if r.states != nil {
+		if o.states == nil {
+			return false
+		}
+		for s := range o.states {
+			if !r.states[s] {
+				return false
+			}
+		}
+	}
+
+	if !r.anyProtocol() {
+		if r.protocol != o.protocol || !portsCover(r.ports, o.ports) {
+			return false
+		}
+	}
+
+	return r.source.covers(o.source) && r.target.covers(o.target)
+}
+
+// anyProtocol reports whether the rule matches packets of every protocol on
+// any port, which are rules without a service or with an ANY service.
+func (r resolvedRule) anyProtocol() bool {
+	return r.protocol == "" || r.protocol == string(FirewallProtocolANY)
+}
+
+// refName returns the first non-empty reference.
+func refName(refs ...string) string {
+	for _, r := range refs {
+		if r != "" {
+			return r
+		}
+	}
+
+	return ""
+}
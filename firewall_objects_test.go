@@ -0,0 +1,196 @@
+package cphalo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testFirewallObjects returns objects used by firewall analysis tests.
+func testFirewallObjects() FirewallObjects {
+	return FirewallObjects{
+		Zones: []FirewallZone{
+			{ID: "z-any", Name: "any", IPAddress: MustParseIPSet("0.0.0.0/0"), System: true},
+			{ID: "z-office", Name: "office", IPAddress: MustParseIPSet("10.0.0.0/8", "192.168.1.1")},
+			{ID: "z-vpn", Name: "vpn", IPAddress: MustParseIPSet("10.1.0.0/16")},
+			{ID: "z-empty", Name: "empty"},
+		},
+		Services: []FirewallService{
+			{ID: "s-ssh", Name: "ssh", Protocol: "TCP", Port: "22"},
+			{ID: "s-http", Name: "http", Protocol: "TCP", Port: "80"},
+			{ID: "s-web", Name: "web", Protocol: "TCP", Port: "80,443"},
+			{ID: "s-db", Name: "postgres", Protocol: "TCP", Port: "5432"},
+			{ID: "s-dns", Name: "dns", Protocol: "UDP", Port: "53"},
+			{ID: "s-high", Name: "high", Protocol: "TCP", Port: "1024:65535"},
+		},
+		Interfaces: []FirewallInterface{
+			{ID: "i-eth0", Name: "eth0", System: true},
+			{ID: "i-eth1", Name: "eth1"},
+		},
+	}
+}
+
+func TestClient_ListFirewallObjects(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/firewall_zones":      jsonResponseTestHandler(t, "firewall_zones_list", http.StatusOK),
+			"GET /v1/firewall_services":   jsonResponseTestHandler(t, "firewall_services_list", http.StatusOK),
+			"GET /v1/firewall_interfaces": jsonResponseTestHandler(t, "firewall_interfaces_list", http.StatusOK),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	objects, err := client.ListFirewallObjects()
+
+	if err != nil {
+		t.Fatalf("firewall objects list failed: %v", err)
+	}
+
+	if len(objects.Zones) == 0 || len(objects.Services) == 0 || len(objects.Interfaces) == 0 {
+		t.Errorf("expected zones, services and interfaces; got %+v", objects)
+	}
+
+	if _, ok := objects.Zone(objects.Zones[0].ID); !ok {
+		t.Errorf("expected zone %s to be found by ID", objects.Zones[0].ID)
+	}
+}
+
+func TestFirewallObjects_Lookup(t *testing.T) {
+	objects := testFirewallObjects()
+
+	if z, ok := objects.Zone("office"); !ok || z.ID != "z-office" {
+		t.Errorf("expected zone office to be found by name; got %+v", z)
+	}
+
+	if s, ok := objects.Service("s-dns"); !ok || s.Name != "dns" {
+		t.Errorf("expected service s-dns to be found by ID; got %+v", s)
+	}
+
+	if _, ok := objects.Interface("eth9"); ok {
+		t.Errorf("expected interface eth9 not to be found")
+	}
+}
+
+func TestOrderedFirewallRules(t *testing.T) {
+	policy := FirewallPolicy{FirewallRules: []FirewallRule{
+		{ID: "a"}, {ID: "b", Position: 3}, {ID: "c"}, {ID: "d", Position: 1}, {ID: "e", Position: 2}, {ID: "f"},
+	}}
+
+	var got []string
+	for _, r := range orderedFirewallRules(policy) {
+		got = append(got, r.ID)
+	}
+
+	if expected := "d,e,b,a,c,f"; strings.Join(got, ",") != expected {
+		t.Errorf("expected rules in order %s; got %s", expected, strings.Join(got, ","))
+	}
+}
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		ports    string
+		expected []PortRange
+		err      bool
+	}{
+		{"", nil, false},
+		{"22", []PortRange{{22, 22}}, false},
+		{"80, 443", []PortRange{{80, 80}, {443, 443}}, false},
+		{"1000:2000", []PortRange{{1000, 2000}}, false},
+		{"1000-2000", []PortRange{{1000, 2000}}, false},
+		{"2000:1000", nil, true},
+		{"http", nil, true},
+		{"70000", nil, true},
+	}
+
+	for _, tt := range tests {
+		ranges, err := ParsePortRanges(tt.ports)
+
+		if (err != nil) != tt.err {
+			t.Errorf("%q: expected error %v; got %v", tt.ports, tt.err, err)
+			continue
+		}
+
+		if len(ranges) != len(tt.expected) {
+			t.Errorf("%q: expected %v; got %v", tt.ports, tt.expected, ranges)
+			continue
+		}
+
+		for i := range ranges {
+			if ranges[i] != tt.expected[i] {
+				t.Errorf("%q: expected %v; got %v", tt.ports, tt.expected, ranges)
+			}
+		}
+	}
+}
+
+func TestPortsCover(t *testing.T) {
+	parse := func(s string) []PortRange {
+		r, err := ParsePortRanges(s)
+		if err != nil {
+			t.Fatalf("cannot parse ports %q: %v", s, err)
+		}
+		return r
+	}
+
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"", "22", true},
+		{"22", "", false},
+		{"1:100", "22,80", true},
+		{"1:50,51:100", "40:60", true},
+		{"1:50,52:100", "40:60", false},
+		{"80,443", "443", true},
+	}
+
+	for _, tt := range tests {
+		if got := portsCover(parse(tt.a), parse(tt.b)); got != tt.expected {
+			t.Errorf("expected %q covering %q to be %v; got %v", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
+
+func TestAddrsCover(t *testing.T) {
+	parse := func(ips ...string) []addrRange {
+		r, err := parseAddrRanges(ips)
+		if err != nil {
+			t.Fatalf("cannot parse addresses %v: %v", ips, err)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name     string
+		a, b     []addrRange
+		expected bool
+	}{
+		{"network covers host", parse("10.0.0.0/8"), parse("10.1.2.3"), true},
+		{"network covers subnet", parse("10.0.0.0/8"), parse("10.1.0.0/16"), true},
+		{"subnet does not cover network", parse("10.1.0.0/16"), parse("10.0.0.0/8"), false},
+		{"adjacent networks merge", parse("10.0.0.0/25", "10.0.0.128/25"), parse("10.0.0.0/24"), true},
+		{"range covers hosts", parse("10.0.0.1-10.0.0.9"), parse("10.0.0.2", "10.0.0.9"), true},
+		{"v4 does not cover v6", parse("0.0.0.0/0"), parse("::1"), false},
+	}
+
+	for _, tt := range tests {
+		if got := addrsCover(tt.a, tt.b); got != tt.expected {
+			t.Errorf("%s: expected %v; got %v", tt.name, tt.expected, got)
+		}
+	}
+
+	if _, err := parseAddrRanges(IPList{"10.0.0.300"}); err == nil {
+		t.Errorf("expected invalid address to fail")
+	}
+}
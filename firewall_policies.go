@@ -19,6 +19,33 @@ type FirewallPolicy struct {
 	IgnoreForwardingRules bool           `json:"ignore_forwarding_rules,omitempty"`
 }
 
+// Validate checks the policy has a name, a known platform and valid rules.
+func (p FirewallPolicy) Validate() error {
+	return p.validate(false)
+}
+
+// validate checks the policy, the name is not required when partial, as
+// updates change only the fields which are set.
+func (p FirewallPolicy) validate(partial bool) error {
+	if p.Name == "" && !partial {
+		return fmt.Errorf("policy without name")
+	}
+
+	switch p.Platform {
+	case "", "linux", "windows":
+	default:
+		return fmt.Errorf("invalid platform %q", p.Platform)
+	}
+
+	for i, r := range p.FirewallRules {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("rule %d: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
+
 // ListFirewallPoliciesResponse represent a list of firewall policies response.
 type ListFirewallPoliciesResponse struct {
 	Count    int              `json:"count"`
@@ -73,10 +100,15 @@ func (c *Client) GetFirewallPolicy(ID string) (response GetFirewallPolicyRespons
 	return response, nil
 }
 
-// CreateFirewallPolicy creates a new firewall policy.
+// CreateFirewallPolicy creates a new firewall policy. The policy is
+// validated before the request is made.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#create-new-firewall-policy
 func (c *Client) CreateFirewallPolicy(policy FirewallPolicy) (response CreateFirewallPolicyResponse, err error) {
+	if err := policy.Validate(); err != nil {
+		return response, fmt.Errorf("invalid firewall policy: %v", err)
+	}
+
 	req, err := c.newRequest(http.MethodPost, "firewall_policies", nil, CreateFirewallPolicyRequest{Policy: policy})
 	if err != nil {
 		return response, fmt.Errorf("cannot create new create request: %v", err)
@@ -90,10 +122,15 @@ func (c *Client) CreateFirewallPolicy(policy FirewallPolicy) (response CreateFir
 	return response, nil
 }
 
-// UpdateFirewallPolicy updates firewall policy.
+// UpdateFirewallPolicy updates firewall policy. Fields of the policy, which
+// are set, are validated before the request is made.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#update-name-or-description-for-the-firewall-policy
 func (c *Client) UpdateFirewallPolicy(policy FirewallPolicy) error {
+	if err := policy.validate(true); err != nil {
+		return fmt.Errorf("invalid firewall policy: %v", err)
+	}
+
 	req, err := c.newRequest(http.MethodPut, "firewall_policies/"+policy.ID, nil, UpdateFirewallPolicyRequest{Policy: policy})
 	if err != nil {
 		return fmt.Errorf("cannot create new update request: %v", err)
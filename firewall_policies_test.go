@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -209,6 +210,10 @@ func TestClient_UpdateFirewallPolicy(t *testing.T) {
 	if reqBody.Policy.Description != policy.Description {
 		t.Errorf("expected request to contain Description=%s; got %s", policy.Description, reqBody.Policy.Description)
 	}
+
+	if err := client.UpdateFirewallPolicy(FirewallPolicy{ID: "id", Description: "hai"}); err != nil {
+		t.Errorf("expected update without name to be valid; got %v", err)
+	}
 }
 
 func TestClient_DeleteFirewallPolicy(t *testing.T) {
@@ -238,3 +243,30 @@ func TestClient_DeleteFirewallPolicy(t *testing.T) {
 		t.Fatalf("Firewall policy deletion failed: %v", err)
 	}
 }
+
+func TestFirewallPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy FirewallPolicy
+		err    string
+	}{
+		{"valid", FirewallPolicy{Name: "web", Platform: "linux", FirewallRules: []FirewallRule{{Chain: FirewallChainInput, Action: FirewallActionAccept}}}, ""},
+		{"without name", FirewallPolicy{Platform: "linux"}, "policy without name"},
+		{"unknown platform", FirewallPolicy{Name: "web", Platform: "bsd"}, `invalid platform "bsd"`},
+		{"invalid rule", FirewallPolicy{Name: "web", FirewallRules: []FirewallRule{{Chain: FirewallChainInput, Action: FirewallActionAccept}, {Chain: "INPUT"}}}, `rule 2: invalid action ""`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+
+			if tt.err == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("expected error containing %q; got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package cphalo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FirewallProtocol represent a protocol of a firewall service.
+type FirewallProtocol string
+
+// Protocols of firewall services. Services can also use IP protocol numbers,
+// such as "47" for GRE.
+const (
+	FirewallProtocolTCP  FirewallProtocol = "TCP"
+	FirewallProtocolUDP  FirewallProtocol = "UDP"
+	FirewallProtocolICMP FirewallProtocol = "ICMP"
+	FirewallProtocolANY  FirewallProtocol = "ANY"
+)
+
+// Normalize returns the protocol in upper case.
+func (p FirewallProtocol) Normalize() FirewallProtocol {
+	return FirewallProtocol(strings.ToUpper(strings.TrimSpace(string(p))))
+}
+
+// Validate checks the protocol is TCP, UDP, ICMP, ANY or an IP protocol number.
+func (p FirewallProtocol) Validate() error {
+	switch p.Normalize() {
+	case FirewallProtocolTCP, FirewallProtocolUDP, FirewallProtocolICMP, FirewallProtocolANY:
+		return nil
+	}
+
+	if n, err := strconv.Atoi(string(p)); err == nil && n >= 0 && n <= 255 {
+		return nil
+	}
+
+	return fmt.Errorf("invalid protocol %q", string(p))
+}
+
+// HasPorts reports whether services of the protocol are limited to ports,
+// which are ICMP types for ICMP.
+func (p FirewallProtocol) HasPorts() bool {
+	switch p.Normalize() {
+	case FirewallProtocolTCP, FirewallProtocolUDP, FirewallProtocolICMP:
+		return true
+	}
+
+	return false
+}
+
+// PortRanges returns the parsed ports of the service, nil meaning all ports.
+func (s FirewallService) PortRanges() ([]PortRange, error) {
+	if !s.Protocol.HasPorts() {
+		return nil, nil
+	}
+
+	ranges, err := ParsePortRanges(s.Port)
+	if err != nil {
+		return nil, fmt.Errorf("service %s: %v", s.Name, err)
+	}
+
+	return ranges, nil
+}
+
+// Validate checks the service has a valid protocol and ports. ICMP services
+// use a single ICMP type as port, other protocols than TCP and UDP no port.
+func (s FirewallService) Validate() error {
+	return s.validate(false)
+}
+
+// validate checks the service, the protocol is not required when partial, as
+// updates change only the fields which are set. Ports without a protocol are
+// checked only for their syntax then.
+func (s FirewallService) validate(partial bool) error {
+	if partial && s.Protocol == "" {
+		if strings.TrimSpace(s.Port) == "" {
+			return nil
+		}
+		if _, err := ParsePortRanges(s.Port); err != nil {
+			return fmt.Errorf("service %s: %v", s.Name, err)
+		}
+		return nil
+	}
+
+	if err := s.Protocol.Validate(); err != nil {
+		return fmt.Errorf("service %s: %v", s.Name, err)
+	}
+
+	if !s.Protocol.HasPorts() {
+		if strings.TrimSpace(s.Port) != "" {
+			return fmt.Errorf("service %s: protocol %s has no ports", s.Name, s.Protocol)
+		}
+		return nil
+	}
+
+	ranges, err := s.PortRanges()
+	if err != nil {
+		return err
+	}
+
+	if s.Protocol.Normalize() == FirewallProtocolICMP && (len(ranges) > 1 || (len(ranges) == 1 && (ranges[0].From != ranges[0].To || ranges[0].To > 255))) {
+		return fmt.Errorf("service %s: invalid ICMP type %q", s.Name, s.Port)
+	}
+
+	return nil
+}
+
+// Matches reports whether traffic of the protocol to the port is matched by
+// the service. Port is the ICMP type for ICMP.
+func (s FirewallService) Matches(protocol FirewallProtocol, port int) bool {
+	if !s.matchesProtocol(protocol) {
+		return false
+	}
+
+	ranges, err := s.PortRanges()
+
+	return err == nil && portsContain(ranges, port)
+}
+
+// Overlaps reports whether some traffic is matched by both services.
+// Services with invalid ports overlap nothing.
+func (s FirewallService) Overlaps(o FirewallService) bool {
+	if !s.matchesProtocol(o.Protocol) && !o.matchesProtocol(s.Protocol) {
+		return false
+	}
+
+	a, err := s.PortRanges()
+	if err != nil {
+		return false
+	}
+
+	b, err := o.PortRanges()
+	if err != nil {
+		return false
+	}
+
+	if a == nil || b == nil {
+		return true
+	}
+
+	for _, ra := range a {
+		for _, rb := range b {
+			if ra.From <= rb.To && rb.From <= ra.To {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (s FirewallService) matchesProtocol(protocol FirewallProtocol) bool {
+	own := s.Protocol.Normalize()
+
+	return own == FirewallProtocolANY || own == protocol.Normalize()
+}
+
+// FindFirewallServices returns services matching traffic of the protocol to
+// the port, services with the fewest ports first.
+func (c *Client) FindFirewallServices(protocol FirewallProtocol, port int) ([]FirewallService, error) {
+	resp, err := c.ListFirewallServices()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list firewall services: %v", err)
+	}
+
+	return findFirewallServices(resp.Services, protocol, port), nil
+}
+
+func findFirewallServices(services []FirewallService, protocol FirewallProtocol, port int) []FirewallService {
+	var found []FirewallService
+
+	for _, s := range services {
+		if s.Matches(protocol, port) {
+			found = append(found, s)
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].portCount() < found[j].portCount() })
+
+	return found
+}
+
+// portCount returns the number of ports matched by the service, services
+// of any protocol counting more than services with all ports.
+func (s FirewallService) portCount() int {
+	if s.Protocol.Normalize() == FirewallProtocolANY {
+		return 2 * 65536
+	}
+
+	ranges, _ := s.PortRanges()
+	if ranges == nil {
+		return 65536
+	}
+
+	count := 0
+	for _, r := range ranges {
+		count += r.To - r.From + 1
+	}
+
+	return count
+}
+
+// PortRange represent an inclusive range of ports.
+type PortRange struct {
+	From int
+	To   int
+}
+
+// ParsePortRanges parses a firewall service port definition, e.g. "22",
+// "8000:8080" or "80,443". Empty definition results in nil meaning all ports.
+func ParsePortRanges(ports string) ([]PortRange, error) {
+	var ranges []PortRange
+
+	for _, p := range strings.Split(ports, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		bounds := strings.FieldsFunc(p, func(r rune) bool { return r == ':' || r == '-' })
+		if len(bounds) == 0 || len(bounds) > 2 {
+			return nil, fmt.Errorf("invalid port %q", p)
+		}
+
+		var r PortRange
+		var err error
+
+		if r.From, err = strconv.Atoi(bounds[0]); err != nil {
+			return nil, fmt.Errorf("invalid port %q", p)
+		}
+
+		r.To = r.From
+		if len(bounds) == 2 {
+			if r.To, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("invalid port %q", p)
+			}
+		}
+
+		if r.From < 0 || r.To > 65535 || r.From > r.To {
+			return nil, fmt.Errorf("invalid port %q", p)
+		}
+
+		ranges = append(ranges, r)
+	}
+
+	return ranges, nil
+}
+
+// formatPorts returns port ranges in the firewall service port format.
+func formatPorts(ranges []PortRange) []string {
+	var ports []string
+
+	for _, r := range ranges {
+		if r.From == r.To {
+			ports = append(ports, strconv.Itoa(r.From))
+		} else {
+			ports = append(ports, fmt.Sprintf("%d:%d", r.From, r.To))
+		}
+	}
+
+	return ports
+}
+
+// portsCover reports whether port ranges a include all ports of b, nil meaning all ports.
+func portsCover(a, b []PortRange) bool {
+	if a == nil {
+		return true
+	}
+	if b == nil {
+		return false
+	}
+
+	merged := append([]PortRange{}, a...)
+	sort.Slice(merged, func(i, j int) bool { return merged[i].From < merged[j].From })
+
+	for i := 0; i < len(merged)-1; {
+		if merged[i].To+1 >= merged[i+1].From {
+			if merged[i].To < merged[i+1].To {
+				merged[i].To = merged[i+1].To
+			}
+			merged = append(merged[:i+1], merged[i+2:]...)
+			continue
+		}
+		i++
+	}
+
+	for _, rb := range b {
+		covered := false
+		for _, ra := range merged {
+			if rb.From >= ra.From && rb.To <= ra.To {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+
+	return true
+}
+
+// portsContain reports whether the port is in the ranges, nil meaning all ports.
+func portsContain(ranges []PortRange, port int) bool {
+	if ranges == nil {
+		return true
+	}
+
+	for _, r := range ranges {
+		if port >= r.From && port <= r.To {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,153 @@
+package cphalo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestFirewallService_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		service FirewallService
+		valid   bool
+	}{
+		{"tcp port", FirewallService{Protocol: "TCP", Port: "22"}, true},
+		{"lower case", FirewallService{Protocol: "udp", Port: "53,5353"}, true},
+		{"tcp range", FirewallService{Protocol: "TCP", Port: "1024:65535"}, true},
+		{"all ports", FirewallService{Protocol: "TCP"}, true},
+		{"icmp type", FirewallService{Protocol: "ICMP", Port: "8"}, true},
+		{"any", FirewallService{Protocol: "ANY"}, true},
+		{"protocol number", FirewallService{Protocol: "47"}, true},
+		{"no protocol", FirewallService{Port: "22"}, false},
+		{"unknown protocol", FirewallService{Protocol: "SCTP", Port: "22"}, false},
+		{"protocol number out of range", FirewallService{Protocol: "256"}, false},
+		{"port out of range", FirewallService{Protocol: "TCP", Port: "70000"}, false},
+		{"reversed range", FirewallService{Protocol: "TCP", Port: "90:80"}, false},
+		{"typo", FirewallService{Protocol: "TCP", Port: "8o"}, false},
+		{"icmp range", FirewallService{Protocol: "ICMP", Port: "0:8"}, false},
+		{"icmp type out of range", FirewallService{Protocol: "ICMP", Port: "300"}, false},
+		{"any with port", FirewallService{Protocol: "ANY", Port: "22"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.service.Validate()
+
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !tt.valid && err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestFirewallService_Overlaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     FirewallService
+		expected bool
+	}{
+		{"same port", FirewallService{Protocol: "TCP", Port: "22"}, FirewallService{Protocol: "tcp", Port: "22"}, true},
+		{"other protocol", FirewallService{Protocol: "TCP", Port: "53"}, FirewallService{Protocol: "UDP", Port: "53"}, false},
+		{"ranges", FirewallService{Protocol: "TCP", Port: "8000:8080"}, FirewallService{Protocol: "TCP", Port: "80,8080"}, true},
+		{"disjoint ranges", FirewallService{Protocol: "TCP", Port: "8000:8079"}, FirewallService{Protocol: "TCP", Port: "80,8080"}, false},
+		{"all ports", FirewallService{Protocol: "UDP"}, FirewallService{Protocol: "UDP", Port: "53"}, true},
+		{"any protocol", FirewallService{Protocol: "ANY"}, FirewallService{Protocol: "UDP", Port: "53"}, true},
+		{"invalid", FirewallService{Protocol: "TCP", Port: "x"}, FirewallService{Protocol: "TCP"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Overlaps(tt.b); got != tt.expected {
+				t.Errorf("expected %v; got %v", tt.expected, got)
+			}
+
+			if got := tt.b.Overlaps(tt.a); got != tt.expected {
+				t.Errorf("expected symmetric result %v; got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFirewallService_Matches(t *testing.T) {
+	web := FirewallService{Protocol: "TCP", Port: "80,443"}
+
+	if !web.Matches(FirewallProtocolTCP, 443) || !web.Matches("tcp", 80) {
+		t.Error("expected web service to match TCP 80 and 443")
+	}
+
+	if web.Matches(FirewallProtocolUDP, 443) || web.Matches(FirewallProtocolTCP, 8080) {
+		t.Error("expected web service not to match UDP 443 and TCP 8080")
+	}
+}
+
+func TestClient_FindFirewallServices(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/firewall_services": jsonResponseTestHandler(t, "firewall_services_list", http.StatusOK),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	tests := []struct {
+		protocol FirewallProtocol
+		port     int
+		expected string
+	}{
+		{FirewallProtocolTCP, 53, "dns AXFR"},
+		{FirewallProtocolUDP, 53, "dns query"},
+		{"tcp", 5432, "postgresql"},
+		{FirewallProtocolICMP, 8, "ping"},
+	}
+
+	for _, tt := range tests {
+		services, err := client.FindFirewallServices(tt.protocol, tt.port)
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(services) != 1 || services[0].Name != tt.expected {
+			t.Errorf("expected service %s for %s %d; got %+v", tt.expected, tt.protocol, tt.port, services)
+		}
+	}
+
+	if services, _ := client.FindFirewallServices(FirewallProtocolTCP, 1); len(services) != 0 {
+		t.Errorf("expected no service for TCP 1; got %+v", services)
+	}
+}
+
+func TestFindFirewallServices_Order(t *testing.T) {
+	services := []FirewallService{
+		{Name: "any", Protocol: "ANY"},
+		{Name: "all tcp", Protocol: "TCP"},
+		{Name: "high", Protocol: "TCP", Port: "1024:65535"},
+		{Name: "web", Protocol: "TCP", Port: "80,443,8080"},
+		{Name: "alt", Protocol: "TCP", Port: "8080"},
+		{Name: "dns", Protocol: "UDP", Port: "53"},
+	}
+
+	var names []string
+	for _, s := range findFirewallServices(services, FirewallProtocolTCP, 8080) {
+		names = append(names, s.Name)
+	}
+
+	if expected := "alt web high all tcp any"; strings.Join(names, " ") != expected {
+		t.Errorf("expected %s; got %s", expected, strings.Join(names, " "))
+	}
+}
@@ -0,0 +1,196 @@
+package cphalo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Fields of firewall rules referring zones, services and interfaces.
+const (
+	FirewallReferenceSource    = "source"
+	FirewallReferenceTarget    = "target"
+	FirewallReferenceService   = "service"
+	FirewallReferenceInterface = "interface"
+)
+
+// FirewallReference represent a firewall rule referring a zone, service or interface.
+type FirewallReference struct {
+	PolicyID   string `json:"policy_id"`
+	PolicyName string `json:"policy_name"`
+	RuleID     string `json:"rule_id"`
+	Position   int    `json:"position"`
+	Field      string `json:"field"`
+}
+
+// FirewallReferenceIndex answers which firewall rules use a zone, service or interface.
+type FirewallReferenceIndex struct {
+	zones      map[string][]FirewallReference
+	services   map[string][]FirewallReference
+	interfaces map[string][]FirewallReference
+}
+
+// NewFirewallReferenceIndex indexes rules of the policies. Objects are indexed
+// by ID, or by name for rules referring them by name only.
+func NewFirewallReferenceIndex(policies []FirewallPolicy) *FirewallReferenceIndex {
+	idx := &FirewallReferenceIndex{
+		zones:      map[string][]FirewallReference{},
+		services:   map[string][]FirewallReference{},
+		interfaces: map[string][]FirewallReference{},
+	}
+
+	for _, p := range policies {
+		for i, r := range orderedFirewallRules(p) {
+			ref := FirewallReference{PolicyID: p.ID, PolicyName: p.Name, RuleID: r.ID, Position: r.Position}
+			if ref.Position == 0 {
+				ref.Position = i + 1
+			}
+
+			for field, st := range map[string]*FirewallRuleSourceTarget{
+				FirewallReferenceSource: r.FirewallSource,
+				FirewallReferenceTarget: r.FirewallTarget,
+			} {
+				if st != nil && st.Kind == FirewallEndpointZone {
+					ref.Field = field
+					idx.zones[st.GetID()] = append(idx.zones[st.GetID()], ref)
+				}
+			}
+
+			if r.FirewallService != nil {
+				ref.Field = FirewallReferenceService
+				id := refName(r.FirewallService.ID, r.FirewallService.Name)
+				idx.services[id] = append(idx.services[id], ref)
+			}
+
+			if r.FirewallInterface != nil {
+				ref.Field = FirewallReferenceInterface
+				id := refName(r.FirewallInterface.ID, r.FirewallInterface.Name)
+				idx.interfaces[id] = append(idx.interfaces[id], ref)
+			}
+		}
+	}
+
+	for _, refs := range []map[string][]FirewallReference{idx.zones, idx.services, idx.interfaces} {
+		for _, list := range refs {
+			sort.Slice(list, func(i, j int) bool {
+				if list[i].PolicyName != list[j].PolicyName {
+					return list[i].PolicyName < list[j].PolicyName
+				}
+				if list[i].Position != list[j].Position {
+					return list[i].Position < list[j].Position
+				}
+				return list[i].Field < list[j].Field
+			})
+		}
+	}
+
+	return idx
+}
+
+// FirewallReferenceIndex indexes rules of all firewall policies.
+func (c *Client) FirewallReferenceIndex() (*FirewallReferenceIndex, error) {
+	resp, err := c.ListFirewallPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list firewall policies: %v", err)
+	}
+
+	policies := resp.Policies
+
+	for i, p := range policies {
+		rules, err := c.ListFirewallRules(p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list rules of firewall policy %s: %v", p.Name, err)
+		}
+
+		policies[i].FirewallRules = rules.Rules
+	}
+
+	return NewFirewallReferenceIndex(policies), nil
+}
+
+// ZoneReferences returns rules using the firewall zone.
+func (idx *FirewallReferenceIndex) ZoneReferences(ID string) []FirewallReference {
+	return idx.zones[ID]
+}
+
+// ServiceReferences returns rules using the firewall service.
+func (idx *FirewallReferenceIndex) ServiceReferences(ID string) []FirewallReference {
+	return idx.services[ID]
+}
+
+// InterfaceReferences returns rules using the firewall interface.
+func (idx *FirewallReferenceIndex) InterfaceReferences(ID string) []FirewallReference {
+	return idx.interfaces[ID]
+}
+
+// FirewallObjectInUseError is returned when deleting a firewall object used by rules.
+type FirewallObjectInUseError struct {
+	Kind       string
+	ID         string
+	References []FirewallReference
+}
+
+func (e FirewallObjectInUseError) Error() string {
+	var refs []string
+	for _, r := range e.References {
+		refs = append(refs, fmt.Sprintf("%s rule %d (%s)", r.PolicyName, r.Position, r.Field))
+	}
+
+	return fmt.Sprintf("firewall %s %s is used by %d rules: %s", e.Kind, e.ID, len(e.References), strings.Join(refs, ", "))
+}
+
+// SafeDeleteFirewallZone deletes the firewall zone unless it is used by any rule.
+//
+// With cascade, the rules using the zone are deleted first.
+func (c *Client) SafeDeleteFirewallZone(ID string, cascade bool) error {
+	return c.safeDelete("zone", ID, cascade, (*FirewallReferenceIndex).ZoneReferences, c.DeleteFirewallZone)
+}
+
+// SafeDeleteFirewallService deletes the firewall service unless it is used by any rule.
+//
+// With cascade, the rules using the service are deleted first.
+func (c *Client) SafeDeleteFirewallService(ID string, cascade bool) error {
+	return c.safeDelete("service", ID, cascade, (*FirewallReferenceIndex).ServiceReferences, c.DeleteFirewallService)
+}
+
+// SafeDeleteFirewallInterface deletes the firewall interface unless it is used by any rule.
+//
+// With cascade, the rules using the interface are deleted first.
+func (c *Client) SafeDeleteFirewallInterface(ID string, cascade bool) error {
+	return c.safeDelete("interface", ID, cascade, (*FirewallReferenceIndex).InterfaceReferences, c.DeleteFirewallInterface)
+}
+
+func (c *Client) safeDelete(
+	kind, ID string,
+	cascade bool,
+	references func(*FirewallReferenceIndex, string) []FirewallReference,
+	remove func(string) error,
+) error {
+	idx, err := c.FirewallReferenceIndex()
+	if err != nil {
+		return err
+	}
+
+	refs := references(idx, ID)
+
+	if len(refs) > 0 && !cascade {
+		return FirewallObjectInUseError{Kind: kind, ID: ID, References: refs}
+	}
+
+	deleted := map[string]bool{}
+	for _, r := range refs {
+		if deleted[r.PolicyID+"/"+r.RuleID] {
+			continue
+		}
+		if err := c.DeleteFirewallRule(r.PolicyID, r.RuleID); err != nil {
+			return fmt.Errorf("cannot delete rule %d of firewall policy %s: %v", r.Position, r.PolicyName, err)
+		}
+		deleted[r.PolicyID+"/"+r.RuleID] = true
+	}
+
+	if err := remove(ID); err != nil {
+		return fmt.Errorf("cannot delete firewall %s %s: %v", kind, ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package cphalo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNewFirewallReferenceIndex(t *testing.T) {
+	policies := []FirewallPolicy{
+		{
+			ID:   "p2",
+			Name: "web",
+			FirewallRules: []FirewallRule{
+				{ID: "r2", Position: 2, FirewallSource: &FirewallRuleSourceTarget{ID: "z1", Kind: "FirewallZone"}, FirewallService: &FirewallService{ID: "s1"}},
+				{ID: "r1", Position: 1, FirewallTarget: &FirewallRuleSourceTarget{ID: "z1", Kind: "FirewallZone"}, FirewallInterface: &FirewallInterface{ID: "i1"}},
+			},
+		},
+		{
+			ID:   "p1",
+			Name: "db",
+			FirewallRules: []FirewallRule{
+				{ID: "r3", FirewallSource: &FirewallRuleSourceTarget{ID: "g1", Kind: "Group"}, FirewallService: &FirewallService{Name: "ssh"}},
+			},
+		},
+	}
+
+	idx := NewFirewallReferenceIndex(policies)
+
+	zones := idx.ZoneReferences("z1")
+	if len(zones) != 2 {
+		t.Fatalf("expected 2 references of zone z1; got %d", len(zones))
+	}
+
+	if zones[0].RuleID != "r1" || zones[0].Field != FirewallReferenceTarget || zones[1].RuleID != "r2" || zones[1].Field != FirewallReferenceSource {
+		t.Errorf("unexpected zone references %+v", zones)
+	}
+
+	if refs := idx.ZoneReferences("g1"); len(refs) != 0 {
+		t.Errorf("expected groups not to be indexed as zones; got %+v", refs)
+	}
+
+	if refs := idx.ServiceReferences("ssh"); len(refs) != 1 || refs[0].PolicyName != "db" || refs[0].Position != 1 {
+		t.Errorf("expected service ssh to be used by db rule 1; got %+v", refs)
+	}
+
+	if refs := idx.InterfaceReferences("i1"); len(refs) != 1 || refs[0].PolicyID != "p2" {
+		t.Errorf("expected interface i1 to be used by policy p2; got %+v", refs)
+	}
+}
+
+func safeDeleteTestServer(t *testing.T, deletes *[]string) *httptest.Server {
+	recorder := func(w http.ResponseWriter, r *http.Request) {
+		*deletes = append(*deletes, r.RequestURI)
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	rules := "/v1/firewall_policies/%s/firewall_rules"
+	routes := map[string]http.Handler{
+		"GET /v1/firewall_policies":                                  jsonResponseTestHandler(t, "firewall_policies_list", http.StatusOK),
+		"DELETE /v1/firewall_zones/ea81ec609956012ee2db40989asd0980": http.HandlerFunc(recorder),
+		"DELETE /v1/firewall_services/unused":                        http.HandlerFunc(recorder),
+		"DELETE /v1/firewall_policies/be28b106ee5b11eas761017da54e9117/firewall_rules/4a1b810cee5b11e89d0d15ea2eff2521": http.HandlerFunc(recorder),
+	}
+
+	for _, id := range []string{"8cb238a0ee5511e18s9a4d1cedf20253", "1efa203cee5ckka8906e5fe9c3ed9996"} {
+		routes["GET "+fmt.Sprintf(rules, id)] = jsonResponseTestHandler(t, "firewall_rules_list", http.StatusOK)
+	}
+	routes["GET "+fmt.Sprintf(rules, "be28b106ee5b11eas761017da54e9117")] = jsonResponseTestHandler(t, "firewall_rules_list_expanded", http.StatusOK)
+
+	return httptest.NewServer(routesTestHandler(t, routes))
+}
+
+func TestClient_SafeDeleteFirewallZone(t *testing.T) {
+	var (
+		err     error
+		deletes []string
+	)
+
+	ts := safeDeleteTestServer(t, &deletes)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.SafeDeleteFirewallZone("ea81ec609956012ee2db40989asd0980", false)
+
+	var inUse FirewallObjectInUseError
+	if !errors.As(err, &inUse) {
+		t.Fatalf("expected in use error; got %v", err)
+	}
+
+	if len(inUse.References) != 1 || inUse.References[0].PolicyName != "thebestfirewall" {
+		t.Errorf("expected zone to be used by thebestfirewall; got %+v", inUse.References)
+	}
+
+	if !strings.Contains(err.Error(), "is used by 1 rules: thebestfirewall rule 1 (source)") {
+		t.Errorf("unexpected error message %s", err)
+	}
+
+	if len(deletes) != 0 {
+		t.Errorf("expected nothing to be deleted; got %v", deletes)
+	}
+
+	err = client.SafeDeleteFirewallZone("ea81ec609956012ee2db40989asd0980", true)
+
+	if err != nil {
+		t.Fatalf("cascade delete failed: %v", err)
+	}
+
+	expected := "/v1/firewall_policies/be28b106ee5b11eas761017da54e9117/firewall_rules/4a1b810cee5b11e89d0d15ea2eff2521 /v1/firewall_zones/ea81ec609956012ee2db40989asd0980"
+	if strings.Join(deletes, " ") != expected {
+		t.Errorf("expected deletes %s; got %s", expected, strings.Join(deletes, " "))
+	}
+}
+
+func TestClient_SafeDeleteFirewallService(t *testing.T) {
+	var (
+		err     error
+		deletes []string
+	)
+
+	ts := safeDeleteTestServer(t, &deletes)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	if err := client.SafeDeleteFirewallService("unused", false); err != nil {
+		t.Fatalf("delete of unused service failed: %v", err)
+	}
+
+	if len(deletes) != 1 || deletes[0] != "/v1/firewall_services/unused" {
+		t.Errorf("expected unused service to be deleted; got %v", deletes)
+	}
+
+	if err := client.SafeDeleteFirewallInterface("eab41be09956012ee2db4087123ad87s", false); err == nil {
+		t.Errorf("expected used interface not to be deleted")
+	}
+}
@@ -0,0 +1,192 @@
+package cphalo
+
+import (
+	"fmt"
+)
+
+// MoveFirewallRule moves the firewall rule to the position, rules in between shift.
+//
+// Positions start at 1. The final position of the rule is verified.
+func (c *Client) MoveFirewallRule(policyID, ruleID string, position int) error {
+	rules, err := c.listOrderedFirewallRules(policyID)
+	if err != nil {
+		return err
+	}
+
+	current := firewallRuleIndex(rules, ruleID)
+	if current < 0 {
+		return fmt.Errorf("rule %s not found in firewall policy %s", ruleID, policyID)
+	}
+
+	if position < 1 || position > len(rules) {
+		return fmt.Errorf("position %d out of range 1-%d", position, len(rules))
+	}
+
+	if current+1 != position {
+		if err := c.UpdateFirewallRule(policyID, FirewallRule{ID: ruleID, Position: position}); err != nil {
+			return fmt.Errorf("cannot move rule %s to position %d: %v", ruleID, position, err)
+		}
+	}
+
+	return c.verifyFirewallRulePosition(policyID, ruleID, position)
+}
+
+// InsertFirewallRuleBefore creates the firewall rule right before the existing rule.
+func (c *Client) InsertFirewallRuleBefore(policyID, beforeRuleID string, rule FirewallRule) (response CreateFirewallRuleResponse, err error) {
+	return c.insertFirewallRule(policyID, beforeRuleID, 0, rule)
+}
+
+// InsertFirewallRuleAfter creates the firewall rule right after the existing rule.
+func (c *Client) InsertFirewallRuleAfter(policyID, afterRuleID string, rule FirewallRule) (response CreateFirewallRuleResponse, err error) {
+	return c.insertFirewallRule(policyID, afterRuleID, 1, rule)
+}
+
+func (c *Client) insertFirewallRule(policyID, ruleID string, offset int, rule FirewallRule) (response CreateFirewallRuleResponse, err error) {
+	rules, err := c.listOrderedFirewallRules(policyID)
+	if err != nil {
+		return response, err
+	}
+
+	i := firewallRuleIndex(rules, ruleID)
+	if i < 0 {
+		return response, fmt.Errorf("rule %s not found in firewall policy %s", ruleID, policyID)
+	}
+
+	rule.Position = i + 1 + offset
+
+	response, err = c.CreateFirewallRule(policyID, rule)
+	if err != nil {
+		return response, err
+	}
+
+	return response, c.verifyFirewallRulePosition(policyID, response.Rule.ID, rule.Position)
+}
+
+// ReorderFirewallRules reorders rules of the firewall policy to the order of
+// the rule IDs, which has to list all rules of the policy.
+//
+// Rules forming the longest run already in the right relative order stay, each
+// of the other rules is moved right after its predecessor, so the number of
+// updates is minimal. The final order is verified.
+func (c *Client) ReorderFirewallRules(policyID string, ruleIDs []string) error {
+	rules, err := c.listOrderedFirewallRules(policyID)
+	if err != nil {
+		return err
+	}
+
+	if len(ruleIDs) != len(rules) {
+		return fmt.Errorf("expected %d rule IDs; got %d", len(rules), len(ruleIDs))
+	}
+
+	order := make([]string, len(rules))
+	current := map[string]int{}
+	for i, r := range rules {
+		order[i] = r.ID
+		current[r.ID] = i
+	}
+
+	seq := make([]int, len(ruleIDs))
+	seen := map[string]bool{}
+	for i, id := range ruleIDs {
+		pos, ok := current[id]
+		if !ok {
+			return fmt.Errorf("rule %s not found in firewall policy %s", id, policyID)
+		}
+		if seen[id] {
+			return fmt.Errorf("duplicate rule %s", id)
+		}
+		seen[id] = true
+		seq[i] = pos
+	}
+
+	kept := longestIncreasingSubsequence(seq)
+
+	for i, id := range ruleIDs {
+		if kept[i] {
+			continue
+		}
+
+		order = removeString(order, id)
+
+		at := 0
+		if i > 0 {
+			at = indexOfString(order, ruleIDs[i-1]) + 1
+		}
+		order = insertString(order, at, id)
+
+		if err := c.UpdateFirewallRule(policyID, FirewallRule{ID: id, Position: at + 1}); err != nil {
+			return fmt.Errorf("cannot move rule %s to position %d: %v", id, at+1, err)
+		}
+	}
+
+	rules, err = c.listOrderedFirewallRules(policyID)
+	if err != nil {
+		return err
+	}
+
+	for i, r := range rules {
+		if i >= len(ruleIDs) || r.ID != ruleIDs[i] {
+			return fmt.Errorf("rules of firewall policy %s are not in the requested order after reordering", policyID)
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) listOrderedFirewallRules(policyID string) ([]FirewallRule, error) {
+	resp, err := c.ListFirewallRules(policyID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list rules of firewall policy %s: %v", policyID, err)
+	}
+
+	return orderedFirewallRules(FirewallPolicy{FirewallRules: resp.Rules}), nil
+}
+
+func (c *Client) verifyFirewallRulePosition(policyID, ruleID string, position int) error {
+	rules, err := c.listOrderedFirewallRules(policyID)
+	if err != nil {
+		return err
+	}
+
+	if i := firewallRuleIndex(rules, ruleID); i+1 != position {
+		return fmt.Errorf("rule %s is at position %d instead of %d", ruleID, i+1, position)
+	}
+
+	return nil
+}
+
+func firewallRuleIndex(rules []FirewallRule, ID string) int {
+	for i, r := range rules {
+		if r.ID == ID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func indexOfString(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func removeString(list []string, s string) []string {
+	if i := indexOfString(list, s); i >= 0 {
+		return append(list[:i], list[i+1:]...)
+	}
+
+	return list
+}
+
+func insertString(list []string, at int, s string) []string {
+	list = append(list, "")
+	copy(list[at+1:], list[at:])
+	list[at] = s
+
+	return list
+}
@@ -0,0 +1,187 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ruleOrderTestServer keeps rules of a single policy, moving and inserting
+// them on updates and creates with a position.
+type ruleOrderTestServer struct {
+	t       *testing.T
+	order   []string
+	updates int
+}
+
+func (s *ruleOrderTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/v1/firewall_policies/p1/firewall_rules"
+
+	if !strings.HasPrefix(r.RequestURI, prefix) {
+		s.t.Errorf("unexpected request %s %s", r.Method, r.RequestURI)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		// rules are returned in reverse to check they get sorted by position
+		resp := ListFirewallRulesResponse{Count: len(s.order)}
+		for i := len(s.order) - 1; i >= 0; i-- {
+			resp.Rules = append(resp.Rules, FirewallRule{ID: s.order[i], Position: i + 1})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	case http.MethodPut:
+		var req UpdateFirewallRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.t.Fatalf("unmarshalling body failed: %v", err)
+		}
+		id := strings.TrimPrefix(r.RequestURI, prefix+"/")
+		s.order = removeString(s.order, id)
+		s.order = insertString(s.order, req.Rule.Position-1, id)
+		s.updates++
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPost:
+		var req CreateFirewallRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.t.Fatalf("unmarshalling body failed: %v", err)
+		}
+		req.Rule.ID = "new"
+		s.order = insertString(s.order, req.Rule.Position-1, req.Rule.ID)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(req)
+	default:
+		s.t.Errorf("unexpected request %s %s", r.Method, r.RequestURI)
+	}
+}
+
+func ruleOrderTestClient(t *testing.T, order ...string) (*Client, *ruleOrderTestServer, func()) {
+	s := &ruleOrderTestServer{t: t, order: order}
+	ts := httptest.NewServer(authTestHandler(s, t))
+
+	client := NewClient("", "", nil)
+
+	var err error
+	client.baseURL, err = url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("cannot parse URL: %v", err)
+	}
+
+	return client, s, ts.Close
+}
+
+func TestClient_MoveFirewallRule(t *testing.T) {
+	client, s, done := ruleOrderTestClient(t, "a", "b", "c", "d")
+	defer done()
+
+	if err := client.MoveFirewallRule("p1", "d", 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []string{"a", "d", "b", "c"}; !reflect.DeepEqual(s.order, expected) {
+		t.Errorf("expected order %v; got %v", expected, s.order)
+	}
+
+	if err := client.MoveFirewallRule("p1", "d", 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if s.updates != 1 {
+		t.Errorf("expected rule already in position not to be updated; got %d updates", s.updates)
+	}
+
+	if err := client.MoveFirewallRule("p1", "d", 5); err == nil {
+		t.Error("expected error for position out of range")
+	}
+
+	if err := client.MoveFirewallRule("p1", "x", 1); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+}
+
+func TestClient_InsertFirewallRule(t *testing.T) {
+	client, s, done := ruleOrderTestClient(t, "a", "b", "c")
+	defer done()
+
+	resp, err := client.InsertFirewallRuleBefore("p1", "b", FirewallRule{Chain: "INPUT", Action: "ACCEPT"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Rule.ID != "new" || resp.Rule.Position != 2 {
+		t.Errorf("expected new rule at position 2; got %+v", resp.Rule)
+	}
+
+	if expected := []string{"a", "new", "b", "c"}; !reflect.DeepEqual(s.order, expected) {
+		t.Errorf("expected order %v; got %v", expected, s.order)
+	}
+
+	s.order = []string{"a", "b", "c"}
+
+	if _, err := client.InsertFirewallRuleAfter("p1", "c", FirewallRule{Chain: "INPUT", Action: "DROP"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []string{"a", "b", "c", "new"}; !reflect.DeepEqual(s.order, expected) {
+		t.Errorf("expected order %v; got %v", expected, s.order)
+	}
+
+	if _, err := client.InsertFirewallRuleAfter("p1", "x", FirewallRule{}); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+}
+
+func TestClient_ReorderFirewallRules(t *testing.T) {
+	tests := []struct {
+		current, desired []string
+		updates          int
+	}{
+		{[]string{"a", "b", "c", "d"}, []string{"a", "b", "c", "d"}, 0},
+		{[]string{"a", "b", "c", "d"}, []string{"d", "a", "b", "c"}, 1},
+		{[]string{"a", "b", "c", "d"}, []string{"b", "c", "d", "a"}, 1},
+		{[]string{"a", "b", "c", "d"}, []string{"d", "c", "b", "a"}, 3},
+		{[]string{"a", "b", "c", "d", "e"}, []string{"b", "a", "d", "c", "e"}, 2},
+		{[]string{"a", "b", "c", "d", "e"}, []string{"e", "c", "a", "d", "b"}, 3},
+	}
+
+	for _, tt := range tests {
+		client, s, done := ruleOrderTestClient(t, tt.current...)
+
+		if err := client.ReorderFirewallRules("p1", tt.desired); err != nil {
+			t.Errorf("%v -> %v: unexpected error: %v", tt.current, tt.desired, err)
+		}
+
+		if !reflect.DeepEqual(s.order, tt.desired) {
+			t.Errorf("%v -> %v: got %v", tt.current, tt.desired, s.order)
+		}
+
+		if s.updates != tt.updates {
+			t.Errorf("%v -> %v: expected %d updates; got %d", tt.current, tt.desired, tt.updates, s.updates)
+		}
+
+		done()
+	}
+}
+
+func TestClient_ReorderFirewallRules_Invalid(t *testing.T) {
+	client, s, done := ruleOrderTestClient(t, "a", "b", "c")
+	defer done()
+
+	for _, ids := range [][]string{
+		{"a", "b"},
+		{"a", "b", "x"},
+		{"a", "a", "b"},
+	} {
+		if err := client.ReorderFirewallRules("p1", ids); err == nil {
+			t.Errorf("expected error for %v", ids)
+		}
+	}
+
+	if s.updates != 0 {
+		t.Errorf("expected no updates for invalid orders; got %d", s.updates)
+	}
+}
@@ -3,14 +3,65 @@ package cphalo
 import (
 	"fmt"
 	"net/http"
+	"strings"
+)
+
+// FirewallChain represent a chain of a firewall rule.
+type FirewallChain string
+
+// Chains of firewall rules.
+const (
+	FirewallChainInput  FirewallChain = "INPUT"
+	FirewallChainOutput FirewallChain = "OUTPUT"
+)
+
+// FirewallAction represent an action of a firewall rule.
+type FirewallAction string
+
+// Actions of firewall rules.
+const (
+	FirewallActionAccept FirewallAction = "ACCEPT"
+	FirewallActionDrop   FirewallAction = "DROP"
+	FirewallActionReject FirewallAction = "REJECT"
+)
+
+// FirewallConnectionState represent a connection state matched by a firewall
+// rule. Rules list connection states separated by commas.
+type FirewallConnectionState string
+
+// Connection states of firewall rules, FirewallConnectionStateAny matches
+// all states and cannot be combined with others.
+const (
+	FirewallConnectionStateAny         FirewallConnectionState = "ANY"
+	FirewallConnectionStateNew         FirewallConnectionState = "NEW"
+	FirewallConnectionStateEstablished FirewallConnectionState = "ESTABLISHED"
+	FirewallConnectionStateRelated     FirewallConnectionState = "RELATED"
+	FirewallConnectionStateInvalid     FirewallConnectionState = "INVALID"
+)
+
+// FirewallEndpointKind represent a kind of a firewall rule source or target.
+type FirewallEndpointKind string
+
+// Kinds of firewall rule sources and targets.
+const (
+	FirewallEndpointZone      FirewallEndpointKind = "FirewallZone"
+	FirewallEndpointGroup     FirewallEndpointKind = "Group"
+	FirewallEndpointUser      FirewallEndpointKind = "User"
+	FirewallEndpointUserGroup FirewallEndpointKind = "UserGroup"
+)
+
+// Names of sources and targets matching all servers and all GhostPorts users.
+const (
+	FirewallAllActiveServers   = "All Active Servers"
+	FirewallAllGhostPortsUsers = "All GhostPorts users"
 )
 
 // FirewallRuleSourceTarget represent a CPHalo firewall source and target.
 type FirewallRuleSourceTarget struct {
-	ID        string `json:"id,omitempty"`
-	Name      string `json:"name,omitempty"`
-	IPAddress string `json:"ip_address,omitempty"`
-	Kind      string `json:"type,omitempty"`
+	ID        string               `json:"id,omitempty"`
+	Name      string               `json:"name,omitempty"`
+	IPAddress string               `json:"ip_address,omitempty"`
+	Kind      FirewallEndpointKind `json:"type,omitempty"`
 }
 
 // GetID returns ID if exists, otherwise Name
@@ -24,24 +75,42 @@ func (f *FirewallRuleSourceTarget) GetID() string {
 
 // FirewallRule represent a CPHalo firewall rule.
 //
+// Active and Log are pointers, so false is sent when set and updates leave
+// them unchanged when nil.
+//
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#object-representation-7
 type FirewallRule struct {
 	ID                string                    `json:"id,omitempty"`
 	URL               string                    `json:"url,omitempty"`
-	Chain             string                    `json:"chain,omitempty"`
-	Action            string                    `json:"action,omitempty"`
-	Active            bool                      `json:"active,omitempty"`
+	Chain             FirewallChain             `json:"chain,omitempty"`
+	Action            FirewallAction            `json:"action,omitempty"`
+	Active            *bool                     `json:"active,omitempty"`
 	ConnectionStates  string                    `json:"connection_states,omitempty"`
 	Position          int                       `json:"position,omitempty"`
 	FirewallInterface *FirewallInterface        `json:"firewall_interface,omitempty"`
 	FirewallService   *FirewallService          `json:"firewall_service,omitempty"`
 	FirewallSource    *FirewallRuleSourceTarget `json:"firewall_source,omitempty"`
 	FirewallTarget    *FirewallRuleSourceTarget `json:"firewall_target,omitempty"`
-	Log               bool                      `json:"log,omitempty"`
+	Log               *bool                     `json:"log,omitempty"`
 	LogPrefix         string                    `json:"log_prefix,omitempty"`
 	Comment           string                    `json:"comment,omitempty"`
 }
 
+// Bool returns a pointer to v, e.g. for Active and Log of a firewall rule.
+func Bool(v bool) *bool {
+	return &v
+}
+
+// GetActive returns whether the rule is active, rules without Active are not.
+func (r FirewallRule) GetActive() bool {
+	return r.Active != nil && *r.Active
+}
+
+// GetLog returns whether the rule logs matched packets.
+func (r FirewallRule) GetLog() bool {
+	return r.Log != nil && *r.Log
+}
+
 // ListFirewallRulesResponse represent a list of firewall rules response.
 type ListFirewallRulesResponse struct {
 	Count int            `json:"count"`
@@ -63,44 +132,119 @@ type CreateFirewallRuleRequest = GetFirewallRuleResponse
 type UpdateFirewallRuleRequest = GetFirewallRuleResponse
 
 func (r *FirewallRule) applyCorrections() {
-	const (
-		allServers = "All Active Servers"
-		allUsers   = "All GhostPorts users"
-	)
-
-	if r.FirewallSource != nil {
-		switch r.FirewallSource.Kind {
-		case "Group":
-			if r.FirewallSource.ID == allServers {
-				r.FirewallSource.Name = r.FirewallSource.ID
-				r.FirewallSource.ID = ""
-			}
-		case "UserGroup":
-			if r.FirewallSource.ID == allUsers {
-				r.FirewallSource.Name = r.FirewallSource.ID
-				r.FirewallSource.ID = ""
-			}
+	for _, st := range []*FirewallRuleSourceTarget{r.FirewallSource, r.FirewallTarget} {
+		if st != nil && st.isAll() && st.ID != "" {
+			st.Name = st.ID
+			st.ID = ""
 		}
 	}
 
-	if r.FirewallTarget != nil {
-		switch r.FirewallTarget.Kind {
-		case "Group":
-			if r.FirewallTarget.ID == allServers {
-				r.FirewallTarget.Name = r.FirewallTarget.ID
-				r.FirewallTarget.ID = ""
-			}
-		case "UserGroup":
-			if r.FirewallTarget.ID == allUsers {
-				r.FirewallTarget.Name = r.FirewallTarget.ID
-				r.FirewallTarget.ID = ""
+	if FirewallConnectionState(r.ConnectionStates) == FirewallConnectionStateAny {
+		r.ConnectionStates = ""
+	}
+}
+
+// isAll reports whether the endpoint is all active servers or all GhostPorts users.
+func (f *FirewallRuleSourceTarget) isAll() bool {
+	switch f.Kind {
+	case FirewallEndpointGroup:
+		return f.GetID() == FirewallAllActiveServers
+	case FirewallEndpointUserGroup:
+		return f.GetID() == FirewallAllGhostPortsUsers
+	}
+
+	return false
+}
+
+// Validate checks the chain, action, connection states, sources and targets
+// of the rule and their combinations.
+func (r FirewallRule) Validate() error {
+	return r.validate(false)
+}
+
+// validate checks the rule, fields which are not set are not checked when
+// partial, as updates change only the fields which are set.
+func (r FirewallRule) validate(partial bool) error {
+	switch {
+	case partial && r.Chain == "":
+	case r.Chain == FirewallChainInput, r.Chain == FirewallChainOutput:
+	default:
+		return fmt.Errorf("invalid chain %q", r.Chain)
+	}
+
+	switch {
+	case partial && r.Action == "":
+	case r.Action == FirewallActionAccept, r.Action == FirewallActionDrop, r.Action == FirewallActionReject:
+	default:
+		return fmt.Errorf("invalid action %q", r.Action)
+	}
+
+	if _, err := ParseFirewallConnectionStates(r.ConnectionStates); err != nil {
+		return err
+	}
+
+	if r.Position < 0 {
+		return fmt.Errorf("invalid position %d", r.Position)
+	}
+
+	if r.LogPrefix != "" && !r.GetLog() && !(partial && r.Log == nil) {
+		return fmt.Errorf("log prefix %q without log", r.LogPrefix)
+	}
+
+	for i, st := range []*FirewallRuleSourceTarget{r.FirewallSource, r.FirewallTarget} {
+		if st == nil {
+			continue
+		}
+
+		side := "source"
+		if i == 1 {
+			side = "target"
+		}
+
+		switch st.Kind {
+		case FirewallEndpointZone, FirewallEndpointGroup:
+		case FirewallEndpointUser, FirewallEndpointUserGroup:
+			if (r.Chain != "" && r.Chain != FirewallChainInput) || side != "source" {
+				return fmt.Errorf("%s of kind %s is allowed only as source of %s rules", side, st.Kind, FirewallChainInput)
 			}
+		default:
+			return fmt.Errorf("invalid %s kind %q", side, st.Kind)
+		}
+
+		if st.GetID() == "" {
+			return fmt.Errorf("%s %s without ID or name", side, st.Kind)
 		}
 	}
 
-	if r.ConnectionStates == "ANY" {
-		r.ConnectionStates = ""
+	return nil
+}
+
+// ParseFirewallConnectionStates parses connection states of a firewall rule,
+// e.g. "NEW, ESTABLISHED". Empty states and ANY result in nil meaning all states.
+func ParseFirewallConnectionStates(states string) ([]FirewallConnectionState, error) {
+	var parsed []FirewallConnectionState
+	hasAny := false
+
+	for _, s := range strings.Split(states, ",") {
+		state := FirewallConnectionState(strings.ToUpper(strings.TrimSpace(s)))
+
+		switch state {
+		case "":
+			continue
+		case FirewallConnectionStateAny:
+			hasAny = true
+		case FirewallConnectionStateNew, FirewallConnectionStateEstablished, FirewallConnectionStateRelated, FirewallConnectionStateInvalid:
+			parsed = append(parsed, state)
+		default:
+			return nil, fmt.Errorf("invalid connection state %q", strings.TrimSpace(s))
+		}
+	}
+
+	if hasAny && len(parsed) > 0 {
+		return nil, fmt.Errorf("connection state ANY combined with %s", states)
 	}
+
+	return parsed, nil
 }
 
 // ListFirewallRules lists all firewall rules.
@@ -149,10 +293,15 @@ func (c *Client) GetFirewallRule(policyID, ruleID string) (response GetFirewallR
 	return response, nil
 }
 
-// CreateFirewallRule creates a new firewall rule.
+// CreateFirewallRule creates a new firewall rule. The rule is validated
+// before the request is made.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#add-new-firewall-rule-to-the-firewall-policy
 func (c *Client) CreateFirewallRule(policyID string, rule FirewallRule) (response CreateFirewallRuleResponse, err error) {
+	if err := rule.Validate(); err != nil {
+		return response, fmt.Errorf("invalid firewall rule: %v", err)
+	}
+
 	url := fmt.Sprintf("firewall_policies/%s/firewall_rules", policyID)
 	rule.applyCorrections()
 	req, err := c.newRequest(http.MethodPost, url, nil, CreateFirewallRuleRequest{Rule: rule})
@@ -168,10 +317,16 @@ func (c *Client) CreateFirewallRule(policyID string, rule FirewallRule) (respons
 	return response, nil
 }
 
-// UpdateFirewallRule updates firewall rule.
+// UpdateFirewallRule updates firewall rule. Fields of the rule, which are
+// set, are validated before the request is made, so the rule can carry only
+// the fields to change, e.g. its position.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#update-firewall-rule
 func (c *Client) UpdateFirewallRule(policyID string, rule FirewallRule) error {
+	if err := rule.validate(true); err != nil {
+		return fmt.Errorf("invalid firewall rule: %v", err)
+	}
+
 	url := fmt.Sprintf("firewall_policies/%s/firewall_rules/%s", policyID, rule.ID)
 	rule.applyCorrections()
 	req, err := c.newRequest(http.MethodPut, url, nil, UpdateFirewallRuleRequest{Rule: rule})
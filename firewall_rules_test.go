@@ -1,9 +1,12 @@
 package cphalo
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -110,7 +113,7 @@ func TestClient_CreateFirewallRule(t *testing.T) {
 		Action:           "DROP",
 		Position:         1,
 		ConnectionStates: "NEW",
-		Log:              true,
+		Log:              Bool(true),
 		LogPrefix:        "test_",
 		Comment:          "test",
 	}
@@ -146,8 +149,8 @@ func TestClient_CreateFirewallRule(t *testing.T) {
 		t.Errorf("expected request to contain ConnectionStates=%s; got %s", rule.ConnectionStates, reqBody.Rule.ConnectionStates)
 	}
 
-	if reqBody.Rule.Log != rule.Log {
-		t.Errorf("expected request to contain Log=%t; got %t", rule.Log, reqBody.Rule.Log)
+	if reqBody.Rule.GetLog() != rule.GetLog() {
+		t.Errorf("expected request to contain Log=%t; got %t", rule.GetLog(), reqBody.Rule.GetLog())
 	}
 
 	if reqBody.Rule.LogPrefix != rule.LogPrefix {
@@ -187,7 +190,7 @@ func TestClient_UpdateFirewallRule(t *testing.T) {
 		Action:           "DROP",
 		Position:         1,
 		ConnectionStates: "NEW",
-		Log:              true,
+		Log:              Bool(true),
 		LogPrefix:        "test_",
 		Comment:          "test",
 	}
@@ -218,8 +221,8 @@ func TestClient_UpdateFirewallRule(t *testing.T) {
 		t.Errorf("expected response to contain ConnectionStates=%s; got %s", rule.ConnectionStates, reqBody.Rule.ConnectionStates)
 	}
 
-	if reqBody.Rule.Log != rule.Log {
-		t.Errorf("expected response to contain Log=%t; got %t", rule.Log, reqBody.Rule.Log)
+	if reqBody.Rule.GetLog() != rule.GetLog() {
+		t.Errorf("expected response to contain Log=%t; got %t", rule.GetLog(), reqBody.Rule.GetLog())
 	}
 
 	if reqBody.Rule.LogPrefix != rule.LogPrefix {
@@ -231,6 +234,47 @@ func TestClient_UpdateFirewallRule(t *testing.T) {
 	}
 }
 
+func TestClient_UpdateFirewallRule_Partial(t *testing.T) {
+	var err error
+	reqBody := map[string]map[string]json.RawMessage{}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"PUT /v1/firewall_policies/123/firewall_rules/id": bodyRecorderTestHandler(t, jsonResponseTestHandler(t, "", http.StatusOK), &reqBody),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	if err := client.UpdateFirewallRule("123", FirewallRule{ID: "id", Position: 2}); err != nil {
+		t.Fatalf("Firewall rule updating failed: %v", err)
+	}
+
+	if len(reqBody["firewall_rule"]) != 2 || string(reqBody["firewall_rule"]["position"]) != "2" {
+		t.Errorf("expected request with ID and position only; got %v", reqBody)
+	}
+
+	if err := client.UpdateFirewallRule("123", FirewallRule{ID: "id", Active: Bool(false), Log: Bool(false)}); err != nil {
+		t.Fatalf("Firewall rule updating failed: %v", err)
+	}
+
+	if string(reqBody["firewall_rule"]["active"]) != "false" || string(reqBody["firewall_rule"]["log"]) != "false" {
+		t.Errorf("expected request to turn off active and log; got %v", reqBody)
+	}
+
+	err = client.UpdateFirewallRule("123", FirewallRule{ID: "id", Chain: "FORWARD"})
+
+	if err == nil || !strings.Contains(err.Error(), `invalid chain "FORWARD"`) {
+		t.Errorf("expected invalid chain error; got %v", err)
+	}
+}
+
 func TestClient_DeleteFirewallRule(t *testing.T) {
 	var err error
 
@@ -258,3 +302,115 @@ func TestClient_DeleteFirewallRule(t *testing.T) {
 		t.Fatalf("Firewall rule deletion failed: %v", err)
 	}
 }
+
+func TestFirewallRule_Validate(t *testing.T) {
+	zone := &FirewallRuleSourceTarget{ID: "z1", Kind: FirewallEndpointZone}
+	users := &FirewallRuleSourceTarget{Name: FirewallAllGhostPortsUsers, Kind: FirewallEndpointUserGroup}
+
+	tests := []struct {
+		name string
+		rule FirewallRule
+		err  string
+	}{
+		{"valid input", FirewallRule{Chain: FirewallChainInput, Action: FirewallActionAccept, FirewallSource: zone, ConnectionStates: "NEW, ESTABLISHED"}, ""},
+		{"valid output", FirewallRule{Chain: FirewallChainOutput, Action: FirewallActionReject, FirewallTarget: zone, Log: Bool(true), LogPrefix: "out"}, ""},
+		{"ghostports users", FirewallRule{Chain: FirewallChainInput, Action: FirewallActionAccept, FirewallSource: users}, ""},
+		{"any state", FirewallRule{Chain: FirewallChainInput, Action: FirewallActionDrop, ConnectionStates: "ANY"}, ""},
+		{"invalid chain", FirewallRule{Chain: "FORWARD", Action: FirewallActionDrop}, `invalid chain "FORWARD"`},
+		{"missing action", FirewallRule{Chain: FirewallChainInput}, `invalid action ""`},
+		{"lower case action", FirewallRule{Chain: FirewallChainInput, Action: "accept"}, `invalid action "accept"`},
+		{"unknown state", FirewallRule{Chain: FirewallChainInput, Action: FirewallActionDrop, ConnectionStates: "NEW,CLOSED"}, `invalid connection state "CLOSED"`},
+		{"any with other state", FirewallRule{Chain: FirewallChainInput, Action: FirewallActionDrop, ConnectionStates: "ANY,NEW"}, "ANY combined"},
+		{"log prefix without log", FirewallRule{Chain: FirewallChainInput, Action: FirewallActionDrop, LogPrefix: "x"}, `log prefix "x" without log`},
+		{"negative position", FirewallRule{Chain: FirewallChainInput, Action: FirewallActionDrop, Position: -1}, "invalid position -1"},
+		{"output user group", FirewallRule{Chain: FirewallChainOutput, Action: FirewallActionAccept, FirewallSource: users}, "source of kind UserGroup is allowed only as source of INPUT rules"},
+		{"user target", FirewallRule{Chain: FirewallChainInput, Action: FirewallActionAccept, FirewallTarget: &FirewallRuleSourceTarget{ID: "u1", Kind: FirewallEndpointUser}}, "target of kind User"},
+		{"unknown kind", FirewallRule{Chain: FirewallChainInput, Action: FirewallActionAccept, FirewallSource: &FirewallRuleSourceTarget{ID: "x", Kind: "Zone"}}, `invalid source kind "Zone"`},
+		{"endpoint without id", FirewallRule{Chain: FirewallChainInput, Action: FirewallActionAccept, FirewallSource: &FirewallRuleSourceTarget{Kind: FirewallEndpointGroup}}, "source Group without ID or name"},
+		{"invalid source and target", FirewallRule{Chain: FirewallChainInput, Action: FirewallActionAccept, FirewallSource: &FirewallRuleSourceTarget{ID: "x", Kind: "Zone"}, FirewallTarget: &FirewallRuleSourceTarget{ID: "y", Kind: "Zone"}}, `invalid source kind "Zone"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Validate()
+
+			if tt.err == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("expected error containing %q; got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestParseFirewallConnectionStates(t *testing.T) {
+	states, err := ParseFirewallConnectionStates("new, Established")
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []FirewallConnectionState{FirewallConnectionStateNew, FirewallConnectionStateEstablished}; !reflect.DeepEqual(states, expected) {
+		t.Errorf("expected %v; got %v", expected, states)
+	}
+
+	for _, any := range []string{"", "ANY"} {
+		if states, err := ParseFirewallConnectionStates(any); err != nil || states != nil {
+			t.Errorf("expected %q to mean all states; got %v, %v", any, states, err)
+		}
+	}
+}
+
+func TestFirewallRule_applyCorrections(t *testing.T) {
+	rule := FirewallRule{
+		ConnectionStates: "ANY",
+		FirewallSource:   &FirewallRuleSourceTarget{ID: FirewallAllGhostPortsUsers, Kind: FirewallEndpointUserGroup},
+		FirewallTarget:   &FirewallRuleSourceTarget{ID: FirewallAllActiveServers, Kind: FirewallEndpointGroup},
+	}
+
+	rule.applyCorrections()
+
+	if rule.ConnectionStates != "" {
+		t.Errorf("expected ANY connection states to be cleared; got %s", rule.ConnectionStates)
+	}
+
+	if *rule.FirewallSource != (FirewallRuleSourceTarget{Name: FirewallAllGhostPortsUsers, Kind: FirewallEndpointUserGroup}) {
+		t.Errorf("expected all users to be referred by name; got %+v", rule.FirewallSource)
+	}
+
+	if *rule.FirewallTarget != (FirewallRuleSourceTarget{Name: FirewallAllActiveServers, Kind: FirewallEndpointGroup}) {
+		t.Errorf("expected all servers to be referred by name; got %+v", rule.FirewallTarget)
+	}
+}
+
+func TestClient_CreateFirewallRule_Invalid(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.RequestURI)
+	}))
+	defer ts.Close()
+
+	var err error
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	rule := FirewallRule{Chain: FirewallChainInput, Action: FirewallActionAccept, LogPrefix: "ssh"}
+
+	if _, err := client.CreateFirewallRule("policy", rule); err == nil || !strings.Contains(err.Error(), "invalid firewall rule") {
+		t.Errorf("expected invalid rule error; got %v", err)
+	}
+
+	// Updates leave Log unchanged when nil, so the prefix is invalid only
+	// when logging is turned off.
+	rule.ID = "id"
+	rule.Log = Bool(false)
+
+	if err := client.UpdateFirewallRule("policy", rule); err == nil || !strings.Contains(err.Error(), "invalid firewall rule") {
+		t.Errorf("expected invalid rule error; got %v", err)
+	}
+}
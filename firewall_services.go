@@ -9,12 +9,12 @@ import (
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#object-representation-9
 type FirewallService struct {
-	ID       string `json:"id,omitempty"`
-	URL      string `json:"url,omitempty"`
-	Name     string `json:"name,omitempty"`
-	Protocol string `json:"protocol,omitempty"`
-	Port     string `json:"port,omitempty"`
-	System   bool   `json:"system,omitempty"`
+	ID       string           `json:"id,omitempty"`
+	URL      string           `json:"url,omitempty"`
+	Name     string           `json:"name,omitempty"`
+	Protocol FirewallProtocol `json:"protocol,omitempty"`
+	Port     string           `json:"port,omitempty"`
+	System   bool             `json:"system,omitempty"`
 }
 
 // ListFirewallServicesResponse represent a list of firewall services response.
@@ -71,10 +71,15 @@ func (c *Client) GetFirewallService(ID string) (response GetFirewallServiceRespo
 	return response, nil
 }
 
-// CreateFirewallService creates a new firewall service.
+// CreateFirewallService creates a new firewall service. Protocol and ports
+// of the service are validated before the request is made.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#create-a-new-firewall-service
 func (c *Client) CreateFirewallService(service FirewallService) (response CreateFirewallServiceResponse, err error) {
+	if err := service.Validate(); err != nil {
+		return response, fmt.Errorf("invalid firewall service: %v", err)
+	}
+
 	req, err := c.newRequest(http.MethodPost, "firewall_services", nil, CreateFirewallServiceRequest{Service: service})
 	if err != nil {
 		return response, fmt.Errorf("cannot create new create request: %v", err)
@@ -88,10 +93,16 @@ func (c *Client) CreateFirewallService(service FirewallService) (response Create
 	return response, nil
 }
 
-// UpdateFirewallService updates firewall service.
+// UpdateFirewallService updates firewall service. Protocol and ports of the
+// service, when set, are validated before the request is made, so the service
+// can carry only the fields to change, e.g. its name.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/cloudpassage-api-documentation#firewall-services
 func (c *Client) UpdateFirewallService(service FirewallService) error {
+	if err := service.validate(true); err != nil {
+		return fmt.Errorf("invalid firewall service: %v", err)
+	}
+
 	req, err := c.newRequest(http.MethodPut, "firewall_services/"+service.ID, nil, UpdateFirewallServiceRequest{Service: service})
 	if err != nil {
 		return fmt.Errorf("cannot create new update request: %v", err)
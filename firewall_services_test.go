@@ -1,9 +1,11 @@
 package cphalo
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -218,3 +220,65 @@ func TestClient_DeleteFirewallService(t *testing.T) {
 		t.Fatalf("Firewall service deletion failed: %v", err)
 	}
 }
+
+func TestClient_CreateFirewallService_Invalid(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.RequestURI)
+	}))
+	defer ts.Close()
+
+	var err error
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	service := FirewallService{Name: "irc", Protocol: "TPC", Port: "6667"}
+
+	if _, err := client.CreateFirewallService(service); err == nil || !strings.Contains(err.Error(), `invalid protocol "TPC"`) {
+		t.Errorf("expected invalid protocol error; got %v", err)
+	}
+
+	service.ID = "id"
+	service.Protocol = FirewallProtocolTCP
+	service.Port = "6667-6660"
+
+	if err := client.UpdateFirewallService(service); err == nil || !strings.Contains(err.Error(), `invalid port "6667-6660"`) {
+		t.Errorf("expected invalid port error; got %v", err)
+	}
+
+	service.Protocol = ""
+
+	if err := client.UpdateFirewallService(service); err == nil || !strings.Contains(err.Error(), `invalid port "6667-6660"`) {
+		t.Errorf("expected invalid port error without protocol; got %v", err)
+	}
+}
+
+func TestClient_UpdateFirewallService_Partial(t *testing.T) {
+	var err error
+	reqBody := map[string]map[string]json.RawMessage{}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"PUT /v1/firewall_services/id": bodyRecorderTestHandler(t, jsonResponseTestHandler(t, "", http.StatusOK), &reqBody),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	if err := client.UpdateFirewallService(FirewallService{ID: "id", Name: "irc"}); err != nil {
+		t.Fatalf("firewall service updating failed: %v", err)
+	}
+
+	if _, ok := reqBody["firewall_service"]["protocol"]; ok || string(reqBody["firewall_service"]["name"]) != `"irc"` {
+		t.Errorf("expected request with name only; got %v", reqBody)
+	}
+}
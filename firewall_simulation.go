@@ -0,0 +1,204 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// FirewallPacket represent a packet evaluated by the firewall simulation.
+type FirewallPacket struct {
+	// Chain is INPUT for incoming and OUTPUT for outgoing packets, INPUT when empty.
+	Chain FirewallChain
+	// SourceIP is matched against rule sources.
+	SourceIP string
+	// DestinationIP is matched against rule targets, it can be empty for
+	// packets coming to the protected server itself.
+	DestinationIP string
+	// Protocol is TCP, UDP or ICMP.
+	Protocol string
+	// Port is the destination port or ICMP type.
+	Port int
+	// ConnectionState is NEW, ESTABLISHED, RELATED or INVALID, NEW when empty.
+	ConnectionState string
+	// Interface is the name of the network interface, it matches only rules
+	// without interface when empty.
+	Interface string
+}
+
+// FirewallTraceStep represent evaluation of a single rule during simulation.
+type FirewallTraceStep struct {
+	RuleID   string `json:"rule_id,omitempty"`
+	Position int    `json:"position"`
+	Matched  bool   `json:"matched"`
+	Reason   string `json:"reason"`
+}
+
+// FirewallSimulationResult represent a result of the firewall simulation.
+//
+// Rule is nil when no rule matched and the default verdict was used.
+type FirewallSimulationResult struct {
+	Verdict  FirewallAction      `json:"verdict"`
+	Rule     *FirewallRule       `json:"rule,omitempty"`
+	Position int                 `json:"position,omitempty"`
+	Trace    []FirewallTraceStep `json:"trace"`
+}
+
+// FirewallSimulator evaluates packets against firewall policies.
+type FirewallSimulator struct {
+	// Objects are used to expand zones, services and interfaces of rules.
+	Objects FirewallObjects
+	// GroupAddresses maps server group IDs to addresses of their servers,
+	// so rules with group sources and targets can be evaluated.
+	GroupAddresses map[string]IPList
+	// DefaultVerdict is used when no rule matches, DROP when empty.
+	DefaultVerdict FirewallAction
+}
+
+// SimulateFirewallPolicy evaluates the packet against the policy rules.
+func SimulateFirewallPolicy(policy FirewallPolicy, objects FirewallObjects, packet FirewallPacket) (FirewallSimulationResult, error) {
+	return FirewallSimulator{Objects: objects}.Simulate(policy, packet)
+}
+
+// Simulate evaluates the packet against the policy rules ordered by position
+// and returns the verdict of the first matching rule with a trace of all rules
+// checked.
+//
+// Rules referring GhostPorts users and user groups never match.
+func (s FirewallSimulator) Simulate(policy FirewallPolicy, packet FirewallPacket) (result FirewallSimulationResult, err error) {
+	p, err := parseFirewallPacket(packet)
+	if err != nil {
+		return result, fmt.Errorf("invalid packet: %v", err)
+	}
+
+	groups := map[string][]addrRange{}
+	for id, ips := range s.GroupAddresses {
+		if groups[id], err = parseAddrRanges(ips); err != nil {
+			return result, fmt.Errorf("invalid addresses of group %s: %v", id, err)
+		}
+	}
+
+	result.Trace = []FirewallTraceStep{}
+
+	for _, r := range resolveFirewallRules(policy, s.Objects) {
+		step := FirewallTraceStep{RuleID: r.rule.ID, Position: r.position}
+		step.Reason = p.mismatch(r, groups)
+		step.Matched = step.Reason == ""
+
+		if step.Matched {
+			step.Reason = "matched"
+		}
+
+		result.Trace = append(result.Trace, step)
+
+		if step.Matched {
+			rule := r.rule
+			result.Verdict = rule.Action
+			result.Rule = &rule
+			result.Position = r.position
+			return result, nil
+		}
+	}
+
+	result.Verdict = s.DefaultVerdict
+	if result.Verdict == "" {
+		result.Verdict = FirewallActionDrop
+	}
+
+	return result, nil
+}
+
+type simulatedPacket struct {
+	FirewallPacket
+	source      netip.Addr
+	destination netip.Addr
+}
+
+func parseFirewallPacket(packet FirewallPacket) (p simulatedPacket, err error) {
+	p.FirewallPacket = packet
+	p.Chain = FirewallChain(strings.ToUpper(string(p.Chain)))
+	p.Protocol = strings.ToUpper(p.Protocol)
+	p.ConnectionState = strings.ToUpper(p.ConnectionState)
+
+	if p.Chain == "" {
+		p.Chain = FirewallChainInput
+	}
+	if p.Chain != FirewallChainInput && p.Chain != FirewallChainOutput {
+		return p, fmt.Errorf("unknown chain %s", packet.Chain)
+	}
+
+	if p.ConnectionState == "" {
+		p.ConnectionState = "NEW"
+	}
+
+	if p.SourceIP != "" {
+		if p.source, err = netip.ParseAddr(p.SourceIP); err != nil {
+			return p, fmt.Errorf("invalid source IP: %v", err)
+		}
+	}
+
+	if p.DestinationIP != "" {
+		if p.destination, err = netip.ParseAddr(p.DestinationIP); err != nil {
+			return p, fmt.Errorf("invalid destination IP: %v", err)
+		}
+	}
+
+	return p, nil
+}
+
+// mismatch returns the reason why the rule does not match the packet, empty when it does.
+func (p simulatedPacket) mismatch(r resolvedRule, groups map[string][]addrRange) string {
+	switch {
+	case !r.rule.GetActive():
+		return "rule is inactive"
+	case r.rule.Chain != p.Chain:
+		return fmt.Sprintf("chain %s does not match %s", r.rule.Chain, p.Chain)
+	case len(r.missing) > 0:
+		return "rule references missing " + strings.Join(r.missing, ", ")
+	case r.iface != "" && r.iface != p.Interface:
+		return fmt.Sprintf("interface %s does not match", r.iface)
+	case r.states != nil && !r.states[p.ConnectionState]:
+		return fmt.Sprintf("connection state %s does not match", p.ConnectionState)
+	case !r.anyProtocol() && r.protocol != p.Protocol:
+		return fmt.Sprintf("protocol %s does not match", r.protocol)
+	case !r.anyProtocol() && !portsContain(r.ports, p.Port):
+		return fmt.Sprintf("port %d does not match", p.Port)
+	}
+
+	if reason := endpointMismatch("source", r.source, p.source, groups); reason != "" {
+		return reason
+	}
+
+	return endpointMismatch("target", r.target, p.destination, groups)
+}
+
+func endpointMismatch(side string, e *ruleEndpoint, addr netip.Addr, groups map[string][]addrRange) string {
+	if e == nil {
+		return ""
+	}
+
+	if !addr.IsValid() {
+		return fmt.Sprintf("%s %s cannot match packet without address", side, e)
+	}
+
+	switch e.kind {
+	case FirewallEndpointZone:
+		if addrsContain(e.addrs, addr) {
+			return ""
+		}
+	case FirewallEndpointGroup:
+		if e.id == FirewallAllActiveServers {
+			for _, g := range groups {
+				if addrsContain(g, addr) {
+					return ""
+				}
+			}
+		} else if addrsContain(groups[e.id], addr) {
+			return ""
+		}
+	default:
+		return fmt.Sprintf("%s %s is not simulated", side, e)
+	}
+
+	return fmt.Sprintf("%s %s does not contain %s", side, e, addr)
+}
@@ -0,0 +1,143 @@
+package cphalo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFirewallSimulator_Simulate(t *testing.T) {
+	policy := FirewallPolicy{
+		FirewallRules: []FirewallRule{
+			{ID: "r3", Position: 3, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "z-office", Kind: "FirewallZone"}, FirewallService: &FirewallService{ID: "s-db"}},
+			{ID: "r1", Position: 1, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), ConnectionStates: "ESTABLISHED, RELATED"},
+			{ID: "r2", Position: 2, Chain: "INPUT", Action: "DROP", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "z-vpn", Kind: "FirewallZone"}},
+			{ID: "r4", Position: 4, Chain: "INPUT", Action: "REJECT", Active: Bool(false)},
+			{ID: "r5", Position: 5, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "g-web", Kind: "Group"}, FirewallService: &FirewallService{ID: "s-high"}, FirewallInterface: &FirewallInterface{ID: "i-eth1"}},
+			{ID: "r6", Position: 6, Chain: "OUTPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: &FirewallService{ID: "s-dns"}},
+		},
+	}
+
+	sim := FirewallSimulator{
+		Objects:        testFirewallObjects(),
+		GroupAddresses: map[string]IPList{"g-web": {"172.16.0.10", "172.16.0.11"}},
+	}
+
+	tests := []struct {
+		name     string
+		packet   FirewallPacket
+		verdict  FirewallAction
+		position int
+		trace    int
+	}{
+		{"established", FirewallPacket{SourceIP: "8.8.8.8", Protocol: "tcp", Port: 5432, ConnectionState: "established"}, "ACCEPT", 1, 1},
+		{"vpn dropped", FirewallPacket{SourceIP: "10.1.2.3", Protocol: "tcp", Port: 5432}, "DROP", 2, 2},
+		{"office to db", FirewallPacket{SourceIP: "10.2.3.4", Protocol: "tcp", Port: 5432}, "ACCEPT", 3, 3},
+		{"office to other port", FirewallPacket{SourceIP: "10.2.3.4", Protocol: "tcp", Port: 5433}, "DROP", 0, 6},
+		{"group on interface", FirewallPacket{SourceIP: "172.16.0.11", Protocol: "tcp", Port: 8080, Interface: "eth1"}, "ACCEPT", 5, 5},
+		{"group on other interface", FirewallPacket{SourceIP: "172.16.0.11", Protocol: "tcp", Port: 8080, Interface: "eth0"}, "DROP", 0, 6},
+		{"outgoing dns", FirewallPacket{Chain: "output", DestinationIP: "1.1.1.1", Protocol: "udp", Port: 53}, "ACCEPT", 6, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := sim.Simulate(policy, tt.packet)
+
+			if err != nil {
+				t.Fatalf("simulation failed: %v", err)
+			}
+
+			if result.Verdict != tt.verdict {
+				t.Errorf("expected verdict %s; got %s", tt.verdict, result.Verdict)
+			}
+
+			if result.Position != tt.position {
+				t.Errorf("expected matching rule at position %d; got %d", tt.position, result.Position)
+			}
+
+			if (result.Rule == nil) != (tt.position == 0) {
+				t.Errorf("expected matching rule only when position is set; got %+v", result.Rule)
+			}
+
+			if len(result.Trace) != tt.trace {
+				t.Errorf("expected %d trace steps; got %d", tt.trace, len(result.Trace))
+			}
+
+			for i, step := range result.Trace {
+				if step.Position != i+1 {
+					t.Errorf("expected trace step %d to be rule at position %d; got %d", i, i+1, step.Position)
+				}
+				if step.Matched != (step.Position == tt.position) {
+					t.Errorf("unexpected trace step %+v", step)
+				}
+			}
+		})
+	}
+}
+
+func TestFirewallSimulator_Trace(t *testing.T) {
+	policy := FirewallPolicy{
+		FirewallRules: []FirewallRule{
+			{ID: "r1", Chain: "INPUT", Action: "ACCEPT", Active: Bool(false)},
+			{ID: "r2", Chain: "OUTPUT", Action: "ACCEPT", Active: Bool(true)},
+			{ID: "r3", Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "z-gone", Kind: "FirewallZone"}},
+			{ID: "r4", Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "u1", Kind: "User"}},
+			{ID: "r5", Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: &FirewallService{ID: "s-ssh"}},
+		},
+	}
+
+	result, err := FirewallSimulator{Objects: testFirewallObjects(), DefaultVerdict: "REJECT"}.Simulate(policy, FirewallPacket{SourceIP: "1.2.3.4", Protocol: "UDP", Port: 22})
+
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+
+	if result.Verdict != "REJECT" {
+		t.Errorf("expected default verdict REJECT; got %s", result.Verdict)
+	}
+
+	reasons := []string{"inactive", "chain OUTPUT", "missing zone z-gone", "User u1 is not simulated", "protocol TCP"}
+	for i, r := range reasons {
+		if !strings.Contains(result.Trace[i].Reason, r) {
+			t.Errorf("expected trace step %d reason to contain %q; got %q", i, r, result.Trace[i].Reason)
+		}
+	}
+}
+
+func TestSimulateFirewallPolicy_InvalidPacket(t *testing.T) {
+	packets := []FirewallPacket{
+		{Chain: "FORWARD"},
+		{SourceIP: "10.0.0.256"},
+		{DestinationIP: "host"},
+	}
+
+	for _, p := range packets {
+		if _, err := SimulateFirewallPolicy(FirewallPolicy{}, testFirewallObjects(), p); err == nil {
+			t.Errorf("expected packet %+v to be invalid", p)
+		}
+	}
+}
+
+func TestFirewallSimulator_AnyProtocol(t *testing.T) {
+	objects := testFirewallObjects()
+	objects.Services = append(objects.Services,
+		FirewallService{ID: "s-any", Name: "all", Protocol: "ANY"},
+		FirewallService{ID: "s-gre", Name: "gre", Protocol: "47"},
+	)
+
+	policy := FirewallPolicy{
+		FirewallRules: []FirewallRule{
+			{ID: "r1", Position: 1, Chain: "INPUT", Action: "DROP", Active: Bool(true), FirewallService: &FirewallService{ID: "s-gre"}},
+			{ID: "r2", Position: 2, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: &FirewallService{ID: "s-any"}},
+		},
+	}
+
+	result, err := FirewallSimulator{Objects: objects}.Simulate(policy, FirewallPacket{SourceIP: "1.2.3.4", Protocol: "tcp", Port: 22})
+
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+
+	if result.Verdict != "ACCEPT" || result.Position != 2 {
+		t.Errorf("expected packet accepted by ANY rule at position 2; got %s at %d: %+v", result.Verdict, result.Position, result.Trace)
+	}
+}
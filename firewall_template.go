@@ -0,0 +1,112 @@
+package cphalo
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+var firewallTemplateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// Render executes Go templates in texts of the template with the data and
+// returns the rendered copy, so a single template serves all environments.
+//
+// Templates can be used in names and descriptions of the policy and zones,
+// names of services and interfaces, service protocols and ports, zone
+// addresses and rule comments and log prefixes. A zone address may render into
+// multiple addresses separated by commas or spaces. Keys missing in map data
+// result in an error.
+func (t FirewallPolicyTemplate) Render(data interface{}) (rendered FirewallPolicyTemplate, err error) {
+	r := &firewallTemplateRenderer{data: data}
+
+	rendered.Policy = t.Policy
+	rendered.Policy.Name = r.render(t.Policy.Name)
+	rendered.Policy.Description = r.render(t.Policy.Description)
+	rendered.Policy.FirewallRules = nil
+
+	for _, rule := range t.Policy.FirewallRules {
+		rule.Comment = r.render(rule.Comment)
+		rule.LogPrefix = r.render(rule.LogPrefix)
+
+		for _, st := range []**FirewallRuleSourceTarget{&rule.FirewallSource, &rule.FirewallTarget} {
+			if *st != nil {
+				copied := **st
+				copied.Name = r.render(copied.Name)
+				*st = &copied
+			}
+		}
+
+		if rule.FirewallService != nil {
+			rule.FirewallService = &FirewallService{ID: rule.FirewallService.ID, Name: r.render(rule.FirewallService.Name)}
+		}
+
+		if rule.FirewallInterface != nil {
+			rule.FirewallInterface = &FirewallInterface{ID: rule.FirewallInterface.ID, Name: r.render(rule.FirewallInterface.Name)}
+		}
+
+		rendered.Policy.FirewallRules = append(rendered.Policy.FirewallRules, rule)
+	}
+
+	for _, z := range t.Objects.Zones {
+		z.Name = r.render(z.Name)
+		z.Description = r.render(z.Description)
+
+		var ips IPList
+		for _, ip := range z.IPAddress {
+			ips = append(ips, strings.FieldsFunc(r.render(ip), func(c rune) bool {
+				return c == ',' || c == ' ' || c == '\t' || c == '\n'
+			})...)
+		}
+		z.IPAddress = ips
+
+		rendered.Objects.Zones = append(rendered.Objects.Zones, z)
+	}
+
+	for _, s := range t.Objects.Services {
+		s.Name = r.render(s.Name)
+		s.Protocol = FirewallProtocol(r.render(string(s.Protocol)))
+		s.Port = r.render(s.Port)
+
+		rendered.Objects.Services = append(rendered.Objects.Services, s)
+	}
+
+	for _, i := range t.Objects.Interfaces {
+		i.Name = r.render(i.Name)
+
+		rendered.Objects.Interfaces = append(rendered.Objects.Interfaces, i)
+	}
+
+	if r.err != nil {
+		return FirewallPolicyTemplate{}, r.err
+	}
+
+	return rendered, nil
+}
+
+// firewallTemplateRenderer renders texts, keeping the first error.
+type firewallTemplateRenderer struct {
+	data interface{}
+	err  error
+}
+
+func (r *firewallTemplateRenderer) render(text string) string {
+	if r.err != nil || !strings.Contains(text, "{{") {
+		return text
+	}
+
+	tmpl, err := template.New("firewall").Funcs(firewallTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		r.err = fmt.Errorf("cannot parse template %q: %v", text, err)
+		return text
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, r.data); err != nil {
+		r.err = fmt.Errorf("cannot render template %q: %v", text, err)
+		return text
+	}
+
+	return b.String()
+}
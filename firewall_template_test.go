@@ -0,0 +1,77 @@
+package cphalo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testFirewallPolicyTemplate() FirewallPolicyTemplate {
+	return FirewallPolicyTemplate{
+		Policy: FirewallPolicy{
+			Name:        "web-{{ .Env }}",
+			Description: "web servers",
+			FirewallRules: []FirewallRule{
+				{
+					Chain:           "INPUT",
+					Action:          "ACCEPT",
+					FirewallSource:  &FirewallRuleSourceTarget{Name: "office-{{ .Env }}", Kind: "FirewallZone"},
+					FirewallService: &FirewallService{Name: "app"},
+					Comment:         "app from {{ .Env }} office",
+				},
+			},
+		},
+		Objects: FirewallTemplateObjects{
+			Zones:    []FirewallTemplateZone{{Name: "office-{{ .Env }}", IPAddress: IPList{`{{ join .OfficeIPs " " }}`, "10.0.0.1"}}},
+			Services: []FirewallService{{Name: "app", Protocol: "TCP", Port: "{{ .Port }}"}},
+		},
+	}
+}
+
+func TestFirewallPolicyTemplate_Render(t *testing.T) {
+	tmpl := testFirewallPolicyTemplate()
+
+	rendered, err := tmpl.Render(map[string]interface{}{
+		"Env":       "prod",
+		"OfficeIPs": []string{"192.168.0.1", "192.168.0.2"},
+		"Port":      8080,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rendered.Policy.Name != "web-prod" || rendered.Policy.Description != "web servers" {
+		t.Errorf("unexpected policy %+v", rendered.Policy)
+	}
+
+	rule := rendered.Policy.FirewallRules[0]
+	if rule.FirewallSource.Name != "office-prod" || rule.Comment != "app from prod office" || rule.FirewallService.Name != "app" {
+		t.Errorf("unexpected rule %+v", rule)
+	}
+
+	if expected := (IPList{"192.168.0.1", "192.168.0.2", "10.0.0.1"}); rendered.Objects.Zones[0].Name != "office-prod" || !reflect.DeepEqual(rendered.Objects.Zones[0].IPAddress, expected) {
+		t.Errorf("expected zone office-prod with addresses %v; got %+v", expected, rendered.Objects.Zones[0])
+	}
+
+	if rendered.Objects.Services[0].Port != "8080" {
+		t.Errorf("expected port 8080; got %s", rendered.Objects.Services[0].Port)
+	}
+
+	if tmpl.Policy.FirewallRules[0].FirewallSource.Name != "office-{{ .Env }}" {
+		t.Error("expected template not to be modified")
+	}
+}
+
+func TestFirewallPolicyTemplate_RenderErrors(t *testing.T) {
+	tmpl := testFirewallPolicyTemplate()
+
+	if _, err := tmpl.Render(map[string]interface{}{"Env": "prod"}); err == nil || !strings.Contains(err.Error(), "cannot render template") {
+		t.Errorf("expected missing key error; got %v", err)
+	}
+
+	tmpl.Policy.Name = "web-{{ .Env"
+
+	if _, err := tmpl.Render(map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "cannot parse template") {
+		t.Errorf("expected parse error; got %v", err)
+	}
+}
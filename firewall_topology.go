@@ -0,0 +1,299 @@
+package cphalo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of firewall topology nodes.
+const (
+	TopologyNodeGroup     = "group"
+	TopologyNodeZone      = "zone"
+	TopologyNodeUser      = "user"
+	TopologyNodeUserGroup = "user_group"
+	TopologyNodeAny       = "any"
+)
+
+// FirewallTopologyNode represent a server group, zone or user in the firewall topology.
+type FirewallTopologyNode struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+}
+
+// FirewallTopologyEdge represent traffic allowed or denied between two nodes.
+type FirewallTopologyEdge struct {
+	From     string         `json:"from"`
+	To       string         `json:"to"`
+	Action   FirewallAction `json:"action"`
+	Services []string       `json:"services"`
+}
+
+// Label returns services and action of the edge.
+func (e FirewallTopologyEdge) Label() string {
+	return fmt.Sprintf("%s (%s)", strings.Join(e.Services, ", "), e.Action)
+}
+
+// FirewallTopologyFilter limits rules included in the firewall topology.
+type FirewallTopologyFilter struct {
+	// Port includes only rules matching the TCP or UDP port, rules without
+	// service match all ports.
+	Port int
+	// Service includes only rules with the firewall service of the name.
+	Service string
+}
+
+// FirewallTopology represent a graph of server groups and their firewall rules.
+type FirewallTopology struct {
+	Nodes []FirewallTopologyNode `json:"nodes"`
+	Edges []FirewallTopologyEdge `json:"edges"`
+}
+
+// FirewallTopology builds the firewall topology of all server groups with
+// a Linux firewall policy assigned.
+func (c *Client) FirewallTopology(filter FirewallTopologyFilter) (topology FirewallTopology, err error) {
+	groups, err := c.ListServerGroups()
+	if err != nil {
+		return topology, fmt.Errorf("cannot list server groups: %v", err)
+	}
+
+	objects, err := c.ListFirewallObjects()
+	if err != nil {
+		return topology, err
+	}
+
+	policies := map[string]FirewallPolicy{}
+	assigned := map[string]FirewallPolicy{}
+
+	for _, g := range groups.Groups {
+		resp, err := c.GetServerGroupFirewallPolicy(g.ID)
+		if err != nil {
+			return topology, fmt.Errorf("cannot get firewall policy of server group %s: %v", g.Name, err)
+		}
+
+		policyID := string(resp.Group.LinuxFirewallPolicyID)
+		if policyID == "" {
+			continue
+		}
+
+		policy, ok := policies[policyID]
+		if !ok {
+			p, err := c.GetFirewallPolicy(policyID)
+			if err != nil {
+				return topology, fmt.Errorf("cannot get firewall policy %s: %v", policyID, err)
+			}
+
+			rules, err := c.ListFirewallRules(policyID)
+			if err != nil {
+				return topology, fmt.Errorf("cannot list rules of firewall policy %s: %v", policyID, err)
+			}
+
+			policy = p.Policy
+			policy.FirewallRules = rules.Rules
+			policies[policyID] = policy
+		}
+
+		assigned[g.ID] = policy
+	}
+
+	return BuildFirewallTopology(groups.Groups, assigned, objects, filter), nil
+}
+
+// BuildFirewallTopology builds the firewall topology from server groups and
+// their firewall policies keyed by group ID.
+//
+// Active INPUT rules become edges from their source to the group, active OUTPUT
+// rules edges from the group to their target. Rules between the same nodes with
+// the same action are merged into a single edge.
+func BuildFirewallTopology(groups []ServerGroup, policies map[string]FirewallPolicy, objects FirewallObjects, filter FirewallTopologyFilter) FirewallTopology {
+	nodes := map[string]FirewallTopologyNode{}
+	edges := map[string]*FirewallTopologyEdge{}
+
+	groupNames := map[string]string{}
+	for _, g := range groups {
+		groupNames[g.ID] = g.Name
+	}
+
+	node := func(e *ruleEndpoint) string {
+		var n FirewallTopologyNode
+
+		switch {
+		case e == nil:
+			n = FirewallTopologyNode{ID: "any", Kind: TopologyNodeAny, Label: "any"}
+		case e.kind == FirewallEndpointZone:
+			n = FirewallTopologyNode{ID: "zone:" + e.name, Kind: TopologyNodeZone, Label: e.name}
+		case e.kind == FirewallEndpointGroup:
+			n = FirewallTopologyNode{ID: "group:" + e.id, Kind: TopologyNodeGroup, Label: refName(groupNames[e.id], e.name)}
+		case e.kind == FirewallEndpointUserGroup:
+			n = FirewallTopologyNode{ID: "user_group:" + e.id, Kind: TopologyNodeUserGroup, Label: e.name}
+		default:
+			n = FirewallTopologyNode{ID: "user:" + e.id, Kind: TopologyNodeUser, Label: e.name}
+		}
+
+		nodes[n.ID] = n
+
+		return n.ID
+	}
+
+	for _, g := range groups {
+		policy, ok := policies[g.ID]
+		if !ok {
+			continue
+		}
+
+		group := node(&ruleEndpoint{kind: FirewallEndpointGroup, id: g.ID, name: g.Name})
+
+		for _, r := range resolveFirewallRules(policy, objects) {
+			if !r.rule.GetActive() || !filter.matches(r) {
+				continue
+			}
+
+			var from, to string
+			switch r.rule.Chain {
+			case FirewallChainInput:
+				from, to = node(r.source), group
+			case FirewallChainOutput:
+				from, to = group, node(r.target)
+			default:
+				continue
+			}
+
+			service := r.service
+			if service == "" {
+				service = "any"
+			}
+
+			key := from + "|" + to + "|" + string(r.rule.Action)
+			edge, ok := edges[key]
+			if !ok {
+				edge = &FirewallTopologyEdge{From: from, To: to, Action: r.rule.Action}
+				edges[key] = edge
+			}
+
+			if !containsString(edge.Services, service) {
+				edge.Services = append(edge.Services, service)
+			}
+		}
+	}
+
+	topology := FirewallTopology{Nodes: []FirewallTopologyNode{}, Edges: []FirewallTopologyEdge{}}
+
+	for _, n := range nodes {
+		topology.Nodes = append(topology.Nodes, n)
+	}
+	sort.Slice(topology.Nodes, func(i, j int) bool { return topology.Nodes[i].ID < topology.Nodes[j].ID })
+
+	for _, e := range edges {
+		sort.Strings(e.Services)
+		topology.Edges = append(topology.Edges, *e)
+	}
+	sort.Slice(topology.Edges, func(i, j int) bool {
+		a, b := topology.Edges[i], topology.Edges[j]
+		return a.From+"|"+a.To+"|"+string(a.Action) < b.From+"|"+b.To+"|"+string(b.Action)
+	})
+
+	return topology
+}
+
+func (f FirewallTopologyFilter) matches(r resolvedRule) bool {
+	if f.Service != "" && r.service != f.Service {
+		return false
+	}
+
+	if f.Port != 0 && !r.anyProtocol() {
+		if r.protocol != "TCP" && r.protocol != "UDP" {
+			return false
+		}
+		if !portsContain(r.ports, f.Port) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DOT renders the topology in the Graphviz DOT language.
+func (t FirewallTopology) DOT() string {
+	var b strings.Builder
+
+	b.WriteString("digraph firewall {\n")
+	b.WriteString("  rankdir=LR;\n")
+
+	shapes := map[string]string{
+		TopologyNodeGroup:     "box",
+		TopologyNodeZone:      "ellipse",
+		TopologyNodeUser:      "oval",
+		TopologyNodeUserGroup: "oval",
+		TopologyNodeAny:       "diamond",
+	}
+
+	for _, n := range t.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s];\n", dotQuote(n.ID), dotQuote(n.Label), shapes[n.Kind])
+	}
+
+	for _, e := range t.Edges {
+		color := "red"
+		if e.Action == FirewallActionAccept {
+			color = "darkgreen"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s, color=%s];\n", dotQuote(e.From), dotQuote(e.To), dotQuote(e.Label()), color)
+	}
+
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+// Mermaid renders the topology as a Mermaid flowchart. Denied traffic is
+// drawn with dotted arrows.
+func (t FirewallTopology) Mermaid() string {
+	var b strings.Builder
+
+	b.WriteString("flowchart LR\n")
+
+	ids := map[string]string{}
+
+	for i, n := range t.Nodes {
+		id := fmt.Sprintf("n%d", i+1)
+		ids[n.ID] = id
+
+		label := mermaidQuote(n.Label)
+		switch n.Kind {
+		case TopologyNodeGroup:
+			fmt.Fprintf(&b, "  %s[%s]\n", id, label)
+		case TopologyNodeAny:
+			fmt.Fprintf(&b, "  %s{%s}\n", id, label)
+		default:
+			fmt.Fprintf(&b, "  %s(%s)\n", id, label)
+		}
+	}
+
+	for _, e := range t.Edges {
+		arrow := "-.->"
+		if e.Action == FirewallActionAccept {
+			arrow = "-->"
+		}
+		fmt.Fprintf(&b, "  %s %s|%s| %s\n", ids[e.From], arrow, mermaidQuote(e.Label()), ids[e.To])
+	}
+
+	return b.String()
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
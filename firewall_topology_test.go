@@ -0,0 +1,158 @@
+package cphalo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func testFirewallTopology(filter FirewallTopologyFilter) FirewallTopology {
+	groups := []ServerGroup{
+		{ID: "g-web", Name: "web"},
+		{ID: "g-db", Name: "db"},
+		{ID: "g-empty", Name: "empty"},
+	}
+
+	policies := map[string]FirewallPolicy{
+		"g-web": {
+			FirewallRules: []FirewallRule{
+				{Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "z-any", Kind: "FirewallZone"}, FirewallService: &FirewallService{ID: "s-web"}},
+				{Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "z-office", Kind: "FirewallZone"}, FirewallService: &FirewallService{ID: "s-ssh"}},
+				{Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "z-office", Kind: "FirewallZone"}, FirewallService: &FirewallService{ID: "s-http"}},
+				{Chain: "OUTPUT", Action: "ACCEPT", Active: Bool(true), FirewallTarget: &FirewallRuleSourceTarget{ID: "g-db", Kind: "Group"}, FirewallService: &FirewallService{ID: "s-db"}},
+				{Chain: "INPUT", Action: "DROP", Active: Bool(false)},
+			},
+		},
+		"g-db": {
+			FirewallRules: []FirewallRule{
+				{Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "g-web", Kind: "Group"}, FirewallService: &FirewallService{ID: "s-db"}},
+				{Chain: "INPUT", Action: "DROP", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "u-1", Name: "john", Kind: "User"}},
+				{Chain: "INPUT", Action: "REJECT", Active: Bool(true)},
+			},
+		},
+	}
+
+	return BuildFirewallTopology(groups, policies, testFirewallObjects(), filter)
+}
+
+func TestBuildFirewallTopology(t *testing.T) {
+	topology := testFirewallTopology(FirewallTopologyFilter{})
+
+	expectedDOT := `digraph firewall {
+  rankdir=LR;
+  "any" [label="any", shape=diamond];
+  "group:g-db" [label="db", shape=box];
+  "group:g-web" [label="web", shape=box];
+  "user:u-1" [label="john", shape=oval];
+  "zone:any" [label="any", shape=ellipse];
+  "zone:office" [label="office", shape=ellipse];
+  "any" -> "group:g-db" [label="any (REJECT)", color=red];
+  "group:g-web" -> "group:g-db" [label="postgres (ACCEPT)", color=darkgreen];
+  "user:u-1" -> "group:g-db" [label="any (DROP)", color=red];
+  "zone:any" -> "group:g-web" [label="web (ACCEPT)", color=darkgreen];
+  "zone:office" -> "group:g-web" [label="http, ssh (ACCEPT)", color=darkgreen];
+}
+`
+
+	if dot := topology.DOT(); dot != expectedDOT {
+		t.Errorf("expected DOT:\n%s\ngot:\n%s", expectedDOT, dot)
+	}
+
+	expectedMermaid := `flowchart LR
+  n1{"any"}
+  n2["db"]
+  n3["web"]
+  n4("john")
+  n5("any")
+  n6("office")
+  n1 -.->|"any (REJECT)"| n2
+  n3 -->|"postgres (ACCEPT)"| n2
+  n4 -.->|"any (DROP)"| n2
+  n5 -->|"web (ACCEPT)"| n3
+  n6 -->|"http, ssh (ACCEPT)"| n3
+`
+
+	if mermaid := topology.Mermaid(); mermaid != expectedMermaid {
+		t.Errorf("expected Mermaid:\n%s\ngot:\n%s", expectedMermaid, mermaid)
+	}
+}
+
+func TestBuildFirewallTopology_Filter(t *testing.T) {
+	tests := []struct {
+		filter FirewallTopologyFilter
+		edges  int
+	}{
+		{FirewallTopologyFilter{Port: 443}, 3},
+		{FirewallTopologyFilter{Port: 5432}, 3},
+		{FirewallTopologyFilter{Service: "ssh"}, 1},
+		{FirewallTopologyFilter{Service: "ssh", Port: 80}, 0},
+	}
+
+	for _, tt := range tests {
+		topology := testFirewallTopology(tt.filter)
+
+		if len(topology.Edges) != tt.edges {
+			t.Errorf("expected %d edges for filter %+v; got %d: %+v", tt.edges, tt.filter, len(topology.Edges), topology.Edges)
+		}
+	}
+}
+
+func TestClient_FirewallTopology(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/groups": jsonResponseTestHandler(t, "server_groups_list", http.StatusOK),
+			"GET /v1/groups/9981f162c2d611e680b17f1fb185c564":                           jsonResponseTestHandler(t, "server_groups_get", http.StatusOK),
+			"GET /v1/groups/99edf4b6c2d611e6aa9177ed6158edb9":                           jsonResponseTestHandler(t, "server_groups_get_child", http.StatusOK),
+			"GET /v1/firewall_policies/8eb1b050abe2013295e406ba9a9c633c":                jsonResponseTestHandler(t, "firewall_policies_get", http.StatusOK),
+			"GET /v1/firewall_policies/8eb1b050abe2013295e406ba9a9c633c/firewall_rules": jsonResponseTestHandler(t, "firewall_rules_list_expanded", http.StatusOK),
+			"GET /v1/firewall_zones":                                                    jsonResponseTestHandler(t, "firewall_zones_list", http.StatusOK),
+			"GET /v1/firewall_services":                                                 jsonResponseTestHandler(t, "firewall_services_list", http.StatusOK),
+			"GET /v1/firewall_interfaces":                                               jsonResponseTestHandler(t, "firewall_interfaces_list", http.StatusOK),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	topology, err := client.FirewallTopology(FirewallTopologyFilter{})
+
+	if err != nil {
+		t.Fatalf("firewall topology failed: %v", err)
+	}
+
+	if len(topology.Nodes) != 2 || len(topology.Edges) != 1 {
+		t.Fatalf("expected 2 nodes and 1 edge; got %+v", topology)
+	}
+
+	edge := topology.Edges[0]
+	if edge.From != "zone:any" || edge.To != "group:9981f162c2d611e680b17f1fb185c564" || edge.Label() != "ssh (ACCEPT)" {
+		t.Errorf("unexpected edge %+v", edge)
+	}
+}
+
+func TestBuildFirewallTopology_FilterAnyProtocol(t *testing.T) {
+	objects := testFirewallObjects()
+	objects.Services = append(objects.Services, FirewallService{ID: "s-any", Name: "all", Protocol: "ANY"})
+
+	policies := map[string]FirewallPolicy{
+		"g-web": {
+			FirewallRules: []FirewallRule{
+				{Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "z-office", Kind: "FirewallZone"}, FirewallService: &FirewallService{ID: "s-any"}},
+			},
+		},
+	}
+
+	topology := BuildFirewallTopology([]ServerGroup{{ID: "g-web", Name: "web"}}, policies, objects, FirewallTopologyFilter{Port: 22})
+
+	if len(topology.Edges) != 1 {
+		t.Errorf("expected ANY rule to match port 22; got %+v", topology.Edges)
+	}
+}
@@ -7,12 +7,16 @@ import (
 
 // FirewallZone represent a CPHalo firewall zone.
 //
+// IPAddress holds the addresses parsed into netip ranges, so containment
+// queries and set operations need no parsing. Zones of firewall policy
+// templates keep them as written, see FirewallTemplateZone.
+//
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#object-representation-10
 type FirewallZone struct {
 	ID          string `json:"id,omitempty"`
 	URL         string `json:"url,omitempty"`
 	Name        string `json:"name,omitempty"`
-	IPAddress   IPList `json:"ip_address,omitempty"`
+	IPAddress   IPSet  `json:"ip_address,omitempty"`
 	Description string `json:"description,omitempty"`
 	System      bool   `json:"system,omitempty"`
 }
@@ -71,10 +75,15 @@ func (c *Client) GetFirewallZone(ID string) (response GetFirewallZoneResponse, e
 	return response, nil
 }
 
-// CreateFirewallZone creates a new firewall zone.
+// CreateFirewallZone creates a new firewall zone. The zone must have
+// IP addresses.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#create-a-new-firewall-zone
 func (c *Client) CreateFirewallZone(zone FirewallZone) (response CreateFirewallZoneResponse, err error) {
+	if err := zone.Validate(); err != nil {
+		return response, fmt.Errorf("invalid firewall zone: %v", err)
+	}
+
 	req, err := c.newRequest(http.MethodPost, "firewall_zones", nil, CreateFirewallZoneRequest{Zone: zone})
 	if err != nil {
 		return response, fmt.Errorf("cannot create new create request: %v", err)
@@ -88,7 +97,8 @@ func (c *Client) CreateFirewallZone(zone FirewallZone) (response CreateFirewallZ
 	return response, nil
 }
 
-// UpdateFirewallZone updates firewall zone.
+// UpdateFirewallZone updates firewall zone. IP addresses are sent only when
+// set, so the zone can carry only the fields to change, e.g. its name.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#update-firewall-zone
 func (c *Client) UpdateFirewallZone(zone FirewallZone) error {
@@ -1,10 +1,12 @@
 package cphalo
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -108,7 +110,7 @@ func TestClient_CreateFirewallZone(t *testing.T) {
 	service := FirewallZone{
 		ID:        "id",
 		Name:      "hello",
-		IPAddress: IPList{"0.0.0.0/0"},
+		IPAddress: MustParseIPSet("0.0.0.0/0"),
 	}
 
 	resp, err := client.CreateFirewallZone(service)
@@ -160,7 +162,7 @@ func TestClient_UpdateFirewallZone(t *testing.T) {
 	service := FirewallZone{
 		ID:        "id",
 		Name:      "hello",
-		IPAddress: IPList{"0.0.0.0/0"},
+		IPAddress: MustParseIPSet("0.0.0.0/0"),
 	}
 
 	err = client.UpdateFirewallZone(service)
@@ -209,3 +211,51 @@ func TestClient_DeleteFirewallZone(t *testing.T) {
 		t.Fatalf("firewall zone deletion failed: %v", err)
 	}
 }
+
+func TestClient_CreateFirewallZone_Invalid(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.RequestURI)
+	}))
+	defer ts.Close()
+
+	var err error
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	zone := FirewallZone{Name: "typo"}
+
+	if _, err := client.CreateFirewallZone(zone); err == nil || !strings.Contains(err.Error(), "zone typo has no IP addresses") {
+		t.Errorf("expected no addresses error; got %v", err)
+	}
+}
+
+func TestClient_UpdateFirewallZone_Partial(t *testing.T) {
+	var err error
+	reqBody := map[string]map[string]json.RawMessage{}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"PUT /v1/firewall_zones/id": bodyRecorderTestHandler(t, jsonResponseTestHandler(t, "", http.StatusOK), &reqBody),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	if err := client.UpdateFirewallZone(FirewallZone{ID: "id", Name: "office"}); err != nil {
+		t.Fatalf("firewall zone updating failed: %v", err)
+	}
+
+	if _, ok := reqBody["firewall_zone"]["ip_address"]; ok || string(reqBody["firewall_zone"]["name"]) != `"office"` {
+		t.Errorf("expected request with name only; got %v", reqBody)
+	}
+}
@@ -0,0 +1,182 @@
+// Package grouping moves CPHalo servers into server groups by rules matching
+// their attributes.
+//
+// Rules are evaluated in order and the first matching rule decides the group
+// of the server. Servers matching no rule stay where they are.
+package grouping
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// API lists servers with the server group tree and moves servers between
+// groups, such as *cphalo.Client.
+type API interface {
+	ListServers() (cphalo.ListServersResponse, error)
+	ListServerGroups() (cphalo.ListServerGroupsResponse, error)
+	MoveServer(ID, gID string) error
+}
+
+// Options configure the grouper.
+type Options struct {
+	// DryRun reports the moves without making them.
+	DryRun bool
+	// SourceGroups limits the servers to move to the servers in the groups
+	// at the paths, such as the default group new servers land in. All
+	// servers are considered when empty.
+	SourceGroups []string
+}
+
+// Move is a move of a server into the group of the matching rule.
+type Move struct {
+	ServerID string `json:"server_id"`
+	Hostname string `json:"hostname"`
+	Rule     string `json:"rule"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	DryRun   bool   `json:"dry_run,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// String returns a single line description of the move.
+func (m Move) String() string {
+	s := fmt.Sprintf("> move server %s from %s to %s (%s)", m.Hostname, m.From, m.To, m.Rule)
+	if m.DryRun {
+		s += " [dry run]"
+	}
+	if m.Error != "" {
+		s += ": " + m.Error
+	}
+
+	return s
+}
+
+// Grouper moves servers into server groups by rules.
+type Grouper struct {
+	api   API
+	rules []Rule
+	opts  Options
+}
+
+// New creates a new Grouper with the rules, which are validated.
+func New(api API, rules []Rule, opts Options) (*Grouper, error) {
+	g := &Grouper{api: api, opts: opts}
+
+	for i, r := range rules {
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i+1, err)
+		}
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		g.rules = append(g.rules, r)
+	}
+
+	return g, nil
+}
+
+// Run moves every server, which is not in the group of its first matching
+// rule, into that group and returns all the moves.
+//
+// Rules can name groups, which do not exist yet, and CPHalo can refuse a move.
+// Such moves carry their error, the other servers are still moved and Run
+// returns how many moves failed.
+func (g *Grouper) Run() ([]Move, error) {
+	groups, err := g.api.ListServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list server groups: %v", err)
+	}
+	tree := cphalo.NewServerGroupTree(groups.Groups)
+
+	sources := map[string]bool{}
+	for _, path := range g.opts.SourceGroups {
+		n, ok := tree.Lookup(path)
+		if !ok {
+			return nil, fmt.Errorf("source server group %s not found", path)
+		}
+		sources[n.Group.ID] = true
+	}
+
+	servers, err := g.api.ListServers()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list servers: %v", err)
+	}
+
+	var moves []Move
+	failed := 0
+
+	for _, s := range servers.Servers {
+		if len(sources) > 0 && !sources[s.GroupID] {
+			continue
+		}
+
+		r, ok := g.match(s)
+		if !ok {
+			continue
+		}
+
+		m := Move{ServerID: s.ID, Hostname: s.Hostname, Rule: r.Name, From: s.GroupPath, To: r.Group, DryRun: g.opts.DryRun}
+		if n, ok := tree.Group(s.GroupID); ok {
+			m.From = n.Path()
+		}
+
+		target, ok := tree.Lookup(r.Group)
+		switch {
+		case !ok:
+			m.Error = fmt.Sprintf("server group %s not found", r.Group)
+		case target.Group.ID == s.GroupID:
+			continue
+		case !g.opts.DryRun:
+			if err := g.api.MoveServer(s.ID, target.Group.ID); err != nil {
+				m.Error = err.Error()
+			}
+		}
+
+		if m.Error != "" {
+			failed++
+		}
+		moves = append(moves, m)
+	}
+
+	if failed > 0 {
+		return moves, fmt.Errorf("cannot move %d of %d servers", failed, len(moves))
+	}
+
+	return moves, nil
+}
+
+func (g *Grouper) match(s cphalo.Server) (Rule, bool) {
+	for _, r := range g.rules {
+		if r.matches(s) {
+			return r, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// Watch runs the grouper right away and then every interval until the context
+// is done, passing results of every run to report. It returns the context error,
+// or an error right away when the interval is not positive.
+func (g *Grouper) Watch(ctx context.Context, interval time.Duration, report func(moves []Move, err error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid watch interval %s", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report(g.Run())
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package grouping
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// testAccount keeps servers in groups, moving them on request.
+type testAccount struct {
+	groups  []cphalo.ServerGroup
+	servers []cphalo.Server
+	fail    map[string]bool
+	calls   []string
+}
+
+func newTestAccount() *testAccount {
+	return &testAccount{
+		groups: []cphalo.ServerGroup{
+			{ID: "root", Name: "example.com"},
+			{ID: "default", Name: "unassigned", ParentID: "root"},
+			{ID: "app", Name: "appservers", ParentID: "root"},
+			{ID: "db", Name: "db", ParentID: "root"},
+			{ID: "aws", Name: "aws", ParentID: "root"},
+		},
+		servers: []cphalo.Server{
+			{ID: "s1", Hostname: "app-1", Platform: "ubuntu", GroupID: "default"},
+			{ID: "s2", Hostname: "db-1", Platform: "centos", ConnectingIPAddress: "10.1.0.5", GroupID: "default"},
+			{ID: "s3", Hostname: "app-2", Platform: "ubuntu", GroupID: "app"},
+			{ID: "s4", Hostname: "win-1", Platform: "windows", GroupID: "default"},
+			{ID: "s5", Hostname: "app-3", Platform: "ubuntu", CSPProvider: "aws_ec2", CSPAccountID: "123", GroupID: "db"},
+		},
+		fail: map[string]bool{},
+	}
+}
+
+func (a *testAccount) ListServers() (cphalo.ListServersResponse, error) {
+	return cphalo.ListServersResponse{Count: len(a.servers), Servers: a.servers}, nil
+}
+
+func (a *testAccount) ListServerGroups() (cphalo.ListServerGroupsResponse, error) {
+	return cphalo.ListServerGroupsResponse{Count: len(a.groups), Groups: a.groups}, nil
+}
+
+func (a *testAccount) MoveServer(ID, gID string) error {
+	a.calls = append(a.calls, fmt.Sprintf("move %s to %s", ID, gID))
+	if a.fail[ID] {
+		return fmt.Errorf("server %s cannot be moved", ID)
+	}
+	for i := range a.servers {
+		if a.servers[i].ID == ID {
+			a.servers[i].GroupID = gID
+			return nil
+		}
+	}
+	return fmt.Errorf("server %s not found", ID)
+}
+
+func testRules() []Rule {
+	return []Rule{
+		{Name: "aws", CSPProvider: "AWS_EC2", CSPAccountID: "123", Group: "example.com/aws"},
+		{Name: "db", ConnectingIPAddress: cphalo.IPList{"10.1.0.0/16"}, Group: "example.com/db"},
+		{Hostname: "^app-", Platform: "Ubuntu", Group: "example.com/appservers"},
+	}
+}
+
+func TestGrouper_Run(t *testing.T) {
+	account := newTestAccount()
+
+	g, err := New(account, testRules(), Options{})
+	if err != nil {
+		t.Fatalf("cannot create grouper: %v", err)
+	}
+
+	moves, err := g.Run()
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	expected := []Move{
+		{ServerID: "s1", Hostname: "app-1", Rule: "rule 3", From: "example.com/unassigned", To: "example.com/appservers"},
+		{ServerID: "s2", Hostname: "db-1", Rule: "db", From: "example.com/unassigned", To: "example.com/db"},
+		{ServerID: "s5", Hostname: "app-3", Rule: "aws", From: "example.com/db", To: "example.com/aws"},
+	}
+
+	if !reflect.DeepEqual(moves, expected) {
+		t.Errorf("expected moves %v; got %v", expected, moves)
+	}
+
+	if calls := strings.Join(account.calls, "; "); calls != "move s1 to app; move s2 to db; move s5 to aws" {
+		t.Errorf("unexpected calls: %s", calls)
+	}
+
+	account.calls = nil
+
+	if moves, err := g.Run(); err != nil || len(moves) != 0 || len(account.calls) != 0 {
+		t.Errorf("expected second run to move nothing; got %v, %v, %v", moves, err, account.calls)
+	}
+}
+
+func TestGrouper_DryRun(t *testing.T) {
+	account := newTestAccount()
+
+	g, err := New(account, testRules(), Options{DryRun: true, SourceGroups: []string{"example.com/unassigned"}})
+	if err != nil {
+		t.Fatalf("cannot create grouper: %v", err)
+	}
+
+	moves, err := g.Run()
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if len(moves) != 2 || moves[0].ServerID != "s1" || moves[1].ServerID != "s2" {
+		t.Errorf("expected moves of s1 and s2 only; got %v", moves)
+	}
+
+	if !strings.HasSuffix(moves[0].String(), "[dry run]") {
+		t.Errorf("expected dry run move; got %s", moves[0])
+	}
+
+	if len(account.calls) != 0 {
+		t.Errorf("expected no calls in dry run; got %v", account.calls)
+	}
+}
+
+func TestGrouper_FailedMoves(t *testing.T) {
+	account := newTestAccount()
+	account.fail["s2"] = true
+
+	rules := append(testRules(), Rule{Platform: "windows", Group: "example.com/windows"})
+
+	g, err := New(account, rules, Options{})
+	if err != nil {
+		t.Fatalf("cannot create grouper: %v", err)
+	}
+
+	moves, err := g.Run()
+	if err == nil || err.Error() != "cannot move 2 of 4 servers" {
+		t.Errorf("expected move errors; got %v", err)
+	}
+
+	var failed []string
+	for _, m := range moves {
+		if m.Error != "" {
+			failed = append(failed, m.ServerID+": "+m.Error)
+		}
+	}
+
+	expected := []string{
+		"s2: server s2 cannot be moved",
+		"s4: server group example.com/windows not found",
+	}
+
+	if !reflect.DeepEqual(failed, expected) {
+		t.Errorf("expected failed moves %v; got %v", expected, failed)
+	}
+
+	if account.servers[0].GroupID != "app" {
+		t.Errorf("expected other servers to be moved; got %+v", account.servers[0])
+	}
+}
+
+func TestGrouper_InvalidConfiguration(t *testing.T) {
+	g, err := New(newTestAccount(), testRules(), Options{SourceGroups: []string{"example.com/missing"}})
+	if err != nil {
+		t.Fatalf("cannot create grouper: %v", err)
+	}
+
+	if _, err := g.Run(); err == nil || err.Error() != "source server group example.com/missing not found" {
+		t.Errorf("expected error for missing source group; got %v", err)
+	}
+
+	if _, err := New(newTestAccount(), []Rule{{Hostname: "app"}}, Options{}); err == nil {
+		t.Errorf("expected error for rule without group")
+	}
+}
+
+func TestGrouper_Watch(t *testing.T) {
+	account := newTestAccount()
+
+	g, err := New(account, testRules(), Options{})
+	if err != nil {
+		t.Fatalf("cannot create grouper: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+
+	err = g.Watch(ctx, time.Millisecond, func(moves []Move, err error) {
+		runs++
+		if err != nil {
+			t.Errorf("run %d failed: %v", runs, err)
+		}
+
+		switch runs {
+		case 1:
+			if len(moves) != 3 {
+				t.Errorf("expected 3 moves in the first run; got %v", moves)
+			}
+			account.servers = append(account.servers, cphalo.Server{ID: "s6", Hostname: "app-4", Platform: "ubuntu", GroupID: "default"})
+		case 2:
+			if len(moves) != 1 || moves[0].ServerID != "s6" {
+				t.Errorf("expected new server to be moved in the second run; got %v", moves)
+			}
+			cancel()
+		}
+	})
+
+	if err != context.Canceled {
+		t.Errorf("expected watch to end with cancellation; got %v", err)
+	}
+
+	if runs != 2 {
+		t.Errorf("expected 2 runs; got %d", runs)
+	}
+
+	if err := g.Watch(context.Background(), 0, func([]Move, error) { t.Error("unexpected run") }); err == nil {
+		t.Error("expected error for zero interval")
+	}
+}
@@ -0,0 +1,113 @@
+package grouping
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// Rule matches servers by their attributes and names the group path, such as
+// "example.com/appservers", matching servers belong to.
+//
+// All set attributes have to match, a rule without any attribute matches all
+// servers. Platform, kernel name and CSP provider are compared ignoring case.
+type Rule struct {
+	Name string `json:"name,omitempty"`
+	// Hostname is a regular expression the hostname has to match.
+	Hostname string `json:"hostname,omitempty"`
+	Platform string `json:"platform,omitempty"`
+	// ConnectingIPAddress lists addresses and networks the connecting IP
+	// address has to be in, separated by commas in JSON like zone addresses.
+	ConnectingIPAddress cphalo.IPList `json:"connecting_ip_address,omitempty"`
+	KernelName          string        `json:"kernel_name,omitempty"`
+	ServerLabel         string        `json:"server_label,omitempty"`
+	CSPProvider         string        `json:"csp_provider,omitempty"`
+	CSPAccountID        string        `json:"csp_account_id,omitempty"`
+	Group               string        `json:"group"`
+
+	hostname *regexp.Regexp
+	networks cphalo.IPSet
+}
+
+// ParseRules decodes a JSON list of rules.
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("cannot decode rules: %v", err)
+	}
+
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i+1, err)
+		}
+	}
+
+	return rules, nil
+}
+
+// compile validates the rule and compiles its hostname expression and
+// connecting IP addresses, which are used by matches.
+func (r *Rule) compile() error {
+	if strings.Trim(r.Group, "/") == "" {
+		return fmt.Errorf("rule without group")
+	}
+
+	if r.Hostname != "" {
+		re, err := regexp.Compile(r.Hostname)
+		if err != nil {
+			return fmt.Errorf("invalid hostname %q: %v", r.Hostname, err)
+		}
+		r.hostname = re
+	}
+
+	set, err := r.ConnectingIPAddress.Parse()
+	if err != nil {
+		return fmt.Errorf("invalid connecting IP address: %v", err)
+	}
+	r.networks = set
+
+	return nil
+}
+
+// matches reports whether the server matches the compiled rule.
+func (r Rule) matches(s cphalo.Server) bool {
+	if r.hostname != nil && !r.hostname.MatchString(s.Hostname) {
+		return false
+	}
+
+	if len(r.ConnectingIPAddress) > 0 {
+		addr, err := netip.ParseAddr(s.ConnectingIPAddress)
+		if err != nil || !r.networks.Contains(addr) {
+			return false
+		}
+	}
+
+	for _, m := range []struct{ want, got string }{
+		{r.Platform, s.Platform},
+		{r.KernelName, s.KernelName},
+		{r.CSPProvider, s.CSPProvider},
+	} {
+		if m.want != "" && !strings.EqualFold(m.want, m.got) {
+			return false
+		}
+	}
+
+	if r.ServerLabel != "" && r.ServerLabel != s.ServerLabel {
+		return false
+	}
+
+	if r.CSPAccountID != "" && r.CSPAccountID != s.CSPAccountID {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,80 @@
+package grouping
+
+import (
+	"strings"
+	"testing"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+func TestRule_matches(t *testing.T) {
+	server := cphalo.Server{
+		Hostname:            "web-12.prod",
+		ServerLabel:         "web",
+		Platform:            "ubuntu",
+		KernelName:          "Linux",
+		ConnectingIPAddress: "10.1.2.3",
+		CSPProvider:         "aws_ec2",
+		CSPAccountID:        "550529930677",
+	}
+
+	tests := []struct {
+		name    string
+		rule    Rule
+		matches bool
+	}{
+		{"catch all", Rule{}, true},
+		{"hostname", Rule{Hostname: `^web-\d+\.prod$`}, true},
+		{"other hostname", Rule{Hostname: `^db-`}, false},
+		{"platform ignoring case", Rule{Platform: "Ubuntu"}, true},
+		{"other platform", Rule{Platform: "centos"}, false},
+		{"kernel", Rule{KernelName: "linux"}, true},
+		{"network", Rule{ConnectingIPAddress: cphalo.IPList{"192.168.0.0/16", "10.1.0.0/16"}}, true},
+		{"address", Rule{ConnectingIPAddress: cphalo.IPList{"10.1.2.3"}}, true},
+		{"other network", Rule{ConnectingIPAddress: cphalo.IPList{"10.2.0.0/16"}}, false},
+		{"label", Rule{ServerLabel: "web"}, true},
+		{"label is exact", Rule{ServerLabel: "we"}, false},
+		{"csp account", Rule{CSPProvider: "aws_ec2", CSPAccountID: "550529930677"}, true},
+		{"other csp account", Rule{CSPAccountID: "1"}, false},
+		{"all attributes have to match", Rule{Hostname: "web", Platform: "windows"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Group = "example.com"
+			if err := tt.rule.compile(); err != nil {
+				t.Fatalf("cannot compile rule: %v", err)
+			}
+
+			if matches := tt.rule.matches(server); matches != tt.matches {
+				t.Errorf("expected match %t; got %t", tt.matches, matches)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(`[
+		{"name": "web", "hostname": "^web-", "group": "example.com/web"},
+		{"connecting_ip_address": "10.0.0.0/8,192.168.0.0/16", "group": "example.com/internal"}
+	]`))
+
+	if err != nil {
+		t.Fatalf("cannot parse rules: %v", err)
+	}
+
+	if len(rules) != 2 || rules[0].Name != "web" || rules[1].Group != "example.com/internal" {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+
+	for _, doc := range []string{
+		`[{"hostname": "(", "group": "example.com"}]`,
+		`[{"connecting_ip_address": "10.0.0.0/33", "group": "example.com"}]`,
+		`[{"platform": "linux"}]`,
+		`[{"os": "linux", "group": "example.com"}]`,
+	} {
+		if _, err := ParseRules(strings.NewReader(doc)); err == nil {
+			t.Errorf("expected error parsing %s", doc)
+		}
+	}
+}
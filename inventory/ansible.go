@@ -0,0 +1,260 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ansibleGroup represent an Ansible group with its hosts and child groups.
+type ansibleGroup struct {
+	name     string
+	hosts    []ansibleHost
+	children []string
+}
+
+// ansibleHost represent an Ansible host with its variables in order.
+type ansibleHost struct {
+	name string
+	vars [][2]string
+}
+
+// ansibleGroupName converts the group path into a valid Ansible group name,
+// "example.com/appservers" becomes "example_com_appservers".
+func ansibleGroupName(path string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, path)
+}
+
+// ansibleGroups returns groups of the server group hierarchy ordered by name.
+// Servers are hosts of the groups of their GroupPath, ancestor groups have
+// their children groups. Servers without a group path are ungrouped.
+//
+// Group paths converted into the same Ansible group name, such as "a.b/x" and
+// "a_b/x", or into a name of the built-in groups all and ungrouped result in
+// an error, as their hosts would be merged.
+func (inv *Inventory) ansibleGroups() ([]*ansibleGroup, error) {
+	paths := map[string]string{"all": "", "ungrouped": ""}
+	groupName := func(path []string) (string, error) {
+		p := strings.Join(path, "/")
+		name := ansibleGroupName(p)
+
+		switch other, ok := paths[name]; {
+		case ok && other == "":
+			return "", fmt.Errorf("server group %s clashes with the Ansible group %s", p, name)
+		case ok && other != p:
+			return "", fmt.Errorf("server groups %s and %s are both the Ansible group %s", other, p, name)
+		}
+		paths[name] = p
+
+		return name, nil
+	}
+
+	groups := map[string]*ansibleGroup{}
+	group := func(name string) *ansibleGroup {
+		if g, ok := groups[name]; ok {
+			return g
+		}
+		g := &ansibleGroup{name: name}
+		groups[name] = g
+		return g
+	}
+
+	hostnames := map[string]int{}
+	for _, h := range inv.Hosts {
+		hostnames[h.Server.Hostname]++
+	}
+
+	for _, h := range inv.Hosts {
+		host := ansibleHost{name: h.Server.Hostname}
+		if host.name == "" || hostnames[host.name] > 1 {
+			host.name = strings.TrimPrefix(host.name+"_"+h.Server.ID, "_")
+		}
+
+		if addr := h.Server.PrimaryIPAddress; addr != "" {
+			host.vars = append(host.vars, [2]string{"ansible_host", addr})
+		}
+		for _, f := range inv.Fields {
+			if v := h.Field(f); v != "" {
+				host.vars = append(host.vars, [2]string{fieldPrefix + f, v})
+			}
+		}
+
+		var path []string
+		for _, name := range strings.Split(h.Server.GroupPath, "/") {
+			if name != "" {
+				path = append(path, name)
+			}
+		}
+
+		if len(path) == 0 {
+			g := group("ungrouped")
+			g.hosts = append(g.hosts, host)
+			continue
+		}
+
+		for i := 1; i < len(path); i++ {
+			parentName, err := groupName(path[:i])
+			if err != nil {
+				return nil, err
+			}
+			child, err := groupName(path[:i+1])
+			if err != nil {
+				return nil, err
+			}
+
+			parent := group(parentName)
+			if !containsString(parent.children, child) {
+				parent.children = append(parent.children, child)
+			}
+		}
+
+		name, err := groupName(path)
+		if err != nil {
+			return nil, err
+		}
+
+		g := group(name)
+		g.hosts = append(g.hosts, host)
+	}
+
+	var sorted []*ansibleGroup
+	for _, g := range groups {
+		sort.Strings(g.children)
+		sorted = append(sorted, g)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].name < sorted[j].name
+	})
+
+	return sorted, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WriteAnsibleINI writes the hosts as Ansible INI inventory grouped by
+// their group paths, see WriteAnsibleYAML.
+func (inv *Inventory) WriteAnsibleINI(w io.Writer) error {
+	groups, err := inv.ansibleGroups()
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+
+	for i, g := range groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		if len(g.hosts) > 0 {
+			fmt.Fprintf(&b, "[%s]\n", g.name)
+			for _, h := range g.hosts {
+				b.WriteString(h.name)
+				for _, v := range h.vars {
+					fmt.Fprintf(&b, " %s=%s", v[0], quoteINIValue(v[1]))
+				}
+				b.WriteString("\n")
+			}
+		}
+
+		if len(g.children) > 0 {
+			if len(g.hosts) > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "[%s:children]\n", g.name)
+			for _, child := range g.children {
+				b.WriteString(child + "\n")
+			}
+		}
+	}
+
+	_, err = io.WriteString(w, b.String())
+
+	return err
+}
+
+// quoteINIValue quotes values, which would not be read back as they are.
+func quoteINIValue(v string) string {
+	if strings.ContainsAny(v, " \t\"'=#;\\") {
+		return strconv.Quote(v)
+	}
+
+	return v
+}
+
+// WriteAnsibleYAML writes the hosts as Ansible YAML inventory.
+//
+// Every server group path becomes a group named like "example_com_appservers"
+// with the servers of the group as hosts and its child groups as children.
+// Host variables are the fields prefixed by "halo_" and ansible_host set to
+// the primary IP address. Group paths resulting in the same group name are
+// reported as an error.
+func (inv *Inventory) WriteAnsibleYAML(w io.Writer) error {
+	groups, err := inv.ansibleGroups()
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+
+	b.WriteString("all:\n")
+	b.WriteString("  children:\n")
+
+	for _, g := range groups {
+		if len(g.hosts) == 0 && len(g.children) == 0 {
+			fmt.Fprintf(&b, "    %s: {}\n", g.name)
+			continue
+		}
+
+		fmt.Fprintf(&b, "    %s:\n", g.name)
+
+		if len(g.hosts) > 0 {
+			b.WriteString("      hosts:\n")
+			for _, h := range g.hosts {
+				if len(h.vars) == 0 {
+					fmt.Fprintf(&b, "        %s: {}\n", yamlString(h.name))
+					continue
+				}
+				fmt.Fprintf(&b, "        %s:\n", yamlString(h.name))
+				for _, v := range h.vars {
+					fmt.Fprintf(&b, "          %s: %s\n", v[0], yamlString(v[1]))
+				}
+			}
+		}
+
+		if len(g.children) > 0 {
+			b.WriteString("      children:\n")
+			for _, child := range g.children {
+				fmt.Fprintf(&b, "        %s: {}\n", child)
+			}
+		}
+	}
+
+	_, err = io.WriteString(w, b.String())
+
+	return err
+}
+
+// yamlString returns the string as a double-quoted YAML scalar, JSON strings
+// being valid YAML.
+func yamlString(s string) string {
+	b, _ := json.Marshal(s)
+
+	return string(b)
+}
@@ -0,0 +1,145 @@
+package inventory
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+func ansibleTestInventory() *Inventory {
+	host := func(ID, hostname, addr, path string) Host {
+		return Host{Server: cphalo.Server{ID: ID, Hostname: hostname, PrimaryIPAddress: addr, GroupPath: path, ServerLabel: "label " + ID}}
+	}
+
+	return &Inventory{
+		Fields: []string{"id", "server_label"},
+		Hosts: []Host{
+			host("s1", "web-1", "10.0.0.1", "example.com/appservers"),
+			host("s2", "web", "10.0.0.2", "example.com/appservers/canary"),
+			host("s3", "web", "", "example.com/appservers/canary"),
+			host("s4", "db-1", "10.0.1.1", "example.com/db"),
+			host("s5", "lost", "", ""),
+		},
+	}
+}
+
+func TestInventory_WriteAnsibleINI(t *testing.T) {
+	var b strings.Builder
+	if err := ansibleTestInventory().WriteAnsibleINI(&b); err != nil {
+		t.Fatalf("cannot write inventory: %v", err)
+	}
+
+	expected := `[example_com:children]
+example_com_appservers
+example_com_db
+
+[example_com_appservers]
+web-1 ansible_host=10.0.0.1 halo_id=s1 halo_server_label="label s1"
+
+[example_com_appservers:children]
+example_com_appservers_canary
+
+[example_com_appservers_canary]
+web_s2 ansible_host=10.0.0.2 halo_id=s2 halo_server_label="label s2"
+web_s3 halo_id=s3 halo_server_label="label s3"
+
+[example_com_db]
+db-1 ansible_host=10.0.1.1 halo_id=s4 halo_server_label="label s4"
+
+[ungrouped]
+lost halo_id=s5 halo_server_label="label s5"
+`
+
+	if b.String() != expected {
+		t.Errorf("expected inventory:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+func TestInventory_WriteAnsibleYAML(t *testing.T) {
+	var b strings.Builder
+	if err := ansibleTestInventory().WriteAnsibleYAML(&b); err != nil {
+		t.Fatalf("cannot write inventory: %v", err)
+	}
+
+	expected := `all:
+  children:
+    example_com:
+      children:
+        example_com_appservers: {}
+        example_com_db: {}
+    example_com_appservers:
+      hosts:
+        "web-1":
+          ansible_host: "10.0.0.1"
+          halo_id: "s1"
+          halo_server_label: "label s1"
+      children:
+        example_com_appservers_canary: {}
+    example_com_appservers_canary:
+      hosts:
+        "web_s2":
+          ansible_host: "10.0.0.2"
+          halo_id: "s2"
+          halo_server_label: "label s2"
+        "web_s3":
+          halo_id: "s3"
+          halo_server_label: "label s3"
+    example_com_db:
+      hosts:
+        "db-1":
+          ansible_host: "10.0.1.1"
+          halo_id: "s4"
+          halo_server_label: "label s4"
+    ungrouped:
+      hosts:
+        "lost":
+          halo_id: "s5"
+          halo_server_label: "label s5"
+`
+
+	if b.String() != expected {
+		t.Errorf("expected inventory:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+func TestAnsibleGroupName(t *testing.T) {
+	if name := ansibleGroupName("example.com/app-servers"); name != "example_com_app_servers" {
+		t.Errorf("expected example_com_app_servers; got %s", name)
+	}
+}
+
+func TestInventory_AnsibleGroupCollisions(t *testing.T) {
+	host := func(ID, path string) Host {
+		return Host{Server: cphalo.Server{ID: ID, Hostname: "host-" + ID, GroupPath: path}}
+	}
+
+	tests := []struct {
+		name  string
+		paths []string
+		err   string
+	}{
+		{"same name", []string{"x/a.b", "x/a_b"}, "server groups x/a.b and x/a_b are both the Ansible group x_a_b"},
+		{"same parent name", []string{"a.b/x", "a_b/y"}, "server groups a.b and a_b are both the Ansible group a_b"},
+		{"ungrouped", []string{"ungrouped"}, "server group ungrouped clashes with the Ansible group ungrouped"},
+		{"all", []string{"example.com", "all"}, "server group all clashes with the Ansible group all"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := &Inventory{}
+			for i, path := range tt.paths {
+				inv.Hosts = append(inv.Hosts, host(strconv.Itoa(i), path))
+			}
+
+			var b strings.Builder
+			if err := inv.WriteAnsibleINI(&b); err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q; got %v", tt.err, err)
+			}
+			if err := inv.WriteAnsibleYAML(&b); err == nil || err.Error() != tt.err {
+				t.Errorf("expected error %q; got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+// Package inventory exports CPHalo servers with their groups to formats of
+// other tools: CSV, JSON Lines, Ansible inventories and Prometheus file_sd.
+package inventory
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// API lists the servers to export and the server groups giving them their
+// group paths and tags.
+type API interface {
+	ListServersWithOptions(opts cphalo.ListServersOptions) (cphalo.ListServersResponse, error)
+	ListServerGroups() (cphalo.ListServerGroupsResponse, error)
+}
+
+// fields maps names of the exported fields to their values.
+var fields = map[string]func(h Host) string{
+	"id":                    func(h Host) string { return h.Server.ID },
+	"hostname":              func(h Host) string { return h.Server.Hostname },
+	"server_label":          func(h Host) string { return h.Server.ServerLabel },
+	"reported_fqdn":         func(h Host) string { return h.Server.ReportedFQDN },
+	"primary_ip_address":    func(h Host) string { return h.Server.PrimaryIPAddress },
+	"connecting_ip_address": func(h Host) string { return h.Server.ConnectingIPAddress },
+	"state":                 func(h Host) string { return h.Server.State },
+	"platform":              func(h Host) string { return h.Server.Platform },
+	"platform_version":      func(h Host) string { return h.Server.PlatformVersion },
+	"os_version":            func(h Host) string { return h.Server.OSVersion },
+	"kernel_name":           func(h Host) string { return h.Server.KernelName },
+	"kernel_machine":        func(h Host) string { return h.Server.KernelMachine },
+	"daemon_version":        func(h Host) string { return h.Server.DaemonVersion },
+	"created_at":            func(h Host) string { return formatTime(h.Server.CreatedAt) },
+	"last_state_change":     func(h Host) string { return formatTime(h.Server.LastStateChange) },
+	"csp_provider":          func(h Host) string { return h.Server.CSPProvider },
+	"csp_account_id":        func(h Host) string { return h.Server.CSPAccountID },
+	"csp_instance_id":       func(h Host) string { return h.Server.CSPInstanceID },
+	"csp_region":            func(h Host) string { return h.Server.CSPRegion },
+	"group_id":              func(h Host) string { return h.Server.GroupID },
+	"group_name":            func(h Host) string { return h.Server.GroupName },
+	"group_path":            func(h Host) string { return h.Server.GroupPath },
+	"group_tag":             func(h Host) string { return h.Group.Tag },
+	"group_description":     func(h Host) string { return h.Group.Description },
+}
+
+// fieldPrefix prefixes fields in Ansible host variables and Prometheus labels,
+// so they do not clash with variables and labels of the tools.
+const fieldPrefix = "halo_"
+
+// DefaultFields are exported when no fields are selected.
+var DefaultFields = []string{"id", "hostname", "primary_ip_address", "state", "platform", "group_path"}
+
+// Fields returns names of all fields, which can be exported.
+func Fields() []string {
+	var names []string
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// allStates are listed when no states are selected, as CPHalo lists only
+// active servers by default.
+var allStates = []string{cphalo.ServerStateActive, cphalo.ServerStateMissing, cphalo.ServerStateDeactivated, cphalo.ServerStateRetired}
+
+// Options configure the inventory.
+type Options struct {
+	// Fields are columns of CSV, keys of JSON Lines, Ansible host variables
+	// and Prometheus labels, see Fields. DefaultFields are used when empty.
+	Fields []string
+	// States limits the servers to the states. All servers when empty.
+	States []string
+	// Platforms limits the servers to the platforms. All servers when empty.
+	Platforms []string
+}
+
+// Host represent an exported server with its group.
+type Host struct {
+	Server cphalo.Server
+	Group  cphalo.ServerGroup
+}
+
+// Field returns value of the field of the host, see Fields.
+func (h Host) Field(name string) string {
+	if f, ok := fields[name]; ok {
+		return f(h)
+	}
+
+	return ""
+}
+
+// Inventory represent servers selected for the export, ordered by group
+// path and hostname.
+type Inventory struct {
+	Hosts  []Host
+	Fields []string
+}
+
+// Load lists servers and groups and selects the servers by the options.
+func Load(api API, opts Options) (*Inventory, error) {
+	inv := &Inventory{Fields: opts.Fields}
+	if len(inv.Fields) == 0 {
+		inv.Fields = DefaultFields
+	}
+
+	for _, f := range inv.Fields {
+		if _, ok := fields[f]; !ok {
+			return nil, fmt.Errorf("unknown field %s", f)
+		}
+	}
+
+	groups, err := api.ListServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list server groups: %v", err)
+	}
+
+	byID := map[string]cphalo.ServerGroup{}
+	for _, g := range groups.Groups {
+		byID[g.ID] = g
+	}
+
+	states := allStates
+	if len(opts.States) > 0 {
+		states = make([]string, len(opts.States))
+		for i, s := range opts.States {
+			states[i] = strings.ToLower(s)
+		}
+	}
+
+	servers, err := api.ListServersWithOptions(cphalo.ListServersOptions{States: states})
+	if err != nil {
+		return nil, fmt.Errorf("cannot list servers: %v", err)
+	}
+
+	for _, s := range servers.Servers {
+		if !anyEqualFold(opts.Platforms, s.Platform) {
+			continue
+		}
+
+		inv.Hosts = append(inv.Hosts, Host{Server: s, Group: byID[s.GroupID]})
+	}
+
+	sort.SliceStable(inv.Hosts, func(i, j int) bool {
+		a, b := inv.Hosts[i].Server, inv.Hosts[j].Server
+		if a.GroupPath != b.GroupPath {
+			return a.GroupPath < b.GroupPath
+		}
+		return a.Hostname < b.Hostname
+	})
+
+	return inv, nil
+}
+
+// anyEqualFold reports whether the value is in the list ignoring case, an
+// empty list contains all values.
+func anyEqualFold(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WriteCSV writes the hosts as CSV with a header of the fields.
+func (inv *Inventory) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(inv.Fields); err != nil {
+		return fmt.Errorf("cannot write CSV header: %v", err)
+	}
+
+	for _, h := range inv.Hosts {
+		record := make([]string, len(inv.Fields))
+		for i, f := range inv.Fields {
+			record[i] = h.Field(f)
+		}
+
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("cannot write CSV record of server %s: %v", h.Server.ID, err)
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// WriteJSONLines writes every host as a JSON object of the fields on its own line.
+func (inv *Inventory) WriteJSONLines(w io.Writer) error {
+	enc := json.NewEncoder(w)
+
+	for _, h := range inv.Hosts {
+		if err := enc.Encode(inv.values(h)); err != nil {
+			return fmt.Errorf("cannot write server %s: %v", h.Server.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// values returns the fields of the host by their names.
+func (inv *Inventory) values(h Host) map[string]string {
+	values := map[string]string{}
+	for _, f := range inv.Fields {
+		values[f] = h.Field(f)
+	}
+
+	return values
+}
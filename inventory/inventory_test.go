@@ -0,0 +1,138 @@
+package inventory
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// catalog returns fixed servers and groups, recording the states servers
+// were listed in.
+type catalog struct {
+	servers []cphalo.Server
+	groups  []cphalo.ServerGroup
+	states  []string
+}
+
+func (c *catalog) ListServersWithOptions(opts cphalo.ListServersOptions) (cphalo.ListServersResponse, error) {
+	c.states = opts.States
+
+	var servers []cphalo.Server
+	for _, s := range c.servers {
+		for _, state := range opts.States {
+			if s.State == state {
+				servers = append(servers, s)
+			}
+		}
+	}
+
+	return cphalo.ListServersResponse{Count: len(servers), Servers: servers}, nil
+}
+
+func (c *catalog) ListServerGroups() (cphalo.ListServerGroupsResponse, error) {
+	return cphalo.ListServerGroupsResponse{Count: len(c.groups), Groups: c.groups}, nil
+}
+
+func newTestCatalog() *catalog {
+	return &catalog{
+		groups: []cphalo.ServerGroup{
+			{ID: "g-app", Name: "appservers", Tag: "app"},
+			{ID: "g-db", Name: "db", Tag: "db"},
+		},
+		servers: []cphalo.Server{
+			{ID: "s2", Hostname: "web-2", PrimaryIPAddress: "10.0.0.2", State: "active", Platform: "ubuntu", GroupID: "g-app", GroupPath: "example.com/appservers"},
+			{ID: "s3", Hostname: "db-1", PrimaryIPAddress: "10.0.1.1", State: "active", Platform: "centos", GroupID: "g-db", GroupPath: "example.com/db"},
+			{ID: "s1", Hostname: "web-1", PrimaryIPAddress: "10.0.0.1", State: "active", Platform: "ubuntu", GroupID: "g-app", GroupPath: "example.com/appservers",
+				LastStateChange: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+			{ID: "s4", Hostname: "win-1", State: "missing", Platform: "windows", GroupID: "g-app", GroupPath: "example.com/appservers"},
+		},
+	}
+}
+
+func loadTestInventory(t *testing.T, opts Options) *Inventory {
+	inv, err := Load(newTestCatalog(), opts)
+	if err != nil {
+		t.Fatalf("cannot load inventory: %v", err)
+	}
+
+	return inv
+}
+
+func TestLoad(t *testing.T) {
+	inv := loadTestInventory(t, Options{})
+
+	var ids []string
+	for _, h := range inv.Hosts {
+		ids = append(ids, h.Server.ID)
+	}
+
+	if expected := []string{"s1", "s2", "s4", "s3"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected hosts %v ordered by group path and hostname; got %v", expected, ids)
+	}
+
+	if !reflect.DeepEqual(inv.Fields, DefaultFields) {
+		t.Errorf("expected default fields; got %v", inv.Fields)
+	}
+
+	if tag := inv.Hosts[0].Field("group_tag"); tag != "app" {
+		t.Errorf("expected group tag app; got %s", tag)
+	}
+
+	if changed := inv.Hosts[0].Field("last_state_change"); changed != "2020-01-02T03:04:05Z" {
+		t.Errorf("expected last state change in RFC 3339; got %s", changed)
+	}
+
+	if _, err := Load(newTestCatalog(), Options{Fields: []string{"hostname", "uptime"}}); err == nil {
+		t.Errorf("expected error for unknown field")
+	}
+}
+
+func TestLoad_Filters(t *testing.T) {
+	c := newTestCatalog()
+
+	inv, err := Load(c, Options{States: []string{"ACTIVE"}, Platforms: []string{"ubuntu", "centos"}})
+	if err != nil || len(inv.Hosts) != 3 {
+		t.Errorf("expected 3 active linux hosts; got %+v, %v", inv, err)
+	}
+
+	if !reflect.DeepEqual(c.states, []string{cphalo.ServerStateActive}) {
+		t.Errorf("expected servers listed in state active; got %v", c.states)
+	}
+
+	if _, err := Load(c, Options{}); err != nil || len(c.states) != 4 {
+		t.Errorf("expected servers listed in all states; got %v, %v", c.states, err)
+	}
+}
+
+func TestInventory_WriteCSV(t *testing.T) {
+	inv := loadTestInventory(t, Options{Fields: []string{"hostname", "primary_ip_address", "group_path"}, Platforms: []string{"ubuntu"}})
+
+	var b strings.Builder
+	if err := inv.WriteCSV(&b); err != nil {
+		t.Fatalf("cannot write CSV: %v", err)
+	}
+
+	expected := "hostname,primary_ip_address,group_path\n" +
+		"web-1,10.0.0.1,example.com/appservers\n" +
+		"web-2,10.0.0.2,example.com/appservers\n"
+
+	if b.String() != expected {
+		t.Errorf("expected CSV:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+func TestInventory_WriteJSONLines(t *testing.T) {
+	inv := loadTestInventory(t, Options{Fields: []string{"id", "state"}, States: []string{"missing"}})
+
+	var b strings.Builder
+	if err := inv.WriteJSONLines(&b); err != nil {
+		t.Fatalf("cannot write JSON lines: %v", err)
+	}
+
+	if expected := `{"id":"s4","state":"missing"}` + "\n"; b.String() != expected {
+		t.Errorf("expected %s; got %s", expected, b.String())
+	}
+}
@@ -0,0 +1,65 @@
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// FileSDTargetGroup represent a target group of Prometheus file based service
+// discovery.
+type FileSDTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// FileSD returns hosts as Prometheus file_sd target groups. Targets are
+// primary IP addresses of the servers with the port, when set. Labels are
+// the fields prefixed by "halo_", hosts with the same labels share a group.
+// Hosts without a primary IP address are left out.
+func (inv *Inventory) FileSD(port int) []FileSDTargetGroup {
+	groups := []FileSDTargetGroup{}
+	index := map[string]int{}
+
+	for _, h := range inv.Hosts {
+		target := h.Server.PrimaryIPAddress
+		if target == "" {
+			continue
+		}
+		if port > 0 {
+			target = net.JoinHostPort(target, strconv.Itoa(port))
+		}
+
+		labels := map[string]string{}
+		for _, f := range inv.Fields {
+			if v := h.Field(f); v != "" {
+				labels[fieldPrefix+f] = v
+			}
+		}
+
+		key, _ := json.Marshal(labels)
+		if i, ok := index[string(key)]; ok {
+			groups[i].Targets = append(groups[i].Targets, target)
+			continue
+		}
+
+		index[string(key)] = len(groups)
+		groups = append(groups, FileSDTargetGroup{Targets: []string{target}, Labels: labels})
+	}
+
+	return groups
+}
+
+// WriteFileSD writes the hosts as Prometheus file_sd JSON, see FileSD.
+func (inv *Inventory) WriteFileSD(w io.Writer, port int) error {
+	b, err := json.MarshalIndent(inv.FileSD(port), "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal file_sd targets: %v", err)
+	}
+
+	_, err = w.Write(append(b, '\n'))
+
+	return err
+}
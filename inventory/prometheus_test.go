@@ -0,0 +1,41 @@
+package inventory
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInventory_FileSD(t *testing.T) {
+	inv := loadTestInventory(t, Options{Fields: []string{"group_path", "platform"}})
+
+	expected := []FileSDTargetGroup{
+		{
+			Targets: []string{"10.0.0.1:9100", "10.0.0.2:9100"},
+			Labels:  map[string]string{"halo_group_path": "example.com/appservers", "halo_platform": "ubuntu"},
+		},
+		{
+			Targets: []string{"10.0.1.1:9100"},
+			Labels:  map[string]string{"halo_group_path": "example.com/db", "halo_platform": "centos"},
+		},
+	}
+
+	if groups := inv.FileSD(9100); !reflect.DeepEqual(groups, expected) {
+		t.Errorf("expected target groups %+v; got %+v", expected, groups)
+	}
+
+	var b strings.Builder
+	if err := inv.WriteFileSD(&b, 0); err != nil {
+		t.Fatalf("cannot write file_sd: %v", err)
+	}
+
+	var written []FileSDTargetGroup
+	if err := json.Unmarshal([]byte(b.String()), &written); err != nil {
+		t.Fatalf("cannot unmarshal file_sd: %v", err)
+	}
+
+	if len(written) != 2 || !reflect.DeepEqual(written[0].Targets, []string{"10.0.0.1", "10.0.0.2"}) {
+		t.Errorf("expected targets without port; got %+v", written)
+	}
+}
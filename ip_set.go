@@ -0,0 +1,409 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// IPSet represent a set of IPv4 and IPv6 addresses, such as addresses of
+// a firewall zone, kept as sorted ranges of netip addresses, IPv4 before
+// IPv6. The zero value is an empty set, sets are built by ParseIPSet.
+//
+// IPSet marshals into the comma separated firewall zone format and vice versa.
+type IPSet []addrRange
+
+// ParseIPSet parses the addresses, which can be single addresses, CIDR
+// networks or ranges written as "10.0.0.1-10.0.0.9".
+func ParseIPSet(ips ...string) (IPSet, error) {
+	ranges, err := parseAddrRanges(ips)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeAddrRanges(ranges), nil
+}
+
+// MustParseIPSet calls ParseIPSet and panics on error, e.g. for addresses
+// known at compile time.
+func MustParseIPSet(ips ...string) IPSet {
+	set, err := ParseIPSet(ips...)
+	if err != nil {
+		panic(err)
+	}
+
+	return set
+}
+
+// Parse parses the addresses, see ParseIPSet.
+func (i IPList) Parse() (IPSet, error) {
+	return ParseIPSet(i...)
+}
+
+// Validate checks all addresses can be parsed.
+func (i IPList) Validate() error {
+	_, err := i.Parse()
+	return err
+}
+
+// Normalize returns the addresses deduplicated and aggregated into the
+// smallest list of CIDR networks, IPv4 before IPv6.
+func (i IPList) Normalize() (IPList, error) {
+	set, err := i.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return set.IPList(), nil
+}
+
+// IPList returns the set as a list of CIDR networks, single addresses
+// are listed without prefix length.
+func (s IPSet) IPList() IPList {
+	ips := IPList{}
+
+	for _, p := range s.Prefixes() {
+		if p.IsSingleIP() {
+			ips = append(ips, p.Addr().String())
+		} else {
+			ips = append(ips, p.String())
+		}
+	}
+
+	return ips
+}
+
+// Prefixes returns the set as the smallest list of CIDR networks.
+func (s IPSet) Prefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+
+	for _, r := range s {
+		for from := r.from; ; {
+			var p netip.Prefix
+			for bits := 0; bits <= from.BitLen(); bits++ {
+				p = netip.PrefixFrom(from, bits)
+				if p.Masked().Addr() == from && !r.to.Less(prefixRange(p).to) {
+					break
+				}
+			}
+			prefixes = append(prefixes, p)
+
+			end := prefixRange(p).to
+			if end == r.to {
+				break
+			}
+			from = end.Next()
+		}
+	}
+
+	return prefixes
+}
+
+// IsEmpty reports whether the set has no addresses.
+func (s IPSet) IsEmpty() bool {
+	return len(s) == 0
+}
+
+// Contains reports whether the address is in the set.
+func (s IPSet) Contains(addr netip.Addr) bool {
+	return addrsContain(s, addr.Unmap().WithZone(""))
+}
+
+// ContainsSet reports whether all addresses of the other set are in the set.
+func (s IPSet) ContainsSet(o IPSet) bool {
+	return addrsCover(s, o)
+}
+
+// Equal reports whether both sets have the same addresses.
+func (s IPSet) Equal(o IPSet) bool {
+	if len(s) != len(o) {
+		return false
+	}
+
+	for i := range s {
+		if s[i] != o[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Union returns addresses in either set.
+func (s IPSet) Union(o IPSet) IPSet {
+	return mergeAddrRanges(append(append([]addrRange{}, s...), o...))
+}
+
+// Intersect returns addresses in both sets.
+func (s IPSet) Intersect(o IPSet) IPSet {
+	var ranges []addrRange
+
+	for _, a := range s {
+		for _, b := range o {
+			if a.from.BitLen() != b.from.BitLen() {
+				continue
+			}
+
+			r := a
+			if r.from.Less(b.from) {
+				r.from = b.from
+			}
+			if b.to.Less(r.to) {
+				r.to = b.to
+			}
+			if !r.to.Less(r.from) {
+				ranges = append(ranges, r)
+			}
+		}
+	}
+
+	return mergeAddrRanges(ranges)
+}
+
+// Subtract returns addresses of the set not in the other set.
+func (s IPSet) Subtract(o IPSet) IPSet {
+	ranges := append([]addrRange{}, s...)
+
+	for _, b := range o {
+		var left []addrRange
+
+		for _, a := range ranges {
+			if a.from.BitLen() != b.from.BitLen() || a.to.Less(b.from) || b.to.Less(a.from) {
+				left = append(left, a)
+				continue
+			}
+
+			if a.from.Less(b.from) {
+				left = append(left, addrRange{from: a.from, to: b.from.Prev()})
+			}
+			if b.to.Less(a.to) {
+				left = append(left, addrRange{from: b.to.Next(), to: a.to})
+			}
+		}
+
+		ranges = left
+	}
+
+	return ranges
+}
+
+// String returns the set in the firewall zone format.
+func (s IPSet) String() string {
+	return strings.Join(s.zoneList(), ",")
+}
+
+// zoneList returns the set as a list of single addresses, CIDR networks and
+// ranges, which are not a single network, written as "10.0.0.1-10.0.0.9".
+func (s IPSet) zoneList() []string {
+	ips := []string{}
+
+	for _, r := range s {
+		switch prefixes := (IPSet{r}).Prefixes(); {
+		case r.from == r.to:
+			ips = append(ips, r.from.String())
+		case len(prefixes) == 1:
+			ips = append(ips, prefixes[0].String())
+		default:
+			ips = append(ips, r.from.String()+"-"+r.to.String())
+		}
+	}
+
+	return ips
+}
+
+// MarshalJSON is used by marshaler interface.
+func (s IPSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON is used by unmarshaler interface.
+func (s *IPSet) UnmarshalJSON(in []byte) error {
+	var ips string
+	if err := json.Unmarshal(in, &ips); err != nil {
+		return err
+	}
+
+	set, err := parseZoneAddresses(ips)
+	if err != nil {
+		return err
+	}
+
+	*s = set
+
+	return nil
+}
+
+// Validate checks the zone has IP addresses.
+func (z FirewallZone) Validate() error {
+	if z.IPAddress.IsEmpty() {
+		return fmt.Errorf("zone %s has no IP addresses", z.Name)
+	}
+
+	return nil
+}
+
+// Contains reports whether the address is in the zone.
+func (z FirewallZone) Contains(addr netip.Addr) bool {
+	return z.IPAddress.Contains(addr)
+}
+
+// Union returns addresses in either zone.
+func (z FirewallZone) Union(o FirewallZone) IPSet {
+	return z.IPAddress.Union(o.IPAddress)
+}
+
+// Intersect returns addresses in both zones.
+func (z FirewallZone) Intersect(o FirewallZone) IPSet {
+	return z.IPAddress.Intersect(o.IPAddress)
+}
+
+// Subtract returns addresses of the zone not in the other zone.
+func (z FirewallZone) Subtract(o FirewallZone) IPSet {
+	return z.IPAddress.Subtract(o.IPAddress)
+}
+
+// addrRange is an inclusive range of IP addresses.
+type addrRange struct {
+	from, to netip.Addr
+}
+
+// parseAddrRanges parses zone addresses, which can be single addresses,
+// CIDR networks or ranges written as "10.0.0.1-10.0.0.9".
+//
+// IPv4-mapped IPv6 addresses are parsed as IPv4 addresses, IPv6 zones are not
+// supported by firewall zones.
+func parseAddrRanges(ips IPList) ([]addrRange, error) {
+	var ranges []addrRange
+
+	for _, ip := range ips {
+		ip = strings.TrimSpace(ip)
+
+		switch {
+		case strings.Contains(ip, "/"):
+			prefix, err := netip.ParsePrefix(ip)
+			if err != nil {
+				return nil, fmt.Errorf("invalid network %q", ip)
+			}
+			if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+				prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+			}
+			ranges = append(ranges, prefixRange(prefix.Masked()))
+		case strings.Contains(ip, "-"):
+			bounds := strings.SplitN(ip, "-", 2)
+			from, err1 := parseAddr(bounds[0])
+			to, err2 := parseAddr(bounds[1])
+			if err1 != nil || err2 != nil || from.BitLen() != to.BitLen() || to.Less(from) {
+				return nil, fmt.Errorf("invalid range %q", ip)
+			}
+			ranges = append(ranges, addrRange{from: from, to: to})
+		default:
+			addr, err := parseAddr(ip)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", ip)
+			}
+			ranges = append(ranges, addrRange{from: addr, to: addr})
+		}
+	}
+
+	return ranges, nil
+}
+
+// parseZoneAddresses parses addresses in the comma separated firewall zone
+// format, empty entries are skipped.
+func parseZoneAddresses(s string) (IPSet, error) {
+	var ips IPList
+
+	for _, ip := range strings.Split(s, ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips.Parse()
+}
+
+func parseAddr(s string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return addr, err
+	}
+
+	if addr.Zone() != "" {
+		return addr, fmt.Errorf("IPv6 zone %s not supported", addr.Zone())
+	}
+
+	return addr.Unmap(), nil
+}
+
+func prefixRange(prefix netip.Prefix) addrRange {
+	from := prefix.Addr()
+	bytes := from.AsSlice()
+
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 1 << (7 - uint(bit%8))
+	}
+
+	to, _ := netip.AddrFromSlice(bytes)
+
+	return addrRange{from: from, to: to}
+}
+
+// mergeAddrRanges returns the ranges sorted, IPv4 before IPv6, with
+// overlapping and adjacent ranges merged.
+func mergeAddrRanges(ranges []addrRange) []addrRange {
+	merged := append([]addrRange{}, ranges...)
+	sort.Slice(merged, func(i, j int) bool { return merged[i].from.Less(merged[j].from) })
+
+	for i := 0; i < len(merged)-1; {
+		cur, next := merged[i], merged[i+1]
+		if cur.to.BitLen() == next.from.BitLen() && (!cur.to.Less(next.from) || cur.to.Next() == next.from) {
+			if cur.to.Less(next.to) {
+				merged[i].to = next.to
+			}
+			merged = append(merged[:i+1], merged[i+2:]...)
+			continue
+		}
+		i++
+	}
+
+	return merged
+}
+
+// addrsCover reports whether ranges a include all addresses of ranges b.
+func addrsCover(a, b []addrRange) bool {
+	merged := mergeAddrRanges(a)
+
+	for _, rb := range b {
+		covered := false
+		for _, ra := range merged {
+			if !rb.from.Less(ra.from) && !ra.to.Less(rb.to) && ra.from.BitLen() == rb.from.BitLen() {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+
+	return true
+}
+
+// addrsContain reports whether the address is in the ranges.
+func addrsContain(ranges []addrRange, addr netip.Addr) bool {
+	for _, r := range ranges {
+		if !addr.Less(r.from) && !r.to.Less(addr) && r.from.BitLen() == addr.BitLen() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isAnyAddress reports whether the ranges cover every IPv4 or IPv6 address.
+func isAnyAddress(ranges []addrRange) bool {
+	return addrsCover(ranges, []addrRange{prefixRange(netip.MustParsePrefix("0.0.0.0/0"))}) ||
+		addrsCover(ranges, []addrRange{prefixRange(netip.MustParsePrefix("::/0"))})
+}
@@ -0,0 +1,171 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestIPList_Normalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		ips      IPList
+		expected IPList
+	}{
+		{"empty", IPList{}, IPList{}},
+		{"single", IPList{"10.0.0.1"}, IPList{"10.0.0.1"}},
+		{"host prefix", IPList{"10.0.0.1/32"}, IPList{"10.0.0.1"}},
+		{"unmasked", IPList{"10.0.0.1/8"}, IPList{"10.0.0.0/8"}},
+		{"duplicate", IPList{"10.0.0.1", " 10.0.0.1"}, IPList{"10.0.0.1"}},
+		{"contained", IPList{"10.1.0.0/16", "10.0.0.0/8", "10.2.3.4"}, IPList{"10.0.0.0/8"}},
+		{"adjacent", IPList{"10.0.0.0/25", "10.0.0.128/25"}, IPList{"10.0.0.0/24"}},
+		{"range", IPList{"10.0.0.1-10.0.0.6"}, IPList{"10.0.0.1", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6"}},
+		{"any", IPList{"0.0.0.0/0", "1.2.3.4"}, IPList{"0.0.0.0/0"}},
+		{"ipv6", IPList{"2001:db8::/33", "2001:db8:8000::/33", "10.0.0.1"}, IPList{"10.0.0.1", "2001:db8::/32"}},
+		{"ipv4-mapped", IPList{"::ffff:10.0.0.1", "::ffff:10.0.0.0/120", "::ffff:10.0.1.1-::ffff:10.0.1.2"}, IPList{"10.0.0.0/24", "10.0.1.1", "10.0.1.2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.ips.Normalize()
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v; got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIPList_Validate(t *testing.T) {
+	for _, ips := range []IPList{
+		{"10.0.0.256"},
+		{"10.0.0.0/33"},
+		{"10.0.0.9-10.0.0.1"},
+		{"10.0.0.1-2001:db8::1"},
+		{"2001:db8:::1"},
+		{"fe80::1%eth0"},
+		{"fe80::1%eth0-fe80::9%eth0"},
+		{"fe80::/64%eth0"},
+		{""},
+	} {
+		if err := ips.Validate(); err == nil {
+			t.Errorf("expected %v to be invalid", ips)
+		}
+	}
+
+	if err := (IPList{"10.0.0.0/8", "10.0.0.1-10.0.0.9", "2001:db8::1"}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestIPSet_Operations(t *testing.T) {
+	parse := func(ips ...string) IPSet {
+		set, err := IPList(ips).Parse()
+		if err != nil {
+			t.Fatalf("cannot parse %v: %v", ips, err)
+		}
+		return set
+	}
+
+	a := parse("10.0.0.0/24", "2001:db8::/64")
+	b := parse("10.0.0.128/25", "192.168.0.1")
+
+	if got := a.Union(b).String(); got != "10.0.0.0/24,192.168.0.1,2001:db8::/64" {
+		t.Errorf("unexpected union %s", got)
+	}
+
+	if got := a.Intersect(b).String(); got != "10.0.0.128/25" {
+		t.Errorf("unexpected intersection %s", got)
+	}
+
+	if got := a.Subtract(b).String(); got != "10.0.0.0/25,2001:db8::/64" {
+		t.Errorf("unexpected difference %s", got)
+	}
+
+	if got := a.Subtract(parse("10.0.0.5")).IPList(); len(got) != 9 || got[0] != "10.0.0.0/30" || got[1] != "10.0.0.4" || got[2] != "10.0.0.6/31" {
+		t.Errorf("unexpected difference %v", got)
+	}
+
+	if !a.Subtract(a).IsEmpty() || !a.Intersect(parse("2001:db9::1")).IsEmpty() {
+		t.Error("expected empty sets")
+	}
+
+	if !a.ContainsSet(parse("10.0.0.1-10.0.0.200")) || a.ContainsSet(b) {
+		t.Error("unexpected ContainsSet result")
+	}
+
+	if !a.Equal(parse("10.0.0.0/25", "10.0.0.128/25", "2001:db8::/64")) || a.Equal(b) {
+		t.Error("unexpected Equal result")
+	}
+}
+
+func TestFirewallZone_Contains(t *testing.T) {
+	zone := FirewallZone{Name: "office", IPAddress: MustParseIPSet("10.0.0.0/8", "192.168.1.1-192.168.1.9")}
+
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{"10.20.30.40", true},
+		{"192.168.1.5", true},
+		{"192.168.1.10", false},
+		{"::ffff:10.0.0.1", true},
+		{"2001:db8::1", false},
+	}
+
+	for _, tt := range tests {
+		if got := zone.Contains(netip.MustParseAddr(tt.ip)); got != tt.expected {
+			t.Errorf("expected Contains(%s) to be %v; got %v", tt.ip, tt.expected, got)
+		}
+	}
+
+	if !(FirewallZone{IPAddress: MustParseIPSet("fe80::/64")}).Contains(netip.MustParseAddr("fe80::1%eth0")) {
+		t.Error("expected link-local zone to contain zoned address")
+	}
+}
+
+func TestFirewallZone_SetOperations(t *testing.T) {
+	office := FirewallZone{Name: "office", IPAddress: MustParseIPSet("10.0.0.0/8")}
+	vpn := FirewallZone{Name: "vpn", IPAddress: MustParseIPSet("10.1.0.0/16", "172.16.0.1")}
+
+	if got := office.Union(vpn).IPList(); !reflect.DeepEqual(got, IPList{"10.0.0.0/8", "172.16.0.1"}) {
+		t.Errorf("unexpected union %v", got)
+	}
+
+	if got := office.Intersect(vpn).IPList(); !reflect.DeepEqual(got, IPList{"10.1.0.0/16"}) {
+		t.Errorf("unexpected intersection %v", got)
+	}
+
+	if got := vpn.Subtract(office).IPList(); !reflect.DeepEqual(got, IPList{"172.16.0.1"}) {
+		t.Errorf("unexpected difference %v", got)
+	}
+}
+
+func TestIPSet_JSON(t *testing.T) {
+	var zone FirewallZone
+	if err := json.Unmarshal([]byte(`{"ip_address": "10.0.0.1-10.0.0.9, 10.0.0.0/8,\r\n192.168.1.1-192.168.1.2,"}`), &zone); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !zone.IPAddress.Equal(MustParseIPSet("10.0.0.0/8", "192.168.1.1", "192.168.1.2")) {
+		t.Errorf("unexpected addresses %v", zone.IPAddress)
+	}
+
+	zone.IPAddress = MustParseIPSet("10.0.0.1-10.0.0.9", "192.168.0.0/24")
+	if b, err := json.Marshal(zone); err != nil || string(b) != `{"ip_address":"10.0.0.1-10.0.0.9,192.168.0.0/24"}` {
+		t.Errorf("unexpected zone %s: %v", b, err)
+	}
+
+	if b, err := json.Marshal(FirewallZone{Name: "office"}); err != nil || string(b) != `{"name":"office"}` {
+		t.Errorf("expected addresses to be left out; got %s: %v", b, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"ip_address": "10.0.0.256"}`), &zone); err == nil {
+		t.Error("expected error for invalid address")
+	}
+}
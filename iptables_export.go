@@ -0,0 +1,240 @@
+package cphalo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// IPTablesExporter renders firewall policies as iptables-save rulesets.
+type IPTablesExporter struct {
+	// Objects are used to expand zones, services and interfaces of rules.
+	Objects FirewallObjects
+	// GroupAddresses maps server group IDs to addresses of their servers,
+	// rules with group sources and targets are rendered for these addresses.
+	GroupAddresses map[string]IPList
+}
+
+// ExportIPTables renders the policy as iptables-save ruleset.
+func ExportIPTables(policy FirewallPolicy, objects FirewallObjects) (string, error) {
+	return IPTablesExporter{Objects: objects}.Export(policy)
+}
+
+// Export renders active rules of the policy as the iptables-save ruleset of
+// the filter table, so it can be compared with the ruleset enforced on a server.
+//
+// Rules with multiple addresses are expanded into a rule per address and
+// logging rules into a LOG rule followed by the rule itself. Rules referring
+// GhostPorts users or server groups without known addresses are left out with
+// a comment.
+func (e IPTablesExporter) Export(policy FirewallPolicy) (string, error) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# CPHalo firewall policy %s\n", policy.Name)
+	b.WriteString("*filter\n")
+	b.WriteString(":INPUT DROP [0:0]\n")
+	b.WriteString(":OUTPUT DROP [0:0]\n")
+
+	for _, r := range resolveFirewallRules(policy, e.Objects) {
+		if !r.rule.GetActive() {
+			continue
+		}
+
+		if len(r.missing) > 0 {
+			return "", fmt.Errorf("rule %d references missing %s", r.position, strings.Join(r.missing, ", "))
+		}
+
+		lines, err := e.rule(r)
+		if err != nil {
+			fmt.Fprintf(&b, "# rule %d skipped: %v\n", r.position, err)
+			continue
+		}
+
+		for _, l := range lines {
+			b.WriteString(l)
+			b.WriteString("\n")
+		}
+	}
+
+	b.WriteString("COMMIT\n")
+
+	return b.String(), nil
+}
+
+func (e IPTablesExporter) rule(r resolvedRule) ([]string, error) {
+	sources, err := e.addresses(r.rule.FirewallSource, "-s", "--src-range")
+	if err != nil {
+		return nil, fmt.Errorf("source: %v", err)
+	}
+
+	targets, err := e.addresses(r.rule.FirewallTarget, "-d", "--dst-range")
+	if err != nil {
+		return nil, fmt.Errorf("target: %v", err)
+	}
+
+	var matches []string
+
+	if r.iface != "" {
+		if r.rule.Chain == FirewallChainOutput {
+			matches = append(matches, "-o "+r.iface)
+		} else {
+			matches = append(matches, "-i "+r.iface)
+		}
+	}
+
+	// Matches are rendered the way iptables-save prints them: no protocol
+	// for any protocol and a protocol match module only with its options.
+	if protocol := FirewallProtocol(r.protocol); protocol != "" && protocol != FirewallProtocolANY {
+		proto := strings.ToLower(r.protocol)
+		matches = append(matches, "-p "+proto)
+
+		if protocol.HasPorts() && r.ports != nil {
+			ports := formatPorts(r.ports)
+
+			switch {
+			case protocol == FirewallProtocolICMP:
+				matches = append(matches, "-m icmp --icmp-type "+ports[0])
+			case len(ports) > 1:
+				matches = append(matches, fmt.Sprintf("-m multiport --dports %s", strings.Join(ports, ",")))
+			default:
+				matches = append(matches, fmt.Sprintf("-m %s --dport %s", proto, ports[0]))
+			}
+		}
+	}
+
+	if states := normalizedConnectionStates(r.rule.ConnectionStates); states != "" {
+		matches = append(matches, "-m state --state "+states)
+	}
+
+	if r.rule.Comment != "" {
+		matches = append(matches, "-m comment --comment "+quoteIPTablesArg(r.rule.Comment))
+	}
+
+	var targetsArgs []string
+
+	if r.rule.GetLog() {
+		log := "-j LOG"
+		if r.rule.LogPrefix != "" {
+			log += " --log-prefix " + quoteIPTablesArg(r.rule.LogPrefix)
+		}
+		targetsArgs = append(targetsArgs, log)
+	}
+
+	switch r.rule.Action {
+	case FirewallActionReject:
+		targetsArgs = append(targetsArgs, "-j REJECT --reject-with icmp-port-unreachable")
+	default:
+		targetsArgs = append(targetsArgs, "-j "+string(r.rule.Action))
+	}
+
+	var lines []string
+
+	for _, jump := range targetsArgs {
+		for _, s := range sources {
+			for _, t := range targets {
+				args := []string{"-A", string(r.rule.Chain)}
+				for _, a := range []string{s, t} {
+					if a != "" {
+						args = append(args, a)
+					}
+				}
+				args = append(args, matches...)
+				args = append(args, jump)
+				lines = append(lines, strings.Join(args, " "))
+			}
+		}
+	}
+
+	return lines, nil
+}
+
+// addresses returns iptables address matches of the endpoint, a single
+// empty match meaning any address. Addresses of all active servers are listed
+// by group ID. IPv6 addresses are left out, they belong to ip6tables.
+func (e IPTablesExporter) addresses(st *FirewallRuleSourceTarget, flag, rangeFlag string) ([]string, error) {
+	if st == nil {
+		return []string{""}, nil
+	}
+
+	var sets []IPSet
+
+	switch st.Kind {
+	case FirewallEndpointZone:
+		zone, ok := e.Objects.Zone(st.GetID())
+		if !ok {
+			ips, err := parseZoneAddresses(st.IPAddress)
+			if err != nil {
+				return nil, fmt.Errorf("zone %s: %v", refName(st.Name, st.GetID()), err)
+			}
+			zone.IPAddress = ips
+		}
+		sets = append(sets, zone.IPAddress)
+	case FirewallEndpointGroup:
+		ids := []string{st.GetID()}
+		if st.GetID() == FirewallAllActiveServers {
+			ids = nil
+			for id := range e.GroupAddresses {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+		}
+
+		for _, id := range ids {
+			ips, err := e.GroupAddresses[id].Parse()
+			if err != nil {
+				return nil, fmt.Errorf("invalid addresses of group %s: %v", id, err)
+			}
+			sets = append(sets, ips)
+		}
+	default:
+		return nil, fmt.Errorf("%s endpoints are not rendered", st.Kind)
+	}
+
+	var matches []string
+	ipv6 := false
+
+	for _, set := range sets {
+		ipv6 = ipv6 || !set.Subtract(ipv4Addresses).IsEmpty()
+
+		for _, ip := range set.Intersect(ipv4Addresses).zoneList() {
+			switch {
+			case strings.Contains(ip, "-"):
+				matches = append(matches, fmt.Sprintf("-m iprange %s %s", rangeFlag, ip))
+			case strings.Contains(ip, "/"):
+				matches = append(matches, flag+" "+ip)
+			default:
+				matches = append(matches, flag+" "+ip+"/32")
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		if ipv6 {
+			return nil, fmt.Errorf("%s %s has only IPv6 addresses", st.Kind, refName(st.Name, st.GetID()))
+		}
+		return nil, fmt.Errorf("%s %s has no addresses", st.Kind, refName(st.Name, st.GetID()))
+	}
+
+	return matches, nil
+}
+
+// ipv4Addresses is the set of all IPv4 addresses.
+var ipv4Addresses = MustParseIPSet("0.0.0.0/0")
+
+// normalizedConnectionStates returns connection states in iptables form, empty for any.
+func normalizedConnectionStates(states string) string {
+	var parts []string
+
+	for _, s := range strings.Split(states, ",") {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s != "" && s != "ANY" {
+			parts = append(parts, s)
+		}
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func quoteIPTablesArg(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,180 @@
+package cphalo
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExportIPTables(t *testing.T) {
+	policy := FirewallPolicy{
+		Name: "web",
+		FirewallRules: []FirewallRule{
+			{Position: 1, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), ConnectionStates: "ESTABLISHED, RELATED"},
+			{Position: 2, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "z-office", Kind: "FirewallZone"}, FirewallService: &FirewallService{ID: "s-ssh"}, Comment: `say "hi"`},
+			{Position: 3, Chain: "INPUT", Action: "REJECT", Active: Bool(true), FirewallInterface: &FirewallInterface{ID: "i-eth1"}, FirewallService: &FirewallService{ID: "s-web"}, Log: Bool(true), LogPrefix: "web"},
+			{Position: 4, Chain: "INPUT", Action: "ACCEPT", Active: Bool(false)},
+			{Position: 5, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "u1", Kind: "User"}},
+			{Position: 6, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: "g-web", Kind: "Group"}, FirewallService: &FirewallService{ID: "s-high"}},
+			{Position: 7, Chain: "OUTPUT", Action: "ACCEPT", Active: Bool(true), FirewallTarget: &FirewallRuleSourceTarget{ID: "z-vpn", Kind: "FirewallZone"}, FirewallService: &FirewallService{ID: "s-dns"}},
+		},
+	}
+
+	exporter := IPTablesExporter{
+		Objects:        testFirewallObjects(),
+		GroupAddresses: map[string]IPList{"g-web": {"172.16.0.10"}},
+	}
+
+	out, err := exporter.Export(policy)
+
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	expected := `# CPHalo firewall policy web
+*filter
+:INPUT DROP [0:0]
+:OUTPUT DROP [0:0]
+-A INPUT -m state --state ESTABLISHED,RELATED -j ACCEPT
+-A INPUT -s 10.0.0.0/8 -p tcp -m tcp --dport 22 -m comment --comment "say \"hi\"" -j ACCEPT
+-A INPUT -s 192.168.1.1/32 -p tcp -m tcp --dport 22 -m comment --comment "say \"hi\"" -j ACCEPT
+-A INPUT -i eth1 -p tcp -m multiport --dports 80,443 -j LOG --log-prefix "web"
+-A INPUT -i eth1 -p tcp -m multiport --dports 80,443 -j REJECT --reject-with icmp-port-unreachable
+# rule 5 skipped: source: User endpoints are not rendered
+-A INPUT -s 172.16.0.10/32 -p tcp -m tcp --dport 1024:65535 -j ACCEPT
+-A OUTPUT -d 10.1.0.0/16 -p udp -m udp --dport 53 -j ACCEPT
+COMMIT
+`
+
+	if out != expected {
+		t.Errorf("expected ruleset:\n%s\ngot:\n%s", expected, out)
+	}
+
+	policy.FirewallRules = append(policy.FirewallRules, FirewallRule{Chain: "INPUT", Action: "DROP", Active: Bool(true), FirewallService: &FirewallService{ID: "s-gone"}})
+
+	if _, err := exporter.Export(policy); err == nil || !strings.Contains(err.Error(), "missing service s-gone") {
+		t.Errorf("expected missing service error; got %v", err)
+	}
+}
+
+func TestExportIPTables_Addresses(t *testing.T) {
+	policy := FirewallPolicy{
+		Name: "addresses",
+		FirewallRules: []FirewallRule{
+			{Position: 1, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{ID: FirewallAllActiveServers, Kind: "Group"}},
+			{Position: 2, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{Name: "mixed", IPAddress: "10.0.0.1-10.0.0.9,2001:db8::1", Kind: "FirewallZone"}},
+			{Position: 3, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallSource: &FirewallRuleSourceTarget{Name: "v6", IPAddress: "2001:db8::/32", Kind: "FirewallZone"}},
+		},
+	}
+
+	exporter := IPTablesExporter{
+		GroupAddresses: map[string]IPList{
+			"g-web": {"172.16.0.10"},
+			"g-db":  {"172.16.1.10", "2001:db8::10"},
+			"g-app": {"172.16.2.10"},
+			"g-lb":  {"172.16.3.10"},
+		},
+	}
+
+	expected := `# CPHalo firewall policy addresses
+*filter
+:INPUT DROP [0:0]
+:OUTPUT DROP [0:0]
+-A INPUT -s 172.16.2.10/32 -j ACCEPT
+-A INPUT -s 172.16.1.10/32 -j ACCEPT
+-A INPUT -s 172.16.3.10/32 -j ACCEPT
+-A INPUT -s 172.16.0.10/32 -j ACCEPT
+-A INPUT -m iprange --src-range 10.0.0.1-10.0.0.9 -j ACCEPT
+# rule 3 skipped: source: FirewallZone v6 has only IPv6 addresses
+COMMIT
+`
+
+	for i := 0; i < 10; i++ {
+		out, err := exporter.Export(policy)
+
+		if err != nil {
+			t.Fatalf("export failed: %v", err)
+		}
+
+		if out != expected {
+			t.Fatalf("expected ruleset:\n%s\ngot:\n%s", expected, out)
+		}
+	}
+}
+
+func TestExportIPTables_Protocols(t *testing.T) {
+	service := func(name string, protocol FirewallProtocol, port string) *FirewallService {
+		return &FirewallService{Name: name, Protocol: protocol, Port: port}
+	}
+
+	policy := FirewallPolicy{
+		Name: "protocols",
+		FirewallRules: []FirewallRule{
+			{Position: 1, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: service("any", FirewallProtocolANY, "")},
+			{Position: 2, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: service("gre", "47", "")},
+			{Position: 3, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: service("all-tcp", FirewallProtocolTCP, "")},
+			{Position: 4, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: service("all-icmp", "icmp", "")},
+			{Position: 5, Chain: "INPUT", Action: "ACCEPT", Active: Bool(true), FirewallService: service("ping", FirewallProtocolICMP, "8")},
+		},
+	}
+
+	out, err := ExportIPTables(policy, FirewallObjects{})
+
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	expected := `# CPHalo firewall policy protocols
+*filter
+:INPUT DROP [0:0]
+:OUTPUT DROP [0:0]
+-A INPUT -j ACCEPT
+-A INPUT -p 47 -j ACCEPT
+-A INPUT -p tcp -j ACCEPT
+-A INPUT -p icmp -j ACCEPT
+-A INPUT -p icmp -m icmp --icmp-type 8 -j ACCEPT
+COMMIT
+`
+
+	if out != expected {
+		t.Errorf("expected ruleset:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestExportIPTables_RoundTrip(t *testing.T) {
+	f, err := os.Open("testdata/iptables_save.txt")
+	if err != nil {
+		t.Fatalf("cannot open file: %v", err)
+	}
+	defer f.Close()
+
+	imp, err := ParseIPTablesSave(f, "imported")
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+
+	out, err := ExportIPTables(imp.Policy, imp.Objects)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	again, err := ParseIPTablesSave(strings.NewReader(out), "imported")
+	if err != nil {
+		t.Fatalf("parsing exported ruleset failed: %v", err)
+	}
+
+	if len(again.Warnings) != 0 {
+		t.Errorf("expected no warnings; got %v", again.Warnings)
+	}
+
+	if len(again.Policy.FirewallRules) != len(imp.Policy.FirewallRules) {
+		t.Fatalf("expected %d rules; got %d", len(imp.Policy.FirewallRules), len(again.Policy.FirewallRules))
+	}
+
+	for i := range imp.Policy.FirewallRules {
+		a, b := imp.Policy.FirewallRules[i], again.Policy.FirewallRules[i]
+		if !equalFirewallRuleMatches(a, b) || a.GetLog() != b.GetLog() || a.LogPrefix != b.LogPrefix || a.Comment != b.Comment {
+			t.Errorf("expected rule %d to survive round trip; got %+v and %+v", i+1, a, b)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package cphalo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// IPTablesImport represent a firewall policy converted from iptables-save output.
+//
+// Rules of the policy refer to zones, services and interfaces by name,
+// the objects they need are listed in Objects.
+type IPTablesImport struct {
+	Policy   FirewallPolicy
+	Objects  FirewallObjects
+	Warnings []string
+}
+
+// ParseIPTablesSave converts INPUT and OUTPUT chains of the filter table
+// in iptables-save output into a firewall policy with the given name.
+//
+// Rules, which cannot be expressed in CPHalo, e.g. negations, custom chains
+// or unsupported matches, are skipped and reported in warnings. LOG rules are
+// merged into the following rule with the same matches.
+func ParseIPTablesSave(r io.Reader, name string) (IPTablesImport, error) {
+	p := &iptablesParser{
+		imp: IPTablesImport{
+			Policy: FirewallPolicy{Name: name, Platform: "linux"},
+		},
+		zones:      map[string]bool{},
+		services:   map[string]bool{},
+		interfaces: map[string]bool{},
+	}
+
+	scanner := bufio.NewScanner(r)
+	table := ""
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "*"):
+			table = line[1:]
+		case line == "COMMIT":
+			table = ""
+		case table != "filter":
+		case strings.HasPrefix(line, ":"):
+			p.chainPolicy(n, line)
+		case strings.HasPrefix(line, "-A "):
+			args, err := splitIPTablesArgs(line)
+			if err != nil {
+				return p.imp, fmt.Errorf("line %d: %v", n, err)
+			}
+			p.rule(n, args[1:])
+		default:
+			p.warn(n, "unsupported line %q", line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return p.imp, fmt.Errorf("cannot read iptables-save output: %v", err)
+	}
+
+	if p.pendingLog != nil {
+		p.warn(p.pendingLogLine, "LOG rule without a following rule skipped")
+	}
+
+	return p.imp, nil
+}
+
+type iptablesParser struct {
+	imp        IPTablesImport
+	zones      map[string]bool
+	services   map[string]bool
+	interfaces map[string]bool
+
+	pendingLog     *FirewallRule
+	pendingLogLine int
+}
+
+func (p *iptablesParser) warn(line int, format string, args ...interface{}) {
+	p.imp.Warnings = append(p.imp.Warnings, fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, args...))
+}
+
+func (p *iptablesParser) chainPolicy(line int, s string) {
+	fields := strings.Fields(s[1:])
+	if len(fields) < 2 {
+		return
+	}
+
+	chain, policy := FirewallChain(fields[0]), FirewallAction(fields[1])
+
+	if (chain == FirewallChainInput || chain == FirewallChainOutput) && policy != FirewallActionDrop {
+		p.warn(line, "chain %s policy %s is not supported, CPHalo drops unmatched packets", chain, policy)
+	}
+}
+
+func (p *iptablesParser) rule(line int, args []string) {
+	if len(args) == 0 {
+		p.warn(line, "rule without chain skipped")
+		return
+	}
+
+	rule := FirewallRule{Chain: FirewallChain(args[0]), Active: Bool(true)}
+
+	if rule.Chain != FirewallChainInput && rule.Chain != FirewallChainOutput {
+		p.warn(line, "rule in chain %s skipped", rule.Chain)
+		return
+	}
+
+	var (
+		source, destination string
+		protocol, ports     string
+		inIface, outIface   string
+		logPrefix           string
+	)
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		value := func() string {
+			if i+1 >= len(args) {
+				return ""
+			}
+			i++
+			return args[i]
+		}
+
+		switch arg {
+		case "!":
+			p.warn(line, "negated match skipped the rule")
+			return
+		case "-s", "--source", "--src-range":
+			source = value()
+		case "-d", "--destination", "--dst-range":
+			destination = value()
+		case "-p", "--protocol":
+			protocol = strings.ToUpper(value())
+		case "--dport", "--destination-port", "--dports", "--destination-ports", "--icmp-type":
+			ports = value()
+		case "-i", "--in-interface":
+			inIface = value()
+		case "-o", "--out-interface":
+			outIface = value()
+		case "--state", "--ctstate":
+			rule.ConnectionStates = strings.Join(strings.Split(value(), ","), ", ")
+		case "--comment":
+			rule.Comment = value()
+		case "-m", "--match":
+			switch m := value(); m {
+			case "tcp", "udp", "icmp", "state", "conntrack", "comment", "multiport", "iprange":
+			default:
+				p.warn(line, "unsupported match %s skipped the rule", m)
+				return
+			}
+		case "-j", "--jump":
+			rule.Action = FirewallAction(value())
+		case "--log-prefix":
+			logPrefix = value()
+		case "--reject-with", "--log-level":
+			value()
+		default:
+			p.warn(line, "unsupported option %s skipped the rule", arg)
+			return
+		}
+	}
+
+	switch rule.Chain {
+	case FirewallChainInput:
+		if destination != "" || outIface != "" {
+			p.warn(line, "destination match in chain INPUT skipped the rule")
+			return
+		}
+		if source != "" {
+			zone, err := p.zone(source)
+			if err != nil {
+				p.warn(line, "%v skipped the rule", err)
+				return
+			}
+			rule.FirewallSource = zone
+		}
+		if inIface != "" {
+			rule.FirewallInterface = p.iface(inIface)
+		}
+	case FirewallChainOutput:
+		if source != "" || inIface != "" {
+			p.warn(line, "source match in chain OUTPUT skipped the rule")
+			return
+		}
+		if destination != "" {
+			zone, err := p.zone(destination)
+			if err != nil {
+				p.warn(line, "%v skipped the rule", err)
+				return
+			}
+			rule.FirewallTarget = zone
+		}
+		if outIface != "" {
+			rule.FirewallInterface = p.iface(outIface)
+		}
+	}
+
+	if protocol != "" && protocol != "ALL" {
+		rule.FirewallService = p.service(protocol, ports)
+	} else if ports != "" {
+		p.warn(line, "port match without protocol skipped the rule")
+		return
+	}
+
+	switch rule.Action {
+	case "LOG":
+		rule.Log = Bool(true)
+		rule.LogPrefix = logPrefix
+		if p.pendingLog != nil {
+			p.warn(p.pendingLogLine, "LOG rule not followed by a rule with the same matches skipped")
+		}
+		p.pendingLog, p.pendingLogLine = &rule, line
+		return
+	case FirewallActionAccept, FirewallActionDrop, FirewallActionReject:
+	default:
+		p.warn(line, "unsupported target %q skipped the rule", rule.Action)
+		return
+	}
+
+	if log := p.pendingLog; log != nil {
+		p.pendingLog = nil
+
+		matches := *log
+		matches.Action, matches.Log, matches.LogPrefix = rule.Action, nil, ""
+		if equalFirewallRuleMatches(matches, rule) {
+			rule.Log, rule.LogPrefix = Bool(true), log.LogPrefix
+		} else {
+			p.warn(p.pendingLogLine, "LOG rule not followed by a rule with the same matches skipped")
+		}
+	}
+
+	rule.Position = len(p.imp.Policy.FirewallRules) + 1
+	p.imp.Policy.FirewallRules = append(p.imp.Policy.FirewallRules, rule)
+}
+
+func (p *iptablesParser) zone(addr string) (*FirewallRuleSourceTarget, error) {
+	var ips IPList
+	for _, a := range strings.Split(addr, ",") {
+		ips = append(ips, strings.TrimSuffix(a, "/32"))
+	}
+	sort.Strings(ips)
+
+	set, err := ips.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.Join(ips, ",")
+	if !p.zones[name] {
+		p.zones[name] = true
+		p.imp.Objects.Zones = append(p.imp.Objects.Zones, FirewallZone{Name: name, IPAddress: set})
+	}
+
+	return &FirewallRuleSourceTarget{Name: name, Kind: FirewallEndpointZone}, nil
+}
+
+func (p *iptablesParser) service(protocol, ports string) *FirewallService {
+	name := strings.ToLower(protocol)
+	if ports != "" {
+		name += "/" + ports
+	}
+
+	if !p.services[name] {
+		p.services[name] = true
+		p.imp.Objects.Services = append(p.imp.Objects.Services, FirewallService{Name: name, Protocol: FirewallProtocol(protocol), Port: ports})
+	}
+
+	return &FirewallService{Name: name}
+}
+
+func (p *iptablesParser) iface(name string) *FirewallInterface {
+	if !p.interfaces[name] {
+		p.interfaces[name] = true
+		p.imp.Objects.Interfaces = append(p.imp.Objects.Interfaces, FirewallInterface{Name: name})
+	}
+
+	return &FirewallInterface{Name: name}
+}
+
+// equalFirewallRuleMatches compares rules, which refer objects by name.
+func equalFirewallRuleMatches(a, b FirewallRule) bool {
+	ref := func(st *FirewallRuleSourceTarget) string {
+		if st == nil {
+			return ""
+		}
+		return string(st.Kind) + "/" + st.GetID()
+	}
+
+	service := func(s *FirewallService) string {
+		if s == nil {
+			return ""
+		}
+		return s.Name
+	}
+
+	iface := func(i *FirewallInterface) string {
+		if i == nil {
+			return ""
+		}
+		return i.Name
+	}
+
+	return a.Chain == b.Chain &&
+		a.Action == b.Action &&
+		a.ConnectionStates == b.ConnectionStates &&
+		ref(a.FirewallSource) == ref(b.FirewallSource) &&
+		ref(a.FirewallTarget) == ref(b.FirewallTarget) &&
+		service(a.FirewallService) == service(b.FirewallService) &&
+		iface(a.FirewallInterface) == iface(b.FirewallInterface)
+}
+
+// splitIPTablesArgs splits the iptables-save line into arguments honouring
+// double quotes and backslash escapes.
+func splitIPTablesArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quoted  bool
+	)
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		switch {
+		case c == '\\' && i+1 < len(line):
+			i++
+			current.WriteByte(line[i])
+			inArg = true
+		case c == '"':
+			quoted = !quoted
+			inArg = true
+		case (c == ' ' || c == '\t') && !quoted:
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// ImportIPTablesPolicy creates the imported firewall policy with its rules.
+//
+// Zones, services and interfaces with the same name and definition are reused,
+// the missing ones are created. An object with the same name and a different
+// definition results in an error before anything is created.
+func (c *Client) ImportIPTablesPolicy(imp IPTablesImport) (policy FirewallPolicy, err error) {
+	return c.CreateFirewallPolicyFromTemplate(FirewallPolicyTemplate{Policy: imp.Policy, Objects: newFirewallTemplateObjects(imp.Objects)})
+}
@@ -0,0 +1,197 @@
+package cphalo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseIPTablesSave(t *testing.T) {
+	f, err := os.Open("testdata/iptables_save.txt")
+	if err != nil {
+		t.Fatalf("cannot open file: %v", err)
+	}
+	defer f.Close()
+
+	imp, err := ParseIPTablesSave(f, "imported")
+
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+
+	if imp.Policy.Name != "imported" || imp.Policy.Platform != "linux" {
+		t.Errorf("expected linux policy imported; got %s %s", imp.Policy.Platform, imp.Policy.Name)
+	}
+
+	rules := imp.Policy.FirewallRules
+	if len(rules) != 6 {
+		t.Fatalf("expected 6 rules; got %d: %+v", len(rules), rules)
+	}
+
+	if rules[0].ConnectionStates != "RELATED, ESTABLISHED" || rules[0].FirewallSource != nil {
+		t.Errorf("unexpected rule 1 %+v", rules[0])
+	}
+
+	if rules[1].Comment != `ssh from "office"` || rules[1].FirewallSource.Name != "10.0.0.0/8" || rules[1].FirewallService.Name != "tcp/22" {
+		t.Errorf("unexpected rule 2 %+v", rules[1])
+	}
+
+	if !rules[2].GetLog() || rules[2].LogPrefix != "web: " || rules[2].FirewallInterface.Name != "eth1" || rules[2].FirewallService.Name != "tcp/80,443" {
+		t.Errorf("expected rule 3 to be logged web rule on eth1; got %+v", rules[2])
+	}
+
+	if rules[3].FirewallSource.Name != "172.16.0.1-172.16.0.9" || rules[3].FirewallService.Name != "icmp/8" {
+		t.Errorf("unexpected rule 4 %+v", rules[3])
+	}
+
+	if rules[4].Action != "REJECT" || rules[5].Chain != "OUTPUT" || rules[5].FirewallTarget.Name != "8.8.8.8" {
+		t.Errorf("unexpected rules 5 and 6 %+v %+v", rules[4], rules[5])
+	}
+
+	for i, r := range rules {
+		if r.Position != i+1 {
+			t.Errorf("expected rule %d to have position %d; got %d", i, i+1, r.Position)
+		}
+	}
+
+	expectedObjects := []int{len(imp.Objects.Zones), len(imp.Objects.Services), len(imp.Objects.Interfaces)}
+	if expectedObjects[0] != 4 || expectedObjects[1] != 4 || expectedObjects[2] != 1 {
+		t.Errorf("expected 4 zones, 4 services and 1 interface; got %v", expectedObjects)
+	}
+
+	warnings := strings.Join(imp.Warnings, "\n")
+	for _, w := range []string{
+		"line 9: chain OUTPUT policy ACCEPT",
+		"line 16: negated match",
+		"line 17: unsupported match recent",
+		"line 18: unsupported target \"custom\"",
+		"line 20: rule in chain custom",
+	} {
+		if !strings.Contains(warnings, w) {
+			t.Errorf("expected warning %q; got\n%s", w, warnings)
+		}
+	}
+
+	if len(imp.Warnings) != 5 {
+		t.Errorf("expected 5 warnings; got %d", len(imp.Warnings))
+	}
+}
+
+func TestParseIPTablesSave_UnmatchedLog(t *testing.T) {
+	input := `*filter
+-A INPUT -p tcp -m tcp --dport 22 -j LOG
+-A INPUT -p tcp -m tcp --dport 23 -j DROP
+COMMIT
+`
+
+	imp, err := ParseIPTablesSave(strings.NewReader(input), "p")
+
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+
+	if len(imp.Policy.FirewallRules) != 1 || imp.Policy.FirewallRules[0].GetLog() {
+		t.Errorf("expected single rule without logging; got %+v", imp.Policy.FirewallRules)
+	}
+
+	if len(imp.Warnings) != 1 || !strings.Contains(imp.Warnings[0], "line 2: LOG rule not followed") {
+		t.Errorf("expected unmatched LOG warning; got %v", imp.Warnings)
+	}
+
+	if _, err := ParseIPTablesSave(strings.NewReader("*filter\n-A INPUT -m comment --comment \"open\n"), "p"); err == nil {
+		t.Errorf("expected unterminated quote to fail")
+	}
+}
+
+func TestParseIPTablesSave_InvalidAddress(t *testing.T) {
+	input := `*filter
+-A INPUT -s 10.0.0.300/32 -j ACCEPT
+-A INPUT -s 10.0.0.1/32 -j ACCEPT
+COMMIT
+`
+
+	imp, err := ParseIPTablesSave(strings.NewReader(input), "p")
+
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+
+	if len(imp.Policy.FirewallRules) != 1 || len(imp.Objects.Zones) != 1 || !imp.Objects.Zones[0].IPAddress.Equal(MustParseIPSet("10.0.0.1")) {
+		t.Errorf("expected single rule with zone 10.0.0.1; got %+v %+v", imp.Policy.FirewallRules, imp.Objects.Zones)
+	}
+
+	if len(imp.Warnings) != 1 || !strings.Contains(imp.Warnings[0], `line 2: invalid address "10.0.0.300" skipped the rule`) {
+		t.Errorf("expected invalid address warning; got %v", imp.Warnings)
+	}
+}
+
+func TestClient_ImportIPTablesPolicy(t *testing.T) {
+	var err error
+	var posts []string
+
+	counter := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			posts = append(posts, r.RequestURI)
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/firewall_zones":       jsonResponseTestHandler(t, "firewall_zones_list", http.StatusOK),
+			"GET /v1/firewall_services":    jsonResponseTestHandler(t, "firewall_services_list", http.StatusOK),
+			"GET /v1/firewall_interfaces":  jsonResponseTestHandler(t, "firewall_interfaces_list", http.StatusOK),
+			"POST /v1/firewall_zones":      counter(jsonResponseTestHandler(t, "firewall_zones_get", http.StatusCreated)),
+			"POST /v1/firewall_services":   counter(jsonResponseTestHandler(t, "firewall_services_get", http.StatusCreated)),
+			"POST /v1/firewall_interfaces": counter(jsonResponseTestHandler(t, "firewall_interfaces_get", http.StatusCreated)),
+			"POST /v1/firewall_policies":   counter(jsonResponseTestHandler(t, "firewall_policies_get", http.StatusCreated)),
+			"POST /v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117/firewall_rules": counter(jsonResponseTestHandler(t, "firewall_rules_get", http.StatusCreated)),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	input := `*filter
+-A INPUT -s 10.0.0.0/8 -i eth1 -p tcp -m tcp --dport 2222 -j ACCEPT
+-A INPUT -s 10.0.0.0/8 -p udp -m udp --dport 53 -j ACCEPT
+COMMIT
+`
+
+	imp, err := ParseIPTablesSave(strings.NewReader(input), "imported")
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+
+	policy, err := client.ImportIPTablesPolicy(imp)
+
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	if policy.ID != "be28b106ee5b11e8a7s1017da54e9117" || len(policy.FirewallRules) != 2 {
+		t.Errorf("expected created policy with 2 rules; got %+v", policy)
+	}
+
+	expectedPosts := "/v1/firewall_zones /v1/firewall_services /v1/firewall_services /v1/firewall_policies " +
+		"/v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117/firewall_rules /v1/firewall_policies/be28b106ee5b11e8a7s1017da54e9117/firewall_rules"
+	if strings.Join(posts, " ") != expectedPosts {
+		t.Errorf("expected posts %s; got %s", expectedPosts, strings.Join(posts, " "))
+	}
+
+	imp.Objects.Services = append(imp.Objects.Services, FirewallService{Name: "ssh", Protocol: "TCP", Port: "2222"})
+
+	_, err = client.ImportIPTablesPolicy(imp)
+
+	if err == nil || !strings.Contains(err.Error(), "service ssh already exists") {
+		t.Errorf("expected conflicting service error; got %v", err)
+	}
+}
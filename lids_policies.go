@@ -0,0 +1,296 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// LIDSRule represent a CPHalo LIDS rule, which matches a pattern in a log file.
+//
+// Rules are stored as a part of the LIDS policy and are identified by name.
+type LIDSRule struct {
+	Name     string `json:"name"`
+	FilePath string `json:"file_path"`
+	Pattern  string `json:"pattern"`
+	Active   bool   `json:"active"`
+	Alert    bool   `json:"alert"`
+	Critical bool   `json:"critical"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// LIDSPolicy represent a CPHalo log-based intrusion detection policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#lids-policies
+type LIDSPolicy struct {
+	ID          string         `json:"id,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Platform    string         `json:"platform,omitempty"`
+	Shared      StringableBool `json:"shared"`
+	Rules       []LIDSRule     `json:"rules,omitempty"`
+}
+
+// ListLIDSPoliciesResponse represent a list of LIDS policies response.
+type ListLIDSPoliciesResponse struct {
+	Count    int          `json:"count"`
+	Policies []LIDSPolicy `json:"lids_policies"`
+}
+
+// GetLIDSPolicyResponse represent a get LIDS policy response.
+type GetLIDSPolicyResponse struct {
+	Policy LIDSPolicy `json:"lids_policy"`
+}
+
+// CreateLIDSPolicyResponse represent a create LIDS policy response.
+type CreateLIDSPolicyResponse = GetLIDSPolicyResponse
+
+// CreateLIDSPolicyRequest represent a create LIDS policy request.
+type CreateLIDSPolicyRequest = GetLIDSPolicyResponse
+
+// UpdateLIDSPolicyRequest represent a update LIDS policy request.
+type UpdateLIDSPolicyRequest = GetLIDSPolicyResponse
+
+// UpdateLIDSRulesRequest represent a request replacing rules of a LIDS policy.
+//
+// Rules are sent even when there are none, so the last rule can be deleted.
+type UpdateLIDSRulesRequest struct {
+	Policy struct {
+		Rules []LIDSRule `json:"rules"`
+	} `json:"lids_policy"`
+}
+
+// ImportLIDSPolicyRequest represent an import LIDS policy request.
+type ImportLIDSPolicyRequest struct {
+	Policy struct {
+		PolicyFile string `json:"policy_file"`
+	} `json:"lids_policy"`
+}
+
+// ImportLIDSPolicyResponse represent an import LIDS policy response.
+type ImportLIDSPolicyResponse = GetLIDSPolicyResponse
+
+// ListLIDSPolicies lists all LIDS policies.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#list-lids-policies
+func (c *Client) ListLIDSPolicies() (response ListLIDSPoliciesResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "lids_policies", nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute request: %v", err)
+	}
+
+	return response, nil
+}
+
+// GetLIDSPolicy returns details of the LIDS policy including its rules.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#get-lids-policy-details
+func (c *Client) GetLIDSPolicy(ID string) (response GetLIDSPolicyResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "lids_policies/"+ID, nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// CreateLIDSPolicy creates a new LIDS policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#create-lids-policy
+func (c *Client) CreateLIDSPolicy(policy LIDSPolicy) (response CreateLIDSPolicyResponse, err error) {
+	req, err := c.newRequest(http.MethodPost, "lids_policies", nil, CreateLIDSPolicyRequest{Policy: policy})
+	if err != nil {
+		return response, fmt.Errorf("cannot create new create request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute create request: %v", err)
+	}
+
+	return response, nil
+}
+
+// UpdateLIDSPolicy updates LIDS policy.
+//
+// The rules of the policy are replaced by the provided ones.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#update-lids-policy
+func (c *Client) UpdateLIDSPolicy(policy LIDSPolicy) error {
+	req, err := c.newRequest(http.MethodPut, "lids_policies/"+policy.ID, nil, UpdateLIDSPolicyRequest{Policy: policy})
+	if err != nil {
+		return fmt.Errorf("cannot create new update request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute update request: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteLIDSPolicy deletes a LIDS policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#delete-lids-policy
+func (c *Client) DeleteLIDSPolicy(ID string) error {
+	req, err := c.newRequest(http.MethodDelete, "lids_policies/"+ID, nil, nil)
+	if err != nil {
+		return fmt.Errorf("cannot create new delete request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute delete request: %v", err)
+	}
+
+	return nil
+}
+
+// ExportLIDSPolicy returns the LIDS policy in CPHalo JSON export format.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#export-lids-policy
+func (c *Client) ExportLIDSPolicy(ID string) (json.RawMessage, error) {
+	var response json.RawMessage
+
+	req, err := c.newRequest(http.MethodGet, "lids_policies/"+ID+"/export", nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create new export request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return nil, fmt.Errorf("cannot execute export request: %v", err)
+	}
+
+	return response, nil
+}
+
+// ImportLIDSPolicy creates a new LIDS policy from CPHalo JSON export format.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#import-lids-policy
+func (c *Client) ImportLIDSPolicy(data []byte) (response ImportLIDSPolicyResponse, err error) {
+	if !json.Valid(data) {
+		return response, fmt.Errorf("policy file is not a valid JSON")
+	}
+
+	reqData := ImportLIDSPolicyRequest{}
+	reqData.Policy.PolicyFile = string(data)
+
+	req, err := c.newRequest(http.MethodPost, "lids_policies/import", nil, reqData)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new import request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute import request: %v", err)
+	}
+
+	return response, nil
+}
+
+// ListLIDSRules lists all rules of the LIDS policy.
+func (c *Client) ListLIDSRules(policyID string) ([]LIDSRule, error) {
+	resp, err := c.GetLIDSPolicy(policyID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get LIDS policy %s: %v", policyID, err)
+	}
+
+	return resp.Policy.Rules, nil
+}
+
+// CreateLIDSRule adds a new rule to the LIDS policy.
+//
+// Rule names have to be unique within the policy.
+func (c *Client) CreateLIDSRule(policyID string, rule LIDSRule) error {
+	return c.updateLIDSRules(policyID, func(p *LIDSPolicy) error {
+		if p.ruleIndex(rule.Name) >= 0 {
+			return fmt.Errorf("LIDS rule %s already exists in policy %s", rule.Name, policyID)
+		}
+
+		p.Rules = append(p.Rules, rule)
+
+		return nil
+	})
+}
+
+// UpdateLIDSRule replaces the rule with the same name in the LIDS policy.
+func (c *Client) UpdateLIDSRule(policyID string, rule LIDSRule) error {
+	return c.updateLIDSRules(policyID, func(p *LIDSPolicy) error {
+		i := p.ruleIndex(rule.Name)
+		if i < 0 {
+			return fmt.Errorf("LIDS rule %s not found in policy %s", rule.Name, policyID)
+		}
+
+		p.Rules[i] = rule
+
+		return nil
+	})
+}
+
+// DeleteLIDSRule removes the rule with the given name from the LIDS policy.
+func (c *Client) DeleteLIDSRule(policyID, name string) error {
+	return c.updateLIDSRules(policyID, func(p *LIDSPolicy) error {
+		i := p.ruleIndex(name)
+		if i < 0 {
+			return fmt.Errorf("LIDS rule %s not found in policy %s", name, policyID)
+		}
+
+		p.Rules = append(p.Rules[:i], p.Rules[i+1:]...)
+
+		return nil
+	})
+}
+
+func (c *Client) updateLIDSRules(policyID string, modify func(p *LIDSPolicy) error) error {
+	resp, err := c.GetLIDSPolicy(policyID)
+	if err != nil {
+		return fmt.Errorf("cannot get LIDS policy %s: %v", policyID, err)
+	}
+
+	policy := resp.Policy
+
+	if err = modify(&policy); err != nil {
+		return err
+	}
+
+	reqData := UpdateLIDSRulesRequest{}
+	reqData.Policy.Rules = policy.Rules
+	if reqData.Policy.Rules == nil {
+		reqData.Policy.Rules = []LIDSRule{}
+	}
+
+	req, err := c.newRequest(http.MethodPut, "lids_policies/"+policyID, nil, reqData)
+	if err != nil {
+		return fmt.Errorf("cannot create new update request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute update request: %v", err)
+	}
+
+	return nil
+}
+
+func (p LIDSPolicy) ruleIndex(name string) int {
+	for i, r := range p.Rules {
+		if r.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
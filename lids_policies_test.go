@@ -0,0 +1,385 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClient_ListLIDSPolicies(t *testing.T) {
+	var err error
+	expectedResults := 1
+	expectedID := "f3541070d5820132c0df06bf4fdcb353"
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "lids_policies_list", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/lids_policies",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.ListLIDSPolicies()
+
+	if err != nil {
+		t.Fatalf("LIDS policies list failed: %v", err)
+	}
+
+	if resp.Count != expectedResults {
+		t.Errorf("expected count to be %d; got %d", expectedResults, resp.Count)
+	}
+
+	if len(resp.Policies) != expectedResults {
+		t.Errorf("expected %d LIDS policies; got %d", expectedResults, len(resp.Policies))
+	}
+
+	if resp.Policies[0].ID != expectedID {
+		t.Errorf("expected LIDS policy 0 to have ID %s; got %s", expectedID, resp.Policies[0].ID)
+	}
+}
+
+func TestClient_GetLIDSPolicy(t *testing.T) {
+	var err error
+	expectedID := "f3541070d5820132c0df06bf4fdcb353"
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "lids_policies_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/lids_policies/id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.GetLIDSPolicy("id")
+
+	if err != nil {
+		t.Fatalf("LIDS policy get failed: %v", err)
+	}
+
+	if resp.Policy.ID != expectedID {
+		t.Errorf("expected LIDS policy to have ID %s; got %s", expectedID, resp.Policy.ID)
+	}
+
+	if len(resp.Policy.Rules) != 2 {
+		t.Fatalf("expected LIDS policy to have 2 rules; got %d", len(resp.Policy.Rules))
+	}
+
+	if resp.Policy.Rules[0].FilePath != "/var/log/auth.log" {
+		t.Errorf("expected rule 0 to have FilePath=/var/log/auth.log; got %s", resp.Policy.Rules[0].FilePath)
+	}
+
+	if !resp.Policy.Rules[0].Alert {
+		t.Errorf("expected rule 0 to alert")
+	}
+}
+
+func TestClient_CreateLIDSPolicy(t *testing.T) {
+	var err error
+	reqBody := CreateLIDSPolicyRequest{}
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "lids_policies_get", http.StatusCreated),
+			t,
+			http.MethodPost,
+			"/v1/lids_policies",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	policy := LIDSPolicy{
+		Name:     "hello",
+		Platform: "linux",
+		Rules: []LIDSRule{
+			{Name: "sudo", FilePath: "/var/log/auth.log", Pattern: "sudo:", Active: true, Critical: true},
+		},
+	}
+
+	resp, err := client.CreateLIDSPolicy(policy)
+
+	if err != nil {
+		t.Fatalf("LIDS policy creating failed: %v", err)
+	}
+
+	expectedID := "f3541070d5820132c0df06bf4fdcb353"
+	if resp.Policy.ID != expectedID {
+		t.Errorf("expected response to contain ID=%s; got %s", expectedID, resp.Policy.ID)
+	}
+
+	if len(reqBody.Policy.Rules) != 1 {
+		t.Fatalf("expected request to contain 1 rule; got %d", len(reqBody.Policy.Rules))
+	}
+
+	if reqBody.Policy.Rules[0] != policy.Rules[0] {
+		t.Errorf("expected request to contain rule %+v; got %+v", policy.Rules[0], reqBody.Policy.Rules[0])
+	}
+}
+
+func TestClient_UpdateLIDSPolicy(t *testing.T) {
+	var err error
+	reqBody := UpdateLIDSPolicyRequest{}
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodPut,
+			"/v1/lids_policies/id",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	policy := LIDSPolicy{ID: "id", Name: "hello", Description: "hai"}
+
+	err = client.UpdateLIDSPolicy(policy)
+
+	if err != nil {
+		t.Fatalf("LIDS policy updating failed: %v", err)
+	}
+
+	if reqBody.Policy.Description != policy.Description {
+		t.Errorf("expected request to contain Description=%s; got %s", policy.Description, reqBody.Policy.Description)
+	}
+}
+
+func TestClient_DeleteLIDSPolicy(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodDelete,
+			"/v1/lids_policies/id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.DeleteLIDSPolicy("id")
+
+	if err != nil {
+		t.Fatalf("LIDS policy deletion failed: %v", err)
+	}
+}
+
+func TestClient_ExportLIDSPolicy(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "lids_policies_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/lids_policies/id/export",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	data, err := client.ExportLIDSPolicy("id")
+
+	if err != nil {
+		t.Fatalf("LIDS policy export failed: %v", err)
+	}
+
+	exported := GetLIDSPolicyResponse{}
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("cannot unmarshal exported policy: %v", err)
+	}
+
+	if len(exported.Policy.Rules) != 2 {
+		t.Errorf("expected exported policy to have 2 rules; got %d", len(exported.Policy.Rules))
+	}
+}
+
+func TestClient_ImportLIDSPolicy(t *testing.T) {
+	var err error
+	reqBody := ImportLIDSPolicyRequest{}
+	policyFile := `{"lids_policy":{"name":"imported","platform":"linux"}}`
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "lids_policies_get", http.StatusCreated),
+			t,
+			http.MethodPost,
+			"/v1/lids_policies/import",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	if _, err = client.ImportLIDSPolicy([]byte("{invalid")); err == nil {
+		t.Errorf("expected import of invalid JSON to fail")
+	}
+
+	_, err = client.ImportLIDSPolicy([]byte(policyFile))
+
+	if err != nil {
+		t.Fatalf("LIDS policy import failed: %v", err)
+	}
+
+	if reqBody.Policy.PolicyFile != policyFile {
+		t.Errorf("expected request to contain policy file %s; got %s", policyFile, reqBody.Policy.PolicyFile)
+	}
+}
+
+func TestClient_LIDSRules(t *testing.T) {
+	var err error
+	reqBody := UpdateLIDSPolicyRequest{}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/lids_policies/id": jsonResponseTestHandler(t, "lids_policies_get", http.StatusOK),
+			"PUT /v1/lids_policies/id": bodyRecorderTestHandler(t, jsonResponseTestHandler(t, "", http.StatusNoContent), &reqBody),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	rules, err := client.ListLIDSRules("id")
+
+	if err != nil {
+		t.Fatalf("LIDS rules list failed: %v", err)
+	}
+
+	if len(rules) != 2 {
+		t.Errorf("expected 2 LIDS rules; got %d", len(rules))
+	}
+
+	if err = client.CreateLIDSRule("id", rules[0]); err == nil {
+		t.Errorf("expected creating duplicate LIDS rule to fail")
+	}
+
+	err = client.CreateLIDSRule("id", LIDSRule{Name: "sudo", FilePath: "/var/log/auth.log", Pattern: "sudo:"})
+
+	if err != nil {
+		t.Fatalf("LIDS rule creating failed: %v", err)
+	}
+
+	if len(reqBody.Policy.Rules) != 3 || reqBody.Policy.Rules[2].Name != "sudo" {
+		t.Errorf("expected request to contain new rule sudo; got %+v", reqBody.Policy.Rules)
+	}
+
+	updated := rules[1]
+	updated.Critical = true
+
+	err = client.UpdateLIDSRule("id", updated)
+
+	if err != nil {
+		t.Fatalf("LIDS rule updating failed: %v", err)
+	}
+
+	if len(reqBody.Policy.Rules) != 2 || !reqBody.Policy.Rules[1].Critical {
+		t.Errorf("expected request to contain updated rule %+v; got %+v", updated, reqBody.Policy.Rules)
+	}
+
+	if err = client.UpdateLIDSRule("id", LIDSRule{Name: "missing"}); err == nil {
+		t.Errorf("expected updating missing LIDS rule to fail")
+	}
+
+	err = client.DeleteLIDSRule("id", rules[0].Name)
+
+	if err != nil {
+		t.Fatalf("LIDS rule deletion failed: %v", err)
+	}
+
+	if len(reqBody.Policy.Rules) != 1 || reqBody.Policy.Rules[0].Name != rules[1].Name {
+		t.Errorf("expected request to contain only rule %s; got %+v", rules[1].Name, reqBody.Policy.Rules)
+	}
+}
+
+func TestClient_DeleteLIDSRule_Last(t *testing.T) {
+	var err error
+	reqBody := map[string]map[string]json.RawMessage{}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/lids_policies/id": jsonResponseTestHandler(t, "lids_policies_get_single_rule", http.StatusOK),
+			"PUT /v1/lids_policies/id": bodyRecorderTestHandler(t, jsonResponseTestHandler(t, "", http.StatusNoContent), &reqBody),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.DeleteLIDSRule("id", "Failed password")
+
+	if err != nil {
+		t.Fatalf("LIDS rule deletion failed: %v", err)
+	}
+
+	if rules, ok := reqBody["lids_policy"]["rules"]; !ok || string(rules) != "[]" {
+		t.Errorf("expected request to contain empty rules; got %s", rules)
+	}
+}
@@ -0,0 +1,292 @@
+// Package reaper retires or deletes CPHalo servers, which stay missing or
+// deactivated for too long, such as servers of terminated autoscaling instances.
+package reaper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// API lists servers in the states the policies reap and retires or deletes
+// the stale ones.
+type API interface {
+	ListServersWithOptions(opts cphalo.ListServersOptions) (cphalo.ListServersResponse, error)
+	RetireServer(ID string) error
+	DeleteServer(ID string) error
+}
+
+// Action is what the reaper does with a stale server.
+type Action string
+
+// Reaper actions.
+const (
+	ActionRetire Action = "retire"
+	ActionDelete Action = "delete"
+)
+
+// Policy reaps servers in the group and its descendants, which are in the
+// state for longer than After.
+//
+// The policy with the longest group path matching the server decides, a policy
+// without group applies to all servers.
+type Policy struct {
+	Group  string
+	State  string
+	After  time.Duration
+	Action Action
+}
+
+func (p Policy) validate() error {
+	switch p.State {
+	case cphalo.ServerStateMissing, cphalo.ServerStateDeactivated:
+	case cphalo.ServerStateRetired:
+		if p.Action == ActionRetire {
+			return fmt.Errorf("retired servers can only be deleted")
+		}
+	default:
+		return fmt.Errorf("invalid state %q", p.State)
+	}
+
+	switch p.Action {
+	case ActionRetire, ActionDelete:
+	default:
+		return fmt.Errorf("invalid action %q", p.Action)
+	}
+
+	if p.After <= 0 {
+		return fmt.Errorf("invalid threshold %s", p.After)
+	}
+
+	return nil
+}
+
+// covers reports whether the policy applies to servers of the group path.
+func (p Policy) covers(groupPath string) bool {
+	group := strings.Trim(p.Group, "/")
+
+	return group == "" || groupPath == group || strings.HasPrefix(groupPath, group+"/")
+}
+
+// Options configure the reaper.
+type Options struct {
+	// DryRun reports the servers to reap without reaping them.
+	DryRun bool
+	// MaxActions caps the number of servers reaped in a single run, the
+	// longest stale servers go first. There is no cap when zero.
+	MaxActions int
+	// Allowlist lists IDs, hostnames and labels of servers never reaped.
+	Allowlist []string
+}
+
+// Reap is a retirement or deletion of a stale server.
+type Reap struct {
+	ServerID        string    `json:"server_id"`
+	Hostname        string    `json:"hostname"`
+	GroupPath       string    `json:"group_path"`
+	State           string    `json:"state"`
+	LastStateChange time.Time `json:"last_state_change"`
+	Action          Action    `json:"action"`
+	DryRun          bool      `json:"dry_run,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// String returns a single line description of the reap.
+func (r Reap) String() string {
+	s := fmt.Sprintf("- %s server %s in %s, %s since %s", r.Action, r.Hostname, r.GroupPath, r.State, r.LastStateChange.Format(time.RFC3339))
+	if r.DryRun {
+		s += " [dry run]"
+	}
+	if r.Error != "" {
+		s += ": " + r.Error
+	}
+
+	return s
+}
+
+// Report summarizes a run of the reaper.
+type Report struct {
+	Servers     int    `json:"servers"`
+	Allowlisted int    `json:"allowlisted"`
+	Capped      int    `json:"capped"`
+	Failed      int    `json:"failed"`
+	Reaps       []Reap `json:"reaps"`
+}
+
+// Count returns the number of reaps with the action.
+func (r Report) Count(action Action) int {
+	n := 0
+	for _, reap := range r.Reaps {
+		if reap.Action == action {
+			n++
+		}
+	}
+
+	return n
+}
+
+// String returns the summary followed by all reaps.
+func (r Report) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d servers checked, %d to retire, %d to delete, %d allowlisted, %d over the cap, %d failed\n",
+		r.Servers, r.Count(ActionRetire), r.Count(ActionDelete), r.Allowlisted, r.Capped, r.Failed)
+
+	for _, reap := range r.Reaps {
+		b.WriteString(reap.String())
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// Reaper retires or deletes stale servers by policies.
+type Reaper struct {
+	api      API
+	policies []Policy
+	opts     Options
+	now      func() time.Time
+}
+
+// New creates a new Reaper with the policies, which are validated.
+func New(api API, policies []Policy, opts Options) (*Reaper, error) {
+	seen := map[string]bool{}
+
+	for i, p := range policies {
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("policy %d: %v", i+1, err)
+		}
+
+		key := strings.Trim(p.Group, "/") + "|" + p.State
+		if seen[key] {
+			return nil, fmt.Errorf("policy %d: duplicate policy for %s servers of group %q", i+1, p.State, p.Group)
+		}
+		seen[key] = true
+	}
+
+	if opts.MaxActions < 0 {
+		return nil, fmt.Errorf("invalid max actions %d", opts.MaxActions)
+	}
+
+	return &Reaper{api: api, policies: policies, opts: opts, now: time.Now}, nil
+}
+
+// Run retires or deletes the servers, which are stale by their policies.
+//
+// Servers are reaped oldest first, so a cap on actions spares the most recent
+// ones. A server CPHalo refuses to retire or delete is counted in Failed and
+// the younger ones are still reaped, Run then returns how many reaps failed.
+func (r *Reaper) Run() (Report, error) {
+	var report Report
+
+	servers, err := r.api.ListServersWithOptions(cphalo.ListServersOptions{States: r.states()})
+	if err != nil {
+		return report, fmt.Errorf("cannot list servers: %v", err)
+	}
+	report.Servers = len(servers.Servers)
+
+	allowed := map[string]bool{}
+	for _, a := range r.opts.Allowlist {
+		allowed[a] = true
+	}
+
+	now := r.now()
+
+	var stale []Reap
+	for _, s := range servers.Servers {
+		p, ok := r.policy(s)
+		if !ok || s.LastStateChange.IsZero() || now.Sub(s.LastStateChange) < p.After {
+			continue
+		}
+
+		if allowed[s.ID] || allowed[s.Hostname] || (s.ServerLabel != "" && allowed[s.ServerLabel]) {
+			report.Allowlisted++
+			continue
+		}
+
+		stale = append(stale, Reap{
+			ServerID:        s.ID,
+			Hostname:        s.Hostname,
+			GroupPath:       s.GroupPath,
+			State:           s.State,
+			LastStateChange: s.LastStateChange,
+			Action:          p.Action,
+			DryRun:          r.opts.DryRun,
+		})
+	}
+
+	sort.SliceStable(stale, func(i, j int) bool {
+		return stale[i].LastStateChange.Before(stale[j].LastStateChange)
+	})
+
+	if r.opts.MaxActions > 0 && len(stale) > r.opts.MaxActions {
+		report.Capped = len(stale) - r.opts.MaxActions
+		stale = stale[:r.opts.MaxActions]
+	}
+
+	for _, reap := range stale {
+		if !r.opts.DryRun {
+			if err := r.reap(reap); err != nil {
+				reap.Error = err.Error()
+				report.Failed++
+			}
+		}
+
+		report.Reaps = append(report.Reaps, reap)
+	}
+
+	if report.Failed > 0 {
+		return report, fmt.Errorf("cannot reap %d of %d servers", report.Failed, len(report.Reaps))
+	}
+
+	return report, nil
+}
+
+// states returns the states of the policies, CPHalo lists servers in other
+// states than active only when they are asked for.
+func (r *Reaper) states() []string {
+	seen := map[string]bool{}
+	var states []string
+
+	for _, p := range r.policies {
+		if !seen[p.State] {
+			seen[p.State] = true
+			states = append(states, p.State)
+		}
+	}
+	sort.Strings(states)
+
+	return states
+}
+
+// policy returns the most specific policy for the server and its state.
+func (r *Reaper) policy(s cphalo.Server) (Policy, bool) {
+	var found Policy
+	ok := false
+
+	for _, p := range r.policies {
+		if p.State != s.State || !p.covers(s.GroupPath) {
+			continue
+		}
+
+		if !ok || len(strings.Trim(p.Group, "/")) > len(strings.Trim(found.Group, "/")) {
+			found, ok = p, true
+		}
+	}
+
+	return found, ok
+}
+
+func (r *Reaper) reap(reap Reap) error {
+	switch reap.Action {
+	case ActionRetire:
+		return r.api.RetireServer(reap.ServerID)
+	case ActionDelete:
+		return r.api.DeleteServer(reap.ServerID)
+	}
+
+	return fmt.Errorf("invalid action %q", reap.Action)
+}
@@ -0,0 +1,211 @@
+package reaper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+var testNow = time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// fleet is an autoscaling fleet of servers, recording their retirements and
+// deletions.
+type fleet struct {
+	servers []cphalo.Server
+	fail    map[string]bool
+	calls   []string
+	states  []string
+}
+
+func newTestFleet() *fleet {
+	server := func(ID, group, state string, staleFor time.Duration) cphalo.Server {
+		return cphalo.Server{
+			ID:              ID,
+			Hostname:        "host-" + ID,
+			GroupPath:       group,
+			State:           state,
+			LastStateChange: testNow.Add(-staleFor),
+		}
+	}
+
+	return &fleet{
+		servers: []cphalo.Server{
+			server("s1", "example.com/asg", cphalo.ServerStateMissing, 2*time.Hour),
+			server("s2", "example.com/asg/web", cphalo.ServerStateMissing, 30*time.Minute),
+			server("s3", "example.com/db", cphalo.ServerStateMissing, 2*time.Hour),
+			server("s4", "example.com/db", cphalo.ServerStateMissing, 10*24*time.Hour),
+			server("s5", "example.com/db", cphalo.ServerStateDeactivated, 40*24*time.Hour),
+			server("s6", "example.com/asg", cphalo.ServerStateActive, 40*24*time.Hour),
+			server("s7", "example.com/asg", cphalo.ServerStateMissing, 3*time.Hour),
+		},
+		fail: map[string]bool{},
+	}
+}
+
+// ListServersWithOptions lists servers in the states, only active servers
+// when there are none, the same way CPHalo does.
+func (f *fleet) ListServersWithOptions(opts cphalo.ListServersOptions) (cphalo.ListServersResponse, error) {
+	f.states = opts.States
+
+	states := map[string]bool{}
+	for _, s := range opts.States {
+		states[s] = true
+	}
+	if len(states) == 0 {
+		states[cphalo.ServerStateActive] = true
+	}
+
+	var servers []cphalo.Server
+	for _, s := range f.servers {
+		if states[s.State] {
+			servers = append(servers, s)
+		}
+	}
+
+	return cphalo.ListServersResponse{Count: len(servers), Servers: servers}, nil
+}
+
+func (f *fleet) RetireServer(ID string) error {
+	f.calls = append(f.calls, "retire "+ID)
+	if f.fail[ID] {
+		return fmt.Errorf("server %s cannot be retired", ID)
+	}
+	return nil
+}
+
+func (f *fleet) DeleteServer(ID string) error {
+	f.calls = append(f.calls, "delete "+ID)
+	if f.fail[ID] {
+		return fmt.Errorf("server %s cannot be deleted", ID)
+	}
+	return nil
+}
+
+func testPolicies() []Policy {
+	return []Policy{
+		{State: cphalo.ServerStateMissing, After: 7 * 24 * time.Hour, Action: ActionRetire},
+		{State: cphalo.ServerStateDeactivated, After: 30 * 24 * time.Hour, Action: ActionDelete},
+		{Group: "example.com/asg", State: cphalo.ServerStateMissing, After: time.Hour, Action: ActionDelete},
+	}
+}
+
+func newTestReaper(t *testing.T, api API, opts Options) *Reaper {
+	r, err := New(api, testPolicies(), opts)
+	if err != nil {
+		t.Fatalf("cannot create reaper: %v", err)
+	}
+	r.now = func() time.Time { return testNow }
+
+	return r
+}
+
+func reapIDs(reaps []Reap) []string {
+	var ids []string
+	for _, r := range reaps {
+		ids = append(ids, r.ServerID)
+	}
+	return ids
+}
+
+func TestReaper_Run(t *testing.T) {
+	servers := newTestFleet()
+
+	report, err := newTestReaper(t, servers, Options{}).Run()
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if expected := []string{"delete s5", "retire s4", "delete s7", "delete s1"}; !reflect.DeepEqual(servers.calls, expected) {
+		t.Errorf("expected calls %v, oldest first; got %v", expected, servers.calls)
+	}
+
+	if expected := []string{cphalo.ServerStateDeactivated, cphalo.ServerStateMissing}; !reflect.DeepEqual(servers.states, expected) {
+		t.Errorf("expected servers listed in states %v; got %v", expected, servers.states)
+	}
+
+	if report.Servers != 6 || report.Count(ActionDelete) != 3 || report.Count(ActionRetire) != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	if summary := strings.SplitN(report.String(), "\n", 2)[0]; summary != "6 servers checked, 1 to retire, 3 to delete, 0 allowlisted, 0 over the cap, 0 failed" {
+		t.Errorf("unexpected summary %q", summary)
+	}
+}
+
+func TestReaper_DryRun(t *testing.T) {
+	servers := newTestFleet()
+
+	report, err := newTestReaper(t, servers, Options{DryRun: true, Allowlist: []string{"s5", "host-s7"}}).Run()
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if len(servers.calls) != 0 {
+		t.Errorf("expected no calls in dry run; got %v", servers.calls)
+	}
+
+	if ids := reapIDs(report.Reaps); !reflect.DeepEqual(ids, []string{"s4", "s1"}) || report.Allowlisted != 2 {
+		t.Errorf("expected allowlisted servers to be skipped; got %v, %+v", ids, report)
+	}
+
+	if !report.Reaps[0].DryRun || !strings.HasSuffix(report.Reaps[0].String(), "[dry run]") {
+		t.Errorf("expected dry run reaps; got %s", report.Reaps[0])
+	}
+}
+
+func TestReaper_MaxActions(t *testing.T) {
+	servers := newTestFleet()
+
+	report, err := newTestReaper(t, servers, Options{MaxActions: 2}).Run()
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if expected := []string{"delete s5", "retire s4"}; !reflect.DeepEqual(servers.calls, expected) || report.Capped != 2 {
+		t.Errorf("expected the oldest 2 servers to be reaped; got %v, %+v", servers.calls, report)
+	}
+}
+
+func TestReaper_FailedReap(t *testing.T) {
+	servers := newTestFleet()
+	servers.fail["s4"] = true
+
+	report, err := newTestReaper(t, servers, Options{}).Run()
+	if err == nil || err.Error() != "cannot reap 1 of 4 servers" {
+		t.Errorf("expected reap error; got %v", err)
+	}
+
+	if len(report.Reaps) != 4 || report.Failed != 1 || report.Reaps[1].Error != "server s4 cannot be retired" {
+		t.Errorf("expected failure to be reported and younger servers to be reaped; got %+v", report)
+	}
+}
+
+func TestNew_InvalidPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies []Policy
+		opts     Options
+	}{
+		{"active servers", []Policy{{State: cphalo.ServerStateActive, After: time.Hour, Action: ActionDelete}}, Options{}},
+		{"retiring retired servers", []Policy{{State: cphalo.ServerStateRetired, After: time.Hour, Action: ActionRetire}}, Options{}},
+		{"unknown action", []Policy{{State: cphalo.ServerStateMissing, After: time.Hour, Action: "archive"}}, Options{}},
+		{"no age", []Policy{{State: cphalo.ServerStateMissing, Action: ActionRetire}}, Options{}},
+		{"same group twice", []Policy{
+			{Group: "example.com/asg", State: cphalo.ServerStateMissing, After: time.Hour, Action: ActionRetire},
+			{Group: "/example.com/asg/", State: cphalo.ServerStateMissing, After: 2 * time.Hour, Action: ActionDelete},
+		}, Options{}},
+		{"negative max actions", nil, Options{MaxActions: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(newTestFleet(), tt.policies, tt.opts); err == nil {
+				t.Errorf("expected error for policies %+v", tt.policies)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package reconciler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Action is the type of a planned change.
+type Action string
+
+// Planned change actions.
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	ActionMove   Action = "move"
+)
+
+// Kind is the type of object a planned change applies to.
+type Kind string
+
+// Kinds of objects managed by the reconciler.
+const (
+	KindZone       Kind = "zone"
+	KindService    Kind = "service"
+	KindInterface  Kind = "interface"
+	KindPolicy     Kind = "policy"
+	KindRule       Kind = "rule"
+	KindAssignment Kind = "assignment"
+)
+
+var actionSymbols = map[Action]string{
+	ActionCreate: "+",
+	ActionUpdate: "~",
+	ActionDelete: "-",
+	ActionMove:   ">",
+}
+
+// Change is a single planned change of the live state.
+type Change struct {
+	Action  Action   `json:"action"`
+	Kind    Kind     `json:"kind"`
+	Name    string   `json:"name"`
+	Policy  string   `json:"policy,omitempty"`
+	Details []string `json:"details,omitempty"`
+
+	apply func(s *applyState) error
+}
+
+// String returns a single line description of the change.
+func (c Change) String() string {
+	name := c.Name
+	if c.Policy != "" {
+		name = c.Policy + ": " + name
+	}
+
+	return fmt.Sprintf("%s %s %s %s", actionSymbols[c.Action], c.Action, c.Kind, name)
+}
+
+// Plan is an ordered list of changes needed to reach the desired state.
+//
+// The changes are ordered so they can be applied one by one: zones, services
+// and interfaces first, then policies and their rules, then policy assignments
+// and finally deletions of no longer desired objects.
+type Plan struct {
+	Changes []Change `json:"changes"`
+
+	ids map[Kind]map[string]string
+}
+
+// Empty reports whether the live state already matches the desired state.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Count returns the number of changes with the given action.
+func (p *Plan) Count(action Action) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+
+	return n
+}
+
+// String renders the plan in a human-readable form.
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "No changes. Live state matches the desired state.\n"
+	}
+
+	var b strings.Builder
+
+	for _, c := range p.Changes {
+		b.WriteString(c.String())
+		b.WriteString("\n")
+		for _, d := range c.Details {
+			b.WriteString("    ")
+			b.WriteString(d)
+			b.WriteString("\n")
+		}
+	}
+
+	fmt.Fprintf(&b, "\nPlan: %d to create, %d to update, %d to move, %d to delete.\n",
+		p.Count(ActionCreate), p.Count(ActionUpdate), p.Count(ActionMove), p.Count(ActionDelete))
+
+	return b.String()
+}
+
+func detail(field string, from, to interface{}) string {
+	return fmt.Sprintf("%s: %v => %v", field, from, to)
+}
@@ -0,0 +1,719 @@
+package reconciler
+
+import (
+	"fmt"
+	"sort"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// kindGroup indexes server groups, which are referenced but never changed.
+const kindGroup Kind = "group"
+
+type planner struct {
+	api     API
+	desired DesiredState
+	live    *liveState
+	opts    Options
+
+	ids     map[Kind]map[string]string
+	dups    map[Kind]map[string]bool
+	desires map[Kind]map[string]bool
+	// refs holds names of objects desired rules and assignments refer to,
+	// which are never pruned.
+	refs    map[Kind]map[string]bool
+	changes []Change
+}
+
+func (p *planner) plan() (*Plan, error) {
+	p.index()
+
+	steps := []func() error{
+		p.planZones,
+		p.planServices,
+		p.planInterfaces,
+		p.planPolicies,
+		p.planAssignments,
+		p.planPrune,
+	}
+
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Plan{Changes: p.changes, ids: p.ids}, nil
+}
+
+func (p *planner) index() {
+	p.ids = map[Kind]map[string]string{}
+	p.dups = map[Kind]map[string]bool{}
+	p.desires = map[Kind]map[string]bool{}
+	p.refs = map[Kind]map[string]bool{}
+
+	add := func(kind Kind, id, name string) {
+		if p.ids[kind] == nil {
+			p.ids[kind] = map[string]string{}
+			p.dups[kind] = map[string]bool{}
+		}
+		if _, ok := p.ids[kind][name]; ok {
+			p.dups[kind][name] = true
+		}
+		p.ids[kind][name] = id
+	}
+
+	for _, kind := range []Kind{KindZone, KindService, KindInterface, KindPolicy, kindGroup} {
+		p.ids[kind] = map[string]string{}
+		p.dups[kind] = map[string]bool{}
+		p.desires[kind] = map[string]bool{}
+		p.refs[kind] = map[string]bool{}
+	}
+
+	for id, name := range p.live.zoneNames {
+		add(KindZone, id, name)
+	}
+	for id, name := range p.live.serviceNames {
+		add(KindService, id, name)
+	}
+	for id, name := range p.live.interfaceNames {
+		add(KindInterface, id, name)
+	}
+	for id, pol := range p.live.policies {
+		add(KindPolicy, id, pol.Name)
+	}
+	for id, name := range p.live.groupNames {
+		add(kindGroup, id, name)
+	}
+
+	for _, z := range p.desired.Zones {
+		p.desires[KindZone][z.Name] = true
+	}
+	for _, s := range p.desired.Services {
+		p.desires[KindService][s.Name] = true
+	}
+	for _, i := range p.desired.Interfaces {
+		p.desires[KindInterface][i.Name] = true
+	}
+	for _, pol := range p.desired.Policies {
+		p.desires[KindPolicy][pol.Name] = true
+
+		for _, r := range pol.Rules {
+			for _, e := range []*Endpoint{r.Source, r.Target} {
+				if e != nil && e.Kind == cphalo.FirewallEndpointZone {
+					p.refs[KindZone][e.Name] = true
+				}
+			}
+			if r.Service != "" {
+				p.refs[KindService][r.Service] = true
+			}
+			if r.Interface != "" {
+				p.refs[KindInterface][r.Interface] = true
+			}
+		}
+	}
+	for _, a := range p.desired.Assignments {
+		if a.Policy != "" {
+			p.refs[KindPolicy][a.Policy] = true
+		}
+	}
+}
+
+// keep reports whether the live object is desired or referred, so it must
+// not be pruned.
+func (p *planner) keep(kind Kind, name string) bool {
+	return p.desires[kind][name] || p.refs[kind][name]
+}
+
+// liveID returns ID of the live object with the given name.
+func (p *planner) liveID(kind Kind, name string) (string, bool, error) {
+	if p.dups[kind][name] {
+		return "", false, fmt.Errorf("ambiguous %s name %s, multiple live objects share it", kind, name)
+	}
+
+	id, ok := p.ids[kind][name]
+
+	return id, ok, nil
+}
+
+// checkRef verifies the referenced object is either desired or live.
+func (p *planner) checkRef(kind Kind, name string) error {
+	if p.desires[kind][name] {
+		return nil
+	}
+
+	_, ok, err := p.liveID(kind, name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("unknown %s %s", kind, name)
+	}
+
+	return nil
+}
+
+func (p *planner) add(c Change) {
+	p.changes = append(p.changes, c)
+}
+
+func (p *planner) planZones() error {
+	for _, z := range p.desired.Zones {
+		z := z
+		id, ok, err := p.liveID(KindZone, z.Name)
+		if err != nil {
+			return err
+		}
+
+		ips, err := cphalo.IPList(z.IPAddress).Parse()
+		if err != nil {
+			return fmt.Errorf("zone %s: %v", z.Name, err)
+		}
+
+		zone := cphalo.FirewallZone{
+			Name:        z.Name,
+			IPAddress:   ips,
+			Description: z.Description,
+		}
+
+		if !ok {
+			p.add(Change{
+				Action:  ActionCreate,
+				Kind:    KindZone,
+				Name:    z.Name,
+				Details: []string{"ip_address: " + zone.IPAddress.String()},
+				apply: func(s *applyState) error {
+					resp, err := s.api.CreateFirewallZone(zone)
+					if err != nil {
+						return err
+					}
+					s.ids[KindZone][z.Name] = resp.Zone.ID
+					return nil
+				},
+			})
+			continue
+		}
+
+		current := p.live.zones[id]
+		var details []string
+		if !current.IPAddress.Equal(zone.IPAddress) {
+			details = append(details, detail("ip_address", current.IPAddress.String(), zone.IPAddress.String()))
+		}
+		if current.Description != zone.Description {
+			details = append(details, detail("description", current.Description, zone.Description))
+		}
+		if len(details) == 0 {
+			continue
+		}
+
+		zone.ID = id
+		p.add(Change{
+			Action:  ActionUpdate,
+			Kind:    KindZone,
+			Name:    z.Name,
+			Details: details,
+			apply: func(s *applyState) error {
+				return s.api.UpdateFirewallZone(zone)
+			},
+		})
+	}
+
+	return nil
+}
+
+func (p *planner) planServices() error {
+	for _, svc := range p.desired.Services {
+		svc := svc
+		id, ok, err := p.liveID(KindService, svc.Name)
+		if err != nil {
+			return err
+		}
+
+		service := cphalo.FirewallService{
+			Name:     svc.Name,
+			Protocol: cphalo.FirewallProtocol(svc.Protocol).Normalize(),
+			Port:     svc.Port,
+		}
+
+		if !ok {
+			p.add(Change{
+				Action:  ActionCreate,
+				Kind:    KindService,
+				Name:    svc.Name,
+				Details: []string{fmt.Sprintf("protocol: %s, port: %s", service.Protocol, service.Port)},
+				apply: func(s *applyState) error {
+					resp, err := s.api.CreateFirewallService(service)
+					if err != nil {
+						return err
+					}
+					s.ids[KindService][svc.Name] = resp.Service.ID
+					return nil
+				},
+			})
+			continue
+		}
+
+		current := p.live.services[id]
+		var details []string
+		if current.Protocol.Normalize() != service.Protocol.Normalize() {
+			details = append(details, detail("protocol", string(current.Protocol), string(service.Protocol)))
+		}
+		if current.Port != service.Port {
+			details = append(details, detail("port", current.Port, service.Port))
+		}
+		if len(details) == 0 {
+			continue
+		}
+
+		service.ID = id
+		p.add(Change{
+			Action:  ActionUpdate,
+			Kind:    KindService,
+			Name:    svc.Name,
+			Details: details,
+			apply: func(s *applyState) error {
+				return s.api.UpdateFirewallService(service)
+			},
+		})
+	}
+
+	return nil
+}
+
+func (p *planner) planInterfaces() error {
+	for _, i := range p.desired.Interfaces {
+		i := i
+		_, ok, err := p.liveID(KindInterface, i.Name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+
+		p.add(Change{
+			Action: ActionCreate,
+			Kind:   KindInterface,
+			Name:   i.Name,
+			apply: func(s *applyState) error {
+				resp, err := s.api.CreateFirewallInterface(cphalo.FirewallInterface{Name: i.Name})
+				if err != nil {
+					return err
+				}
+				s.ids[KindInterface][i.Name] = resp.Interface.ID
+				return nil
+			},
+		})
+	}
+
+	return nil
+}
+
+func (p *planner) planPolicies() error {
+	for _, pol := range p.desired.Policies {
+		pol := pol
+		if err := p.checkRules(pol); err != nil {
+			return err
+		}
+
+		id, ok, err := p.liveID(KindPolicy, pol.Name)
+		if err != nil {
+			return err
+		}
+
+		platform := pol.Platform
+		if platform == "" {
+			platform = "linux"
+		}
+
+		policy := cphalo.FirewallPolicy{
+			Name:                  pol.Name,
+			Platform:              platform,
+			Description:           pol.Description,
+			Shared:                cphalo.StringableBool(pol.Shared),
+			IgnoreForwardingRules: pol.IgnoreForwardingRules,
+		}
+
+		if !ok {
+			p.add(Change{
+				Action:  ActionCreate,
+				Kind:    KindPolicy,
+				Name:    pol.Name,
+				Details: []string{fmt.Sprintf("platform: %s, rules: %d", platform, len(pol.Rules))},
+				apply: func(s *applyState) error {
+					resp, err := s.api.CreateFirewallPolicy(policy)
+					if err != nil {
+						return err
+					}
+					s.ids[KindPolicy][pol.Name] = resp.Policy.ID
+					return nil
+				},
+			})
+
+			for i, r := range pol.Rules {
+				p.add(p.createRule(pol.Name, r, i+1))
+			}
+			continue
+		}
+
+		current := p.live.policies[id]
+		var details []string
+		if current.Platform != policy.Platform {
+			details = append(details, detail("platform", current.Platform, policy.Platform))
+		}
+		if current.Description != policy.Description {
+			details = append(details, detail("description", current.Description, policy.Description))
+		}
+		if current.Shared != policy.Shared {
+			details = append(details, detail("shared", bool(current.Shared), pol.Shared))
+		}
+		if current.IgnoreForwardingRules != policy.IgnoreForwardingRules {
+			details = append(details, detail("ignore_forwarding_rules", current.IgnoreForwardingRules, policy.IgnoreForwardingRules))
+		}
+		if len(details) > 0 {
+			policy.ID = id
+			p.add(Change{
+				Action:  ActionUpdate,
+				Kind:    KindPolicy,
+				Name:    pol.Name,
+				Details: details,
+				apply: func(s *applyState) error {
+					return s.api.UpdateFirewallPolicy(policy)
+				},
+			})
+		}
+
+		p.planRules(pol, p.live.rules[id])
+	}
+
+	return nil
+}
+
+func (p *planner) checkRules(pol Policy) error {
+	for i, r := range pol.Rules {
+		var err error
+		for _, e := range []*Endpoint{r.Source, r.Target} {
+			if e == nil || err != nil {
+				continue
+			}
+			switch e.Kind {
+			case cphalo.FirewallEndpointZone:
+				err = p.checkRef(KindZone, e.Name)
+			case cphalo.FirewallEndpointGroup:
+				if e.Name != cphalo.FirewallAllActiveServers {
+					err = p.checkRef(kindGroup, e.Name)
+				}
+			}
+		}
+		if err == nil && r.Service != "" {
+			err = p.checkRef(KindService, r.Service)
+		}
+		if err == nil && r.Interface != "" {
+			err = p.checkRef(KindInterface, r.Interface)
+		}
+		if err != nil {
+			return fmt.Errorf("policy %s rule %d: %v", pol.Name, i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// planRules matches desired and live rules by content and plans deletions,
+// creations, updates and moves, which result in the desired rule order.
+func (p *planner) planRules(pol Policy, liveRules []cphalo.FirewallRule) {
+	desired := make([]ruleSpec, len(pol.Rules))
+	for i, r := range pol.Rules {
+		desired[i] = desiredSpec(r)
+	}
+
+	current := make([]ruleSpec, len(liveRules))
+	for i, r := range liveRules {
+		current[i] = p.liveSpec(r)
+	}
+
+	used := make([]bool, len(current))
+	match := make([]int, len(desired))
+	for i, d := range desired {
+		match[i] = -1
+		for j, c := range current {
+			if !used[j] && c.key() == d.key() {
+				used[j] = true
+				match[i] = j
+				break
+			}
+		}
+	}
+
+	// order simulates the live rule order: live indexes for kept rules,
+	// negative values for created ones
+	var order []int
+	for j := range current {
+		if !used[j] {
+			p.add(p.deleteRule(pol.Name, liveRules[j].ID, current[j]))
+			continue
+		}
+		order = append(order, j)
+	}
+
+	for i, d := range desired {
+		j := match[i]
+		if j < 0 {
+			order = insertAt(order, i, -1-i)
+			p.add(p.createRule(pol.Name, pol.Rules[i], i+1))
+			continue
+		}
+
+		at := indexOf(order, j)
+		moved := at != i
+		if moved {
+			order = insertAt(removeAt(order, at), i, j)
+		}
+
+		details := current[j].diff(d)
+		action := ActionUpdate
+		if len(details) == 0 {
+			action = ActionMove
+		}
+		if moved {
+			details = append(details, detail("position", at+1, i+1))
+		}
+
+		if len(details) > 0 {
+			p.add(p.updateRule(action, pol.Name, liveRules[j].ID, pol.Rules[i], i+1, details))
+		}
+	}
+}
+
+func (p *planner) createRule(policy string, r Rule, position int) Change {
+	return Change{
+		Action:  ActionCreate,
+		Kind:    KindRule,
+		Policy:  policy,
+		Name:    desiredSpec(r).String(),
+		Details: []string{fmt.Sprintf("position: %d", position)},
+		apply: func(s *applyState) error {
+			policyID, err := s.id(KindPolicy, policy)
+			if err != nil {
+				return err
+			}
+			rule, err := buildRule(s, r, position)
+			if err != nil {
+				return err
+			}
+			_, err = s.api.CreateFirewallRule(policyID, rule)
+			return err
+		},
+	}
+}
+
+func (p *planner) updateRule(action Action, policy, ruleID string, r Rule, position int, details []string) Change {
+	return Change{
+		Action:  action,
+		Kind:    KindRule,
+		Policy:  policy,
+		Name:    desiredSpec(r).String(),
+		Details: details,
+		apply: func(s *applyState) error {
+			policyID, err := s.id(KindPolicy, policy)
+			if err != nil {
+				return err
+			}
+			rule, err := buildRule(s, r, position)
+			if err != nil {
+				return err
+			}
+			rule.ID = ruleID
+			return s.api.UpdateFirewallRule(policyID, rule)
+		},
+	}
+}
+
+func (p *planner) deleteRule(policy, ruleID string, spec ruleSpec) Change {
+	return Change{
+		Action: ActionDelete,
+		Kind:   KindRule,
+		Policy: policy,
+		Name:   spec.String(),
+		apply: func(s *applyState) error {
+			policyID, err := s.id(KindPolicy, policy)
+			if err != nil {
+				return err
+			}
+			return s.api.DeleteFirewallRule(policyID, ruleID)
+		},
+	}
+}
+
+func (p *planner) planAssignments() error {
+	for _, a := range p.desired.Assignments {
+		a := a
+		groupID, ok, err := p.liveID(kindGroup, a.Group)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("assignment: unknown server group %s", a.Group)
+		}
+
+		if a.Policy != "" {
+			if err := p.checkRef(KindPolicy, a.Policy); err != nil {
+				return fmt.Errorf("assignment of group %s: %v", a.Group, err)
+			}
+		}
+
+		resp, err := p.liveGroupPolicy(groupID)
+		if err != nil {
+			return fmt.Errorf("cannot get firewall policy of group %s: %v", a.Group, err)
+		}
+
+		currentID := string(resp.Group.LinuxFirewallPolicyID)
+		desiredID := ""
+		if a.Policy != "" {
+			desiredID = p.ids[KindPolicy][a.Policy]
+		}
+		if currentID != "" && currentID == desiredID {
+			continue
+		}
+		if currentID == "" && a.Policy == "" {
+			continue
+		}
+
+		currentName := currentID
+		if pol, ok := p.live.policies[currentID]; ok {
+			currentName = pol.Name
+		}
+
+		p.add(Change{
+			Action:  ActionUpdate,
+			Kind:    KindAssignment,
+			Name:    a.Group,
+			Details: []string{detail("linux_firewall_policy", currentName, a.Policy)},
+			apply: func(s *applyState) error {
+				policyID := ""
+				if a.Policy != "" {
+					var err error
+					if policyID, err = s.id(KindPolicy, a.Policy); err != nil {
+						return err
+					}
+				}
+				return s.api.UpdateServerGroupFirewallPolicy(cphalo.ServerGroupFirewallPolicy{
+					GroupID:               groupID,
+					LinuxFirewallPolicyID: cphalo.NullableString(policyID),
+				})
+			},
+		})
+	}
+
+	return nil
+}
+
+func (p *planner) liveGroupPolicy(groupID string) (cphalo.GetServerGroupFirewallPolicyResponse, error) {
+	return p.api.GetServerGroupFirewallPolicy(groupID)
+}
+
+func (p *planner) planPrune() error {
+	if !p.opts.Prune {
+		return nil
+	}
+
+	var policies []cphalo.FirewallPolicy
+	for _, pol := range p.live.policies {
+		if !p.keep(KindPolicy, pol.Name) {
+			policies = append(policies, pol)
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	for _, pol := range policies {
+		id := pol.ID
+		p.add(Change{
+			Action: ActionDelete,
+			Kind:   KindPolicy,
+			Name:   pol.Name,
+			apply: func(s *applyState) error {
+				return s.api.DeleteFirewallPolicy(id)
+			},
+		})
+	}
+
+	var zones []cphalo.FirewallZone
+	for _, z := range p.live.zones {
+		if !z.System && !p.keep(KindZone, z.Name) {
+			zones = append(zones, z)
+		}
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].Name < zones[j].Name })
+	for _, z := range zones {
+		id := z.ID
+		p.add(Change{
+			Action: ActionDelete,
+			Kind:   KindZone,
+			Name:   z.Name,
+			apply: func(s *applyState) error {
+				return s.api.DeleteFirewallZone(id)
+			},
+		})
+	}
+
+	var services []cphalo.FirewallService
+	for _, svc := range p.live.services {
+		if !svc.System && !p.keep(KindService, svc.Name) {
+			services = append(services, svc)
+		}
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	for _, svc := range services {
+		id := svc.ID
+		p.add(Change{
+			Action: ActionDelete,
+			Kind:   KindService,
+			Name:   svc.Name,
+			apply: func(s *applyState) error {
+				return s.api.DeleteFirewallService(id)
+			},
+		})
+	}
+
+	var interfaces []cphalo.FirewallInterface
+	for _, i := range p.live.interfaces {
+		if !i.System && !p.keep(KindInterface, i.Name) {
+			interfaces = append(interfaces, i)
+		}
+	}
+	sort.Slice(interfaces, func(i, j int) bool { return interfaces[i].Name < interfaces[j].Name })
+	for _, i := range interfaces {
+		id := i.ID
+		p.add(Change{
+			Action: ActionDelete,
+			Kind:   KindInterface,
+			Name:   i.Name,
+			apply: func(s *applyState) error {
+				return s.api.DeleteFirewallInterface(id)
+			},
+		})
+	}
+
+	return nil
+}
+
+func insertAt(s []int, i, v int) []int {
+	s = append(s, 0)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+
+	return s
+}
+
+func removeAt(s []int, i int) []int {
+	return append(s[:i], s[i+1:]...)
+}
+
+func indexOf(s []int, v int) int {
+	for i, x := range s {
+		if x == v {
+			return i
+		}
+	}
+
+	return -1
+}
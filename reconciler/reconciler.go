@@ -0,0 +1,203 @@
+// Package reconciler brings CPHalo firewall configuration to a declared state.
+//
+// The desired state of zones, services, interfaces, policies with their rules
+// and policy assignments is compared with the live state read through the
+// client and turned into a Plan, which can be reviewed and then applied.
+package reconciler
+
+import (
+	"fmt"
+	"sort"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+var _ API = &cphalo.Client{}
+
+// API is the subset of the CPHalo client used by the reconciler.
+type API interface {
+	ListFirewallZones() (cphalo.ListFirewallZonesResponse, error)
+	CreateFirewallZone(zone cphalo.FirewallZone) (cphalo.CreateFirewallZoneResponse, error)
+	UpdateFirewallZone(zone cphalo.FirewallZone) error
+	DeleteFirewallZone(ID string) error
+
+	ListFirewallServices() (cphalo.ListFirewallServicesResponse, error)
+	CreateFirewallService(service cphalo.FirewallService) (cphalo.CreateFirewallServiceResponse, error)
+	UpdateFirewallService(service cphalo.FirewallService) error
+	DeleteFirewallService(ID string) error
+
+	ListFirewallInterfaces() (cphalo.ListFirewallInterfacesResponse, error)
+	CreateFirewallInterface(fwInterface cphalo.FirewallInterface) (cphalo.CreateFirewallInterfaceResponse, error)
+	DeleteFirewallInterface(ID string) error
+
+	ListFirewallPolicies() (cphalo.ListFirewallPoliciesResponse, error)
+	CreateFirewallPolicy(policy cphalo.FirewallPolicy) (cphalo.CreateFirewallPolicyResponse, error)
+	UpdateFirewallPolicy(policy cphalo.FirewallPolicy) error
+	DeleteFirewallPolicy(ID string) error
+
+	ListFirewallRules(policyID string) (cphalo.ListFirewallRulesResponse, error)
+	CreateFirewallRule(policyID string, rule cphalo.FirewallRule) (cphalo.CreateFirewallRuleResponse, error)
+	UpdateFirewallRule(policyID string, rule cphalo.FirewallRule) error
+	DeleteFirewallRule(policyID, ruleID string) error
+
+	ListServerGroups() (cphalo.ListServerGroupsResponse, error)
+	GetServerGroupFirewallPolicy(ID string) (cphalo.GetServerGroupFirewallPolicyResponse, error)
+	UpdateServerGroupFirewallPolicy(group cphalo.ServerGroupFirewallPolicy) error
+}
+
+// Options configure the reconciler.
+type Options struct {
+	// Prune enables deletion of live zones, services, interfaces and
+	// policies, which are not present in the desired state. System
+	// objects and objects desired rules or assignments refer to are never
+	// deleted.
+	Prune bool
+}
+
+// Reconciler plans and applies changes of the CPHalo firewall configuration.
+type Reconciler struct {
+	api  API
+	opts Options
+}
+
+// New creates a new Reconciler.
+func New(api API, opts Options) *Reconciler {
+	return &Reconciler{api: api, opts: opts}
+}
+
+type liveState struct {
+	zones      map[string]cphalo.FirewallZone
+	services   map[string]cphalo.FirewallService
+	interfaces map[string]cphalo.FirewallInterface
+	policies   map[string]cphalo.FirewallPolicy
+	rules      map[string][]cphalo.FirewallRule
+	groups     map[string]cphalo.ServerGroup
+
+	zoneNames      map[string]string
+	serviceNames   map[string]string
+	interfaceNames map[string]string
+	groupNames     map[string]string
+}
+
+type applyState struct {
+	api API
+	ids map[Kind]map[string]string
+}
+
+func (s *applyState) id(kind Kind, name string) (string, error) {
+	id, ok := s.ids[kind][name]
+	if !ok {
+		return "", fmt.Errorf("%s %s has no ID", kind, name)
+	}
+
+	return id, nil
+}
+
+// Plan compares the desired state with the live state and returns the changes needed.
+func (r *Reconciler) Plan(desired DesiredState) (*Plan, error) {
+	if err := desired.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid desired state: %v", err)
+	}
+
+	live, err := r.loadLive()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &planner{api: r.api, desired: desired, live: live, opts: r.opts}
+
+	return p.plan()
+}
+
+// Apply executes the plan changes one by one in their order.
+//
+// Apply stops at the first failed change, changes applied before are kept.
+func (r *Reconciler) Apply(plan *Plan) error {
+	s := &applyState{api: r.api, ids: map[Kind]map[string]string{}}
+	for kind, ids := range plan.ids {
+		s.ids[kind] = map[string]string{}
+		for name, id := range ids {
+			s.ids[kind][name] = id
+		}
+	}
+
+	for i, c := range plan.Changes {
+		if err := c.apply(s); err != nil {
+			return fmt.Errorf("change %d (%s) failed: %v", i+1, c, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) loadLive() (*liveState, error) {
+	live := &liveState{
+		zones:          map[string]cphalo.FirewallZone{},
+		services:       map[string]cphalo.FirewallService{},
+		interfaces:     map[string]cphalo.FirewallInterface{},
+		policies:       map[string]cphalo.FirewallPolicy{},
+		rules:          map[string][]cphalo.FirewallRule{},
+		groups:         map[string]cphalo.ServerGroup{},
+		zoneNames:      map[string]string{},
+		serviceNames:   map[string]string{},
+		interfaceNames: map[string]string{},
+		groupNames:     map[string]string{},
+	}
+
+	zones, err := r.api.ListFirewallZones()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list firewall zones: %v", err)
+	}
+	for _, z := range zones.Zones {
+		live.zones[z.ID] = z
+		live.zoneNames[z.ID] = z.Name
+	}
+
+	services, err := r.api.ListFirewallServices()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list firewall services: %v", err)
+	}
+	for _, s := range services.Services {
+		live.services[s.ID] = s
+		live.serviceNames[s.ID] = s.Name
+	}
+
+	interfaces, err := r.api.ListFirewallInterfaces()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list firewall interfaces: %v", err)
+	}
+	for _, i := range interfaces.Interfaces {
+		live.interfaces[i.ID] = i
+		live.interfaceNames[i.ID] = i.Name
+	}
+
+	groups, err := r.api.ListServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list server groups: %v", err)
+	}
+	for _, g := range groups.Groups {
+		live.groups[g.ID] = g
+		live.groupNames[g.ID] = g.Name
+	}
+
+	policies, err := r.api.ListFirewallPolicies()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list firewall policies: %v", err)
+	}
+	for _, p := range policies.Policies {
+		live.policies[p.ID] = p
+
+		rules, err := r.api.ListFirewallRules(p.ID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list rules of firewall policy %s: %v", p.Name, err)
+		}
+
+		sorted := append([]cphalo.FirewallRule{}, rules.Rules...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Position < sorted[j].Position
+		})
+		live.rules[p.ID] = sorted
+	}
+
+	return live, nil
+}
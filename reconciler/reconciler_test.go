@@ -0,0 +1,658 @@
+package reconciler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// fakeAPI is an in-memory CPHalo firewall, which keeps rules ordered by
+// position the same way CPHalo does.
+type fakeAPI struct {
+	seq         int
+	zones       []cphalo.FirewallZone
+	services    []cphalo.FirewallService
+	interfaces  []cphalo.FirewallInterface
+	policies    []cphalo.FirewallPolicy
+	rules       map[string][]cphalo.FirewallRule
+	groups      []cphalo.ServerGroup
+	assignments map[string]string
+	windows     map[string]string
+	calls       []string
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{
+		rules:       map[string][]cphalo.FirewallRule{},
+		assignments: map[string]string{},
+		windows:     map[string]string{},
+	}
+}
+
+func (f *fakeAPI) nextID(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("%s-%d", prefix, f.seq)
+}
+
+func (f *fakeAPI) call(format string, args ...interface{}) {
+	f.calls = append(f.calls, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeAPI) ListFirewallZones() (cphalo.ListFirewallZonesResponse, error) {
+	return cphalo.ListFirewallZonesResponse{Count: len(f.zones), Zones: f.zones}, nil
+}
+
+func (f *fakeAPI) CreateFirewallZone(zone cphalo.FirewallZone) (resp cphalo.CreateFirewallZoneResponse, err error) {
+	f.call("create zone %s", zone.Name)
+	zone.ID = f.nextID("zone")
+	f.zones = append(f.zones, zone)
+	resp.Zone = zone
+	return resp, nil
+}
+
+func (f *fakeAPI) UpdateFirewallZone(zone cphalo.FirewallZone) error {
+	f.call("update zone %s", zone.Name)
+	for i := range f.zones {
+		if f.zones[i].ID == zone.ID {
+			f.zones[i] = zone
+			return nil
+		}
+	}
+	return fmt.Errorf("zone %s not found", zone.ID)
+}
+
+func (f *fakeAPI) DeleteFirewallZone(ID string) error {
+	f.call("delete zone %s", ID)
+	for i := range f.zones {
+		if f.zones[i].ID == ID {
+			f.zones = append(f.zones[:i], f.zones[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("zone %s not found", ID)
+}
+
+func (f *fakeAPI) ListFirewallServices() (cphalo.ListFirewallServicesResponse, error) {
+	return cphalo.ListFirewallServicesResponse{Count: len(f.services), Services: f.services}, nil
+}
+
+func (f *fakeAPI) CreateFirewallService(service cphalo.FirewallService) (resp cphalo.CreateFirewallServiceResponse, err error) {
+	f.call("create service %s", service.Name)
+	service.ID = f.nextID("service")
+	f.services = append(f.services, service)
+	resp.Service = service
+	return resp, nil
+}
+
+func (f *fakeAPI) UpdateFirewallService(service cphalo.FirewallService) error {
+	f.call("update service %s", service.Name)
+	for i := range f.services {
+		if f.services[i].ID == service.ID {
+			f.services[i] = service
+			return nil
+		}
+	}
+	return fmt.Errorf("service %s not found", service.ID)
+}
+
+func (f *fakeAPI) DeleteFirewallService(ID string) error {
+	f.call("delete service %s", ID)
+	for i := range f.services {
+		if f.services[i].ID == ID {
+			f.services = append(f.services[:i], f.services[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("service %s not found", ID)
+}
+
+func (f *fakeAPI) ListFirewallInterfaces() (cphalo.ListFirewallInterfacesResponse, error) {
+	return cphalo.ListFirewallInterfacesResponse{Count: len(f.interfaces), Interfaces: f.interfaces}, nil
+}
+
+func (f *fakeAPI) CreateFirewallInterface(fwInterface cphalo.FirewallInterface) (resp cphalo.CreateFirewallInterfaceResponse, err error) {
+	f.call("create interface %s", fwInterface.Name)
+	fwInterface.ID = f.nextID("interface")
+	f.interfaces = append(f.interfaces, fwInterface)
+	resp.Interface = fwInterface
+	return resp, nil
+}
+
+func (f *fakeAPI) DeleteFirewallInterface(ID string) error {
+	f.call("delete interface %s", ID)
+	for i := range f.interfaces {
+		if f.interfaces[i].ID == ID {
+			f.interfaces = append(f.interfaces[:i], f.interfaces[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("interface %s not found", ID)
+}
+
+func (f *fakeAPI) ListFirewallPolicies() (cphalo.ListFirewallPoliciesResponse, error) {
+	return cphalo.ListFirewallPoliciesResponse{Count: len(f.policies), Policies: f.policies}, nil
+}
+
+func (f *fakeAPI) CreateFirewallPolicy(policy cphalo.FirewallPolicy) (resp cphalo.CreateFirewallPolicyResponse, err error) {
+	f.call("create policy %s", policy.Name)
+	policy.ID = f.nextID("policy")
+	f.policies = append(f.policies, policy)
+	resp.Policy = policy
+	return resp, nil
+}
+
+func (f *fakeAPI) UpdateFirewallPolicy(policy cphalo.FirewallPolicy) error {
+	f.call("update policy %s", policy.Name)
+	for i := range f.policies {
+		if f.policies[i].ID == policy.ID {
+			f.policies[i] = policy
+			return nil
+		}
+	}
+	return fmt.Errorf("policy %s not found", policy.ID)
+}
+
+func (f *fakeAPI) DeleteFirewallPolicy(ID string) error {
+	f.call("delete policy %s", ID)
+	for i := range f.policies {
+		if f.policies[i].ID == ID {
+			f.policies = append(f.policies[:i], f.policies[i+1:]...)
+			delete(f.rules, ID)
+			return nil
+		}
+	}
+	return fmt.Errorf("policy %s not found", ID)
+}
+
+func (f *fakeAPI) ListFirewallRules(policyID string) (cphalo.ListFirewallRulesResponse, error) {
+	rules := f.rules[policyID]
+	return cphalo.ListFirewallRulesResponse{Count: len(rules), Rules: rules}, nil
+}
+
+func (f *fakeAPI) renumber(policyID string) {
+	for i := range f.rules[policyID] {
+		f.rules[policyID][i].Position = i + 1
+	}
+}
+
+func (f *fakeAPI) insertRule(policyID string, rule cphalo.FirewallRule) {
+	rules := f.rules[policyID]
+	at := rule.Position - 1
+	if at < 0 || at > len(rules) {
+		at = len(rules)
+	}
+	rules = append(rules, cphalo.FirewallRule{})
+	copy(rules[at+1:], rules[at:])
+	rules[at] = rule
+	f.rules[policyID] = rules
+	f.renumber(policyID)
+}
+
+func (f *fakeAPI) CreateFirewallRule(policyID string, rule cphalo.FirewallRule) (resp cphalo.CreateFirewallRuleResponse, err error) {
+	f.call("create rule %s at %d", rule.Comment, rule.Position)
+	rule.ID = f.nextID("rule")
+	f.insertRule(policyID, rule)
+	resp.Rule = rule
+	return resp, nil
+}
+
+func (f *fakeAPI) UpdateFirewallRule(policyID string, rule cphalo.FirewallRule) error {
+	f.call("update rule %s at %d", rule.Comment, rule.Position)
+	if err := f.DeleteFirewallRule(policyID, rule.ID); err != nil {
+		return err
+	}
+	f.calls = f.calls[:len(f.calls)-1]
+	f.insertRule(policyID, rule)
+	return nil
+}
+
+func (f *fakeAPI) DeleteFirewallRule(policyID, ruleID string) error {
+	f.call("delete rule %s", ruleID)
+	rules := f.rules[policyID]
+	for i := range rules {
+		if rules[i].ID == ruleID {
+			f.rules[policyID] = append(rules[:i], rules[i+1:]...)
+			f.renumber(policyID)
+			return nil
+		}
+	}
+	return fmt.Errorf("rule %s not found", ruleID)
+}
+
+func (f *fakeAPI) ListServerGroups() (cphalo.ListServerGroupsResponse, error) {
+	return cphalo.ListServerGroupsResponse{Count: len(f.groups), Groups: f.groups}, nil
+}
+
+func (f *fakeAPI) GetServerGroupFirewallPolicy(ID string) (resp cphalo.GetServerGroupFirewallPolicyResponse, err error) {
+	resp.Group.GroupID = ID
+	resp.Group.LinuxFirewallPolicyID = cphalo.NullableString(f.assignments[ID])
+	resp.Group.WindowsFirewallPolicyID = cphalo.NullableString(f.windows[ID])
+	return resp, nil
+}
+
+func (f *fakeAPI) UpdateServerGroupFirewallPolicy(group cphalo.ServerGroupFirewallPolicy) error {
+	f.call("assign %s to %s", group.LinuxFirewallPolicyID, group.GroupID)
+	f.assignments[group.GroupID] = string(group.LinuxFirewallPolicyID)
+	if group.WindowsFirewallPolicyID != "" {
+		f.windows[group.GroupID] = string(group.WindowsFirewallPolicyID)
+	}
+	return nil
+}
+
+func ruleComments(rules []cphalo.FirewallRule) string {
+	var comments []string
+	for _, r := range rules {
+		comments = append(comments, r.Comment)
+	}
+	return strings.Join(comments, ",")
+}
+
+func testDesiredState() DesiredState {
+	return DesiredState{
+		Zones: []Zone{
+			{Name: "office", IPAddress: []string{"10.0.0.0/8", "192.168.1.1"}},
+		},
+		Services: []Service{
+			{Name: "ssh", Protocol: "tcp", Port: "22"},
+			{Name: "http", Protocol: "TCP", Port: "80"},
+		},
+		Interfaces: []Interface{
+			{Name: "eth1"},
+		},
+		Policies: []Policy{
+			{
+				Name: "web",
+				Rules: []Rule{
+					{Chain: "INPUT", Action: "ACCEPT", Source: &Endpoint{Kind: cphalo.FirewallEndpointZone, Name: "office"}, Service: "ssh", Comment: "a"},
+					{Chain: "INPUT", Action: "ACCEPT", Service: "http", Interface: "eth1", Comment: "b"},
+					{Chain: "INPUT", Action: "DROP", Source: &Endpoint{Kind: cphalo.FirewallEndpointGroup, Name: "db"}, Comment: "c"},
+				},
+			},
+		},
+		Assignments: []Assignment{
+			{Group: "web", Policy: "web"},
+		},
+	}
+}
+
+func TestReconciler_CreateFromScratch(t *testing.T) {
+	api := newFakeAPI()
+	api.groups = []cphalo.ServerGroup{{ID: "g-web", Name: "web"}, {ID: "g-db", Name: "db"}}
+	api.windows["g-web"] = "policy-windows"
+
+	r := New(api, Options{})
+
+	plan, err := r.Plan(testDesiredState())
+	if err != nil {
+		t.Fatalf("planning failed: %v", err)
+	}
+
+	expected := map[Action]int{ActionCreate: 8, ActionUpdate: 1, ActionMove: 0, ActionDelete: 0}
+	for action, count := range expected {
+		if plan.Count(action) != count {
+			t.Errorf("expected %d %s changes; got %d\n%s", count, action, plan.Count(action), plan)
+		}
+	}
+
+	if err := r.Apply(plan); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	expectedCalls := []string{
+		"create zone office",
+		"create service ssh",
+		"create service http",
+		"create interface eth1",
+		"create policy web",
+		"create rule a at 1",
+		"create rule b at 2",
+		"create rule c at 3",
+		"assign policy-5 to g-web",
+	}
+	if strings.Join(api.calls, "\n") != strings.Join(expectedCalls, "\n") {
+		t.Errorf("expected calls:\n%s\ngot:\n%s", strings.Join(expectedCalls, "\n"), strings.Join(api.calls, "\n"))
+	}
+
+	rules := api.rules["policy-5"]
+	if rules[0].FirewallSource.ID != "zone-1" {
+		t.Errorf("expected rule a source to reference zone-1; got %s", rules[0].FirewallSource.ID)
+	}
+	if rules[1].FirewallService.ID != "service-3" || rules[1].FirewallInterface.ID != "interface-4" {
+		t.Errorf("expected rule b to reference service-3 on interface-4; got %+v", rules[1])
+	}
+	if rules[2].FirewallSource.ID != "g-db" {
+		t.Errorf("expected rule c source to reference g-db; got %s", rules[2].FirewallSource.ID)
+	}
+	if api.windows["g-web"] != "policy-windows" {
+		t.Errorf("expected windows policy of g-web to be kept; got %q", api.windows["g-web"])
+	}
+
+	plan, err = r.Plan(testDesiredState())
+	if err != nil {
+		t.Fatalf("re-planning failed: %v", err)
+	}
+
+	if !plan.Empty() {
+		t.Errorf("expected empty plan after apply; got\n%s", plan)
+	}
+}
+
+func TestReconciler_RuleChanges(t *testing.T) {
+	api := newFakeAPI()
+	api.groups = []cphalo.ServerGroup{{ID: "g-web", Name: "web"}, {ID: "g-db", Name: "db"}}
+
+	r := New(api, Options{})
+	desired := testDesiredState()
+
+	plan, err := r.Plan(desired)
+	if err != nil {
+		t.Fatalf("planning failed: %v", err)
+	}
+	if err := r.Apply(plan); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	api.calls = nil
+
+	rules := desired.Policies[0].Rules
+	inactive := false
+	changed := rules[0]
+	changed.Active = &inactive
+	desired.Policies[0].Rules = []Rule{
+		rules[2],
+		{Chain: "OUTPUT", Action: "ACCEPT", Comment: "d"},
+		changed,
+	}
+	desired.Zones[0].IPAddress = []string{"10.0.0.0/8"}
+
+	plan, err = r.Plan(desired)
+	if err != nil {
+		t.Fatalf("planning failed: %v", err)
+	}
+
+	out := plan.String()
+	for _, s := range []string{
+		"~ update zone office",
+		"ip_address: 10.0.0.0/8,192.168.1.1 => 10.0.0.0/8",
+		"- delete rule web: INPUT ACCEPT from any to any service http on eth1",
+		"+ create rule web: OUTPUT ACCEPT from any to any service any",
+		"~ update rule web: INPUT ACCEPT from FirewallZone:office to any service ssh",
+		"active: true => false",
+		"Plan: 1 to create, 2 to update, 1 to move, 1 to delete.",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected plan to contain %q; got\n%s", s, out)
+		}
+	}
+
+	if err := r.Apply(plan); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	if got := ruleComments(api.rules["policy-5"]); got != "c,d,a" {
+		t.Errorf("expected rules in order c,d,a; got %s", got)
+	}
+
+	if api.rules["policy-5"][2].GetActive() {
+		t.Errorf("expected rule a to be inactive")
+	}
+
+	plan, err = r.Plan(desired)
+	if err != nil {
+		t.Fatalf("re-planning failed: %v", err)
+	}
+
+	if !plan.Empty() {
+		t.Errorf("expected empty plan after apply; got\n%s", plan)
+	}
+}
+
+// testServerTransport sends requests of the client to the test server.
+type testServerTransport struct {
+	url *url.URL
+}
+
+func (t testServerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme, req.URL.Host = t.url.Scheme, t.url.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestReconciler_RuleChangesOverHTTP(t *testing.T) {
+	responses := map[string]string{
+		"POST /oauth/access_token":                         `{"access_token": "token", "expires_in": 900}`,
+		"GET /v1/firewall_zones":                           `{"count": 0, "firewall_zones": []}`,
+		"GET /v1/firewall_services":                        `{"count": 0, "firewall_services": []}`,
+		"GET /v1/firewall_interfaces":                      `{"count": 0, "firewall_interfaces": []}`,
+		"GET /v1/groups":                                   `{"count": 0, "groups": []}`,
+		"GET /v1/firewall_policies":                        `{"count": 1, "firewall_policies": [{"id": "p-1", "name": "web", "platform": "linux"}]}`,
+		"GET /v1/firewall_policies/p-1/firewall_rules":     `{"count": 1, "firewall_rules": [{"id": "r-1", "chain": "INPUT", "action": "ACCEPT", "active": true, "log": true, "position": 1, "comment": "a"}]}`,
+		"PUT /v1/firewall_policies/p-1/firewall_rules/r-1": `{}`,
+	}
+
+	var body map[string]map[string]json.RawMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + r.URL.Path
+		resp, ok := responses[route]
+		if !ok {
+			t.Errorf("unexpected request %s", route)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("cannot decode request body: %v", err)
+			}
+		}
+
+		_, _ = w.Write([]byte(resp))
+	}))
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	client := cphalo.NewClient("", "", &http.Client{Transport: testServerTransport{url: tsURL}})
+	r := New(client, Options{})
+
+	inactive := false
+	desired := DesiredState{Policies: []Policy{{
+		Name:     "web",
+		Platform: "linux",
+		Rules:    []Rule{{Chain: "INPUT", Action: "ACCEPT", Active: &inactive, Comment: "a"}},
+	}}}
+
+	plan, err := r.Plan(desired)
+	if err != nil {
+		t.Fatalf("planning failed: %v", err)
+	}
+
+	if out := plan.String(); !strings.Contains(out, "active: true => false") || !strings.Contains(out, "log: true => false") {
+		t.Errorf("expected plan to turn off active and log; got\n%s", out)
+	}
+
+	if err := r.Apply(plan); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	if string(body["firewall_rule"]["active"]) != "false" || string(body["firewall_rule"]["log"]) != "false" {
+		t.Errorf("expected request to send active and log as false; got %v", body)
+	}
+}
+
+func TestReconciler_Prune(t *testing.T) {
+	api := newFakeAPI()
+	api.zones = []cphalo.FirewallZone{
+		{ID: "z1", Name: "office", IPAddress: cphalo.MustParseIPSet("10.0.0.0/8")},
+		{ID: "z2", Name: "old"},
+		{ID: "z3", Name: "any", System: true},
+	}
+	api.services = []cphalo.FirewallService{{ID: "s1", Name: "old-svc", Protocol: "TCP", Port: "1"}}
+	api.policies = []cphalo.FirewallPolicy{{ID: "p1", Name: "old-policy"}}
+
+	desired := DesiredState{Zones: []Zone{{Name: "office", IPAddress: []string{"10.0.0.0/8"}}}}
+
+	plan, err := New(api, Options{}).Plan(desired)
+	if err != nil {
+		t.Fatalf("planning failed: %v", err)
+	}
+
+	if !plan.Empty() {
+		t.Errorf("expected empty plan without prune; got\n%s", plan)
+	}
+
+	r := New(api, Options{Prune: true})
+
+	plan, err = r.Plan(desired)
+	if err != nil {
+		t.Fatalf("planning failed: %v", err)
+	}
+
+	if err := r.Apply(plan); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	expectedCalls := "delete policy p1\ndelete zone z2\ndelete service s1"
+	if got := strings.Join(api.calls, "\n"); got != expectedCalls {
+		t.Errorf("expected calls:\n%s\ngot:\n%s", expectedCalls, got)
+	}
+}
+
+func TestReconciler_PruneReferenced(t *testing.T) {
+	api := newFakeAPI()
+	api.zones = []cphalo.FirewallZone{{ID: "z1", Name: "office", IPAddress: cphalo.MustParseIPSet("10.0.0.0/8")}}
+	api.services = []cphalo.FirewallService{{ID: "s1", Name: "ssh", Protocol: "TCP", Port: "22"}}
+	api.interfaces = []cphalo.FirewallInterface{{ID: "i1", Name: "eth1"}}
+	api.policies = []cphalo.FirewallPolicy{{ID: "p1", Name: "base"}}
+	api.groups = []cphalo.ServerGroup{{ID: "g-web", Name: "web"}}
+
+	desired := DesiredState{
+		Policies: []Policy{
+			{
+				Name: "p",
+				Rules: []Rule{
+					{Chain: "INPUT", Action: "ACCEPT", Source: &Endpoint{Kind: cphalo.FirewallEndpointZone, Name: "office"}, Service: "ssh", Interface: "eth1", Comment: "a"},
+				},
+			},
+		},
+		Assignments: []Assignment{{Group: "web", Policy: "base"}},
+	}
+
+	r := New(api, Options{Prune: true})
+
+	plan, err := r.Plan(desired)
+	if err != nil {
+		t.Fatalf("planning failed: %v", err)
+	}
+
+	if n := plan.Count(ActionDelete); n != 0 {
+		t.Errorf("expected referenced objects to be kept; got\n%s", plan)
+	}
+
+	if err := r.Apply(plan); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	if len(api.zones) != 1 || len(api.services) != 1 || len(api.interfaces) != 1 || len(api.policies) != 2 {
+		t.Errorf("expected no live object to be deleted; got calls %v", api.calls)
+	}
+}
+
+func TestReconciler_UserEndpoint(t *testing.T) {
+	api := newFakeAPI()
+	api.policies = []cphalo.FirewallPolicy{{ID: "p1", Name: "p", Platform: "linux"}}
+	api.rules["p1"] = []cphalo.FirewallRule{
+		{
+			ID:             "r1",
+			Chain:          cphalo.FirewallChainInput,
+			Action:         cphalo.FirewallActionAccept,
+			Active:         cphalo.Bool(true),
+			Position:       1,
+			FirewallSource: &cphalo.FirewallRuleSourceTarget{ID: "u-1", Name: "alice", Kind: cphalo.FirewallEndpointUser},
+			Comment:        "a",
+		},
+		{
+			ID:             "r2",
+			Chain:          cphalo.FirewallChainInput,
+			Action:         cphalo.FirewallActionAccept,
+			Active:         cphalo.Bool(true),
+			Position:       2,
+			FirewallSource: &cphalo.FirewallRuleSourceTarget{ID: "ug-1", Name: "admins", Kind: cphalo.FirewallEndpointUserGroup},
+			Comment:        "b",
+		},
+	}
+
+	desired := DesiredState{
+		Policies: []Policy{
+			{
+				Name: "p",
+				Rules: []Rule{
+					{Chain: "INPUT", Action: "ACCEPT", Source: &Endpoint{Kind: cphalo.FirewallEndpointUser, Name: "u-1"}, Comment: "a"},
+					{Chain: "INPUT", Action: "ACCEPT", Source: &Endpoint{Kind: cphalo.FirewallEndpointUserGroup, Name: "ug-1"}, Comment: "b"},
+				},
+			},
+		},
+	}
+
+	plan, err := New(api, Options{}).Plan(desired)
+	if err != nil {
+		t.Fatalf("planning failed: %v", err)
+	}
+
+	if !plan.Empty() {
+		t.Errorf("expected rules with user endpoints to match by ID; got\n%s", plan)
+	}
+}
+
+func TestReconciler_PlanErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(api *fakeAPI, s *DesiredState)
+		err    string
+	}{
+		{
+			"unknown zone",
+			func(api *fakeAPI, s *DesiredState) { s.Zones = nil },
+			"unknown zone office",
+		},
+		{
+			"unknown group",
+			func(api *fakeAPI, s *DesiredState) { api.groups = api.groups[:1] },
+			"unknown group db",
+		},
+		{
+			"ambiguous service",
+			func(api *fakeAPI, s *DesiredState) {
+				api.services = []cphalo.FirewallService{{ID: "1", Name: "ssh"}, {ID: "2", Name: "ssh"}}
+			},
+			"ambiguous service name ssh",
+		},
+		{
+			"unknown assignment policy",
+			func(api *fakeAPI, s *DesiredState) { s.Assignments[0].Policy = "missing" },
+			"unknown policy missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI()
+			api.groups = []cphalo.ServerGroup{{ID: "g-web", Name: "web"}, {ID: "g-db", Name: "db"}}
+			desired := testDesiredState()
+			tt.modify(api, &desired)
+
+			_, err := New(api, Options{}).Plan(desired)
+
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error containing %q; got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package reconciler
+
+import (
+	"fmt"
+	"strings"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// ruleSpec is a firewall rule with all references expressed by names.
+type ruleSpec struct {
+	chain     cphalo.FirewallChain
+	action    cphalo.FirewallAction
+	states    string
+	source    string
+	target    string
+	service   string
+	iface     string
+	active    bool
+	log       bool
+	logPrefix string
+	comment   string
+}
+
+// key identifies the rule by its matching behaviour, so rules with equal
+// keys are the same rule regardless of their IDs and positions.
+func (r ruleSpec) key() string {
+	return strings.Join([]string{string(r.chain), string(r.action), r.states, r.source, r.target, r.service, r.iface}, "|")
+}
+
+// diff lists changes of attributes, which are not a part of the rule key.
+func (r ruleSpec) diff(to ruleSpec) []string {
+	var details []string
+
+	if r.active != to.active {
+		details = append(details, detail("active", r.active, to.active))
+	}
+	if r.log != to.log {
+		details = append(details, detail("log", r.log, to.log))
+	}
+	if r.logPrefix != to.logPrefix {
+		details = append(details, detail("log_prefix", r.logPrefix, to.logPrefix))
+	}
+	if r.comment != to.comment {
+		details = append(details, detail("comment", r.comment, to.comment))
+	}
+
+	return details
+}
+
+// String describes the rule in a compact human-readable form.
+func (r ruleSpec) String() string {
+	orAny := func(s string) string {
+		if s == "" {
+			return "any"
+		}
+		return s
+	}
+
+	s := fmt.Sprintf("%s %s from %s to %s service %s", r.chain, r.action, orAny(r.source), orAny(r.target), orAny(r.service))
+	if r.iface != "" {
+		s += " on " + r.iface
+	}
+	if r.states != "" {
+		s += " states " + r.states
+	}
+
+	return s
+}
+
+func desiredSpec(r Rule) ruleSpec {
+	endpoint := func(e *Endpoint) string {
+		if e == nil {
+			return ""
+		}
+		return string(e.Kind) + ":" + e.Name
+	}
+
+	return ruleSpec{
+		chain:     r.Chain,
+		action:    r.Action,
+		states:    normalizeStates(r.ConnectionStates),
+		source:    endpoint(r.Source),
+		target:    endpoint(r.Target),
+		service:   r.Service,
+		iface:     r.Interface,
+		active:    r.isActive(),
+		log:       r.Log,
+		logPrefix: r.LogPrefix,
+		comment:   r.Comment,
+	}
+}
+
+func (p *planner) liveSpec(r cphalo.FirewallRule) ruleSpec {
+	endpoint := func(e *cphalo.FirewallRuleSourceTarget) string {
+		if e == nil {
+			return ""
+		}
+
+		name := e.Name
+		switch e.Kind {
+		case cphalo.FirewallEndpointZone:
+			if n, ok := p.live.zoneNames[e.ID]; ok {
+				name = n
+			}
+		case cphalo.FirewallEndpointGroup:
+			if n, ok := p.live.groupNames[e.ID]; ok {
+				name = n
+			}
+		case cphalo.FirewallEndpointUser, cphalo.FirewallEndpointUserGroup:
+			// Desired users and user groups are referred by ID.
+			if e.ID != "" {
+				name = e.ID
+			}
+		}
+		if name == "" {
+			name = e.ID
+		}
+
+		return string(e.Kind) + ":" + name
+	}
+
+	spec := ruleSpec{
+		chain:     r.Chain,
+		action:    r.Action,
+		states:    normalizeStates(r.ConnectionStates),
+		source:    endpoint(r.FirewallSource),
+		target:    endpoint(r.FirewallTarget),
+		active:    r.GetActive(),
+		log:       r.GetLog(),
+		logPrefix: r.LogPrefix,
+		comment:   r.Comment,
+	}
+
+	if r.FirewallService != nil {
+		spec.service = r.FirewallService.Name
+		if n, ok := p.live.serviceNames[r.FirewallService.ID]; ok {
+			spec.service = n
+		}
+	}
+
+	if r.FirewallInterface != nil {
+		spec.iface = r.FirewallInterface.Name
+		if n, ok := p.live.interfaceNames[r.FirewallInterface.ID]; ok {
+			spec.iface = n
+		}
+	}
+
+	return spec
+}
+
+// buildRule converts the desired rule into CPHalo rule resolving names to IDs.
+func buildRule(s *applyState, r Rule, position int) (cphalo.FirewallRule, error) {
+	rule := cphalo.FirewallRule{
+		Chain:            r.Chain,
+		Action:           r.Action,
+		Active:           cphalo.Bool(r.isActive()),
+		ConnectionStates: normalizeStates(r.ConnectionStates),
+		Position:         position,
+		Log:              cphalo.Bool(r.Log),
+		LogPrefix:        r.LogPrefix,
+		Comment:          r.Comment,
+	}
+
+	var err error
+
+	if rule.FirewallSource, err = buildEndpoint(s, r.Source); err != nil {
+		return rule, fmt.Errorf("source: %v", err)
+	}
+
+	if rule.FirewallTarget, err = buildEndpoint(s, r.Target); err != nil {
+		return rule, fmt.Errorf("target: %v", err)
+	}
+
+	if r.Service != "" {
+		id, err := s.id(KindService, r.Service)
+		if err != nil {
+			return rule, err
+		}
+		rule.FirewallService = &cphalo.FirewallService{ID: id}
+	}
+
+	if r.Interface != "" {
+		id, err := s.id(KindInterface, r.Interface)
+		if err != nil {
+			return rule, err
+		}
+		rule.FirewallInterface = &cphalo.FirewallInterface{ID: id}
+	}
+
+	return rule, nil
+}
+
+func buildEndpoint(s *applyState, e *Endpoint) (*cphalo.FirewallRuleSourceTarget, error) {
+	if e == nil {
+		return nil, nil
+	}
+
+	st := &cphalo.FirewallRuleSourceTarget{Kind: e.Kind, ID: e.Name}
+
+	switch {
+	case e.Kind == cphalo.FirewallEndpointZone:
+		id, err := s.id(KindZone, e.Name)
+		if err != nil {
+			return nil, err
+		}
+		st.ID = id
+	case e.Kind == cphalo.FirewallEndpointGroup && e.Name != cphalo.FirewallAllActiveServers:
+		id, err := s.id(kindGroup, e.Name)
+		if err != nil {
+			return nil, err
+		}
+		st.ID = id
+	}
+
+	return st, nil
+}
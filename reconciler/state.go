@@ -0,0 +1,214 @@
+package reconciler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	cphalo "gitlab.com/kiwicom/cphalo-go"
+)
+
+// DesiredState is a declarative description of the CPHalo firewall configuration.
+//
+// All references between objects are done by name. The JSON field names are
+// also used by YAML libraries honouring json tags, so the same document can
+// be kept in YAML.
+type DesiredState struct {
+	Zones       []Zone       `json:"zones,omitempty"`
+	Services    []Service    `json:"services,omitempty"`
+	Interfaces  []Interface  `json:"interfaces,omitempty"`
+	Policies    []Policy     `json:"policies,omitempty"`
+	Assignments []Assignment `json:"assignments,omitempty"`
+}
+
+// Zone is a desired firewall zone.
+type Zone struct {
+	Name        string   `json:"name"`
+	IPAddress   []string `json:"ip_address"`
+	Description string   `json:"description,omitempty"`
+}
+
+// Service is a desired firewall service.
+type Service struct {
+	Name     string `json:"name"`
+	Protocol string `json:"protocol"`
+	Port     string `json:"port,omitempty"`
+}
+
+// Interface is a desired firewall interface.
+type Interface struct {
+	Name string `json:"name"`
+}
+
+// Policy is a desired firewall policy with its rules in the order of evaluation.
+type Policy struct {
+	Name                  string `json:"name"`
+	Platform              string `json:"platform,omitempty"`
+	Description           string `json:"description,omitempty"`
+	Shared                bool   `json:"shared,omitempty"`
+	IgnoreForwardingRules bool   `json:"ignore_forwarding_rules,omitempty"`
+	Rules                 []Rule `json:"rules,omitempty"`
+}
+
+// Rule is a desired firewall rule.
+//
+// Active defaults to true when omitted.
+type Rule struct {
+	Chain            cphalo.FirewallChain  `json:"chain"`
+	Action           cphalo.FirewallAction `json:"action"`
+	Active           *bool                 `json:"active,omitempty"`
+	ConnectionStates string                `json:"connection_states,omitempty"`
+	Source           *Endpoint             `json:"source,omitempty"`
+	Target           *Endpoint             `json:"target,omitempty"`
+	Service          string                `json:"service,omitempty"`
+	Interface        string                `json:"interface,omitempty"`
+	Log              bool                  `json:"log,omitempty"`
+	LogPrefix        string                `json:"log_prefix,omitempty"`
+	Comment          string                `json:"comment,omitempty"`
+}
+
+// Endpoint is a rule source or target referenced by kind and name.
+//
+// Zones and server groups are resolved by name, users and user groups are
+// sent as they are, so their Name has to be the CPHalo ID.
+type Endpoint struct {
+	Kind cphalo.FirewallEndpointKind `json:"kind"`
+	Name string                      `json:"name"`
+}
+
+// Assignment assigns a firewall policy to a server group.
+//
+// Empty Policy unassigns any Linux firewall policy from the group.
+type Assignment struct {
+	Group  string `json:"group"`
+	Policy string `json:"policy,omitempty"`
+}
+
+// ParseDesiredState decodes a JSON desired state document and validates it.
+func ParseDesiredState(r io.Reader) (DesiredState, error) {
+	var state DesiredState
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&state); err != nil {
+		return state, fmt.Errorf("cannot decode desired state: %v", err)
+	}
+
+	if err := state.Validate(); err != nil {
+		return state, err
+	}
+
+	return state, nil
+}
+
+// Validate checks the desired state for duplicates and invalid values.
+//
+// References to objects not defined in the document are checked against
+// the live state while planning.
+func (s DesiredState) Validate() error {
+	seen := map[string]bool{}
+	unique := func(kind, name string) error {
+		if name == "" {
+			return fmt.Errorf("%s without name", kind)
+		}
+		if seen[kind+"/"+name] {
+			return fmt.Errorf("duplicate %s %s", kind, name)
+		}
+		seen[kind+"/"+name] = true
+		return nil
+	}
+
+	for _, z := range s.Zones {
+		if err := unique("zone", z.Name); err != nil {
+			return err
+		}
+		ips, err := cphalo.IPList(z.IPAddress).Parse()
+		if err != nil {
+			return fmt.Errorf("zone %s: %v", z.Name, err)
+		}
+		if err := (cphalo.FirewallZone{Name: z.Name, IPAddress: ips}).Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, svc := range s.Services {
+		if err := unique("service", svc.Name); err != nil {
+			return err
+		}
+		if svc.Protocol == "" {
+			return fmt.Errorf("service %s has no protocol", svc.Name)
+		}
+		if err := (cphalo.FirewallService{Name: svc.Name, Protocol: cphalo.FirewallProtocol(svc.Protocol), Port: svc.Port}).Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, i := range s.Interfaces {
+		if err := unique("interface", i.Name); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range s.Policies {
+		if err := unique("policy", p.Name); err != nil {
+			return err
+		}
+		for i, r := range p.Rules {
+			if err := r.validate(); err != nil {
+				return fmt.Errorf("policy %s rule %d: %v", p.Name, i+1, err)
+			}
+		}
+	}
+
+	for _, a := range s.Assignments {
+		if err := unique("assignment", a.Group); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validate checks the rule the same way CPHalo rules are checked, with
+// endpoints referred by name.
+func (r Rule) validate() error {
+	endpoint := func(e *Endpoint) *cphalo.FirewallRuleSourceTarget {
+		if e == nil {
+			return nil
+		}
+		return &cphalo.FirewallRuleSourceTarget{Name: e.Name, Kind: e.Kind}
+	}
+
+	return cphalo.FirewallRule{
+		Chain:            r.Chain,
+		Action:           r.Action,
+		ConnectionStates: r.ConnectionStates,
+		FirewallSource:   endpoint(r.Source),
+		FirewallTarget:   endpoint(r.Target),
+		Log:              cphalo.Bool(r.Log),
+		LogPrefix:        r.LogPrefix,
+	}.Validate()
+}
+
+func (r Rule) isActive() bool {
+	return r.Active == nil || *r.Active
+}
+
+// normalizeStates returns connection states in a canonical form, ANY being empty.
+func normalizeStates(states string) string {
+	var parts []string
+
+	for _, s := range strings.Split(states, ",") {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s != "" && s != "ANY" {
+			parts = append(parts, s)
+		}
+	}
+
+	sort.Strings(parts)
+
+	return strings.Join(parts, ", ")
+}
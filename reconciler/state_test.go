@@ -0,0 +1,68 @@
+package reconciler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDesiredState(t *testing.T) {
+	doc := `{
+		"zones": [{"name": "office", "ip_address": ["10.0.0.0/8"]}],
+		"policies": [{
+			"name": "web",
+			"rules": [{"chain": "INPUT", "action": "ACCEPT", "connection_states": "established, new", "source": {"kind": "FirewallZone", "name": "office"}}]
+		}],
+		"assignments": [{"group": "web", "policy": "web"}]
+	}`
+
+	state, err := ParseDesiredState(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("parsing failed: %v", err)
+	}
+
+	if len(state.Policies) != 1 || len(state.Policies[0].Rules) != 1 {
+		t.Fatalf("expected 1 policy with 1 rule; got %+v", state.Policies)
+	}
+
+	rule := state.Policies[0].Rules[0]
+	if !rule.isActive() {
+		t.Errorf("expected rule to be active by default")
+	}
+	if rule.Source.Name != "office" {
+		t.Errorf("expected rule source office; got %s", rule.Source.Name)
+	}
+	if states := normalizeStates(rule.ConnectionStates); states != "ESTABLISHED, NEW" {
+		t.Errorf("expected normalized states ESTABLISHED, NEW; got %s", states)
+	}
+}
+
+func TestParseDesiredState_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		err  string
+	}{
+		{"unknown field", `{"zonez": []}`, "unknown field"},
+		{"duplicate zone", `{"zones": [{"name": "a", "ip_address": ["1.1.1.1"]}, {"name": "a", "ip_address": ["1.1.1.1"]}]}`, "duplicate zone a"},
+		{"zone without ips", `{"zones": [{"name": "a"}]}`, "zone a has no IP addresses"},
+		{"zone with invalid ip", `{"zones": [{"name": "a", "ip_address": ["10.0.0.0/33"]}]}`, `zone a: invalid network "10.0.0.0/33"`},
+		{"service without protocol", `{"services": [{"name": "ssh"}]}`, "service ssh has no protocol"},
+		{"service with invalid port", `{"services": [{"name": "ssh", "protocol": "tcp", "port": "22:2"}]}`, `service ssh: invalid port "22:2"`},
+		{"invalid chain", `{"policies": [{"name": "p", "rules": [{"chain": "FORWARD", "action": "ACCEPT"}]}]}`, `policy p rule 1: invalid chain "FORWARD"`},
+		{"invalid action", `{"policies": [{"name": "p", "rules": [{"chain": "INPUT", "action": "ALLOW"}]}]}`, `invalid action "ALLOW"`},
+		{"invalid endpoint", `{"policies": [{"name": "p", "rules": [{"chain": "INPUT", "action": "DROP", "target": {"kind": "Zone", "name": "x"}}]}]}`, `invalid target kind "Zone"`},
+		{"user target", `{"policies": [{"name": "p", "rules": [{"chain": "OUTPUT", "action": "DROP", "target": {"kind": "User", "name": "u-1"}}]}]}`, "target of kind User is allowed only as source of INPUT rules"},
+		{"log prefix without log", `{"policies": [{"name": "p", "rules": [{"chain": "INPUT", "action": "DROP", "log_prefix": "drop"}]}]}`, `log prefix "drop" without log`},
+		{"duplicate assignment", `{"assignments": [{"group": "g"}, {"group": "g", "policy": "p"}]}`, "duplicate assignment g"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDesiredState(strings.NewReader(tt.doc))
+
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error containing %q; got %v", tt.err, err)
+			}
+		})
+	}
+}
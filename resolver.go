@@ -0,0 +1,223 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of objects the resolver resolves names of.
+const (
+	ResolverKindZone           = "firewall zone"
+	ResolverKindService        = "firewall service"
+	ResolverKindInterface      = "firewall interface"
+	ResolverKindServerGroup    = "server group"
+	ResolverKindFirewallPolicy = "firewall policy"
+)
+
+// DefaultResolverTTL is how long the resolver keeps listed objects by default.
+const DefaultResolverTTL = 5 * time.Minute
+
+// resolverResources maps the first segment of API paths to the kinds cached
+// from them.
+var resolverResources = map[string]string{
+	"firewall_zones":      ResolverKindZone,
+	"firewall_services":   ResolverKindService,
+	"firewall_interfaces": ResolverKindInterface,
+	"groups":              ResolverKindServerGroup,
+	"firewall_policies":   ResolverKindFirewallPolicy,
+}
+
+// NameNotFoundError is returned when no object of the kind has the name.
+type NameNotFoundError struct {
+	Kind string
+	Name string
+}
+
+func (e NameNotFoundError) Error() string {
+	return fmt.Sprintf("%s %s not found", e.Kind, e.Name)
+}
+
+// AmbiguousNameError is returned when multiple objects of the kind have the name.
+type AmbiguousNameError struct {
+	Kind string
+	Name string
+	IDs  []string
+}
+
+func (e AmbiguousNameError) Error() string {
+	return fmt.Sprintf("%s name %s is ambiguous, used by %s", e.Kind, e.Name, strings.Join(e.IDs, ", "))
+}
+
+// Resolver resolves names of firewall zones, services, interfaces, firewall
+// policies and server groups to their IDs.
+//
+// Objects of a kind are listed on the first lookup of the kind and kept for
+// the TTL. Writes made through the client drop the cached objects of the
+// written kind. Writes made elsewhere are seen after the TTL or Invalidate.
+// The resolver is safe for concurrent use. Close it when no longer needed,
+// so the client stops notifying it of writes.
+type Resolver struct {
+	client     *Client
+	ttl        time.Duration
+	now        func() time.Time
+	unregister func()
+
+	mu    sync.Mutex
+	cache map[string]resolverIndex
+}
+
+// resolverIndex represent IDs of objects of a kind by their names.
+type resolverIndex struct {
+	ids     map[string][]string
+	expires time.Time
+}
+
+// NewResolver creates a resolver listing objects by the client. Listed objects
+// are kept for the TTL, or until invalidated when the TTL is zero.
+func NewResolver(client *Client, ttl time.Duration) *Resolver {
+	r := &Resolver{
+		client: client,
+		ttl:    ttl,
+		now:    time.Now,
+		cache:  map[string]resolverIndex{},
+	}
+
+	r.unregister = client.onWrite(func(req *http.Request) {
+		rsc := strings.TrimPrefix(req.URL.Path, client.baseURL.Path+"/"+DefaultAPIVersion+"/")
+		if kind, ok := resolverResources[strings.SplitN(rsc, "/", 2)[0]]; ok {
+			r.Invalidate(kind)
+		}
+	})
+
+	return r
+}
+
+// Close stops dropping cached objects on writes made through the client.
+// The resolver keeps resolving names, writes are then seen after the TTL or
+// Invalidate only.
+func (r *Resolver) Close() {
+	r.unregister()
+}
+
+// Invalidate drops cached objects of the kinds, or of all kinds when none is given.
+func (r *Resolver) Invalidate(kinds ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(kinds) == 0 {
+		r.cache = map[string]resolverIndex{}
+		return
+	}
+
+	for _, kind := range kinds {
+		delete(r.cache, kind)
+	}
+}
+
+// ZoneID returns ID of the firewall zone with the name.
+func (r *Resolver) ZoneID(name string) (string, error) {
+	return r.resolve(ResolverKindZone, name)
+}
+
+// ServiceID returns ID of the firewall service with the name.
+func (r *Resolver) ServiceID(name string) (string, error) {
+	return r.resolve(ResolverKindService, name)
+}
+
+// InterfaceID returns ID of the firewall interface with the name.
+func (r *Resolver) InterfaceID(name string) (string, error) {
+	return r.resolve(ResolverKindInterface, name)
+}
+
+// ServerGroupID returns ID of the server group with the name.
+func (r *Resolver) ServerGroupID(name string) (string, error) {
+	return r.resolve(ResolverKindServerGroup, name)
+}
+
+// FirewallPolicyID returns ID of the firewall policy with the name.
+func (r *Resolver) FirewallPolicyID(name string) (string, error) {
+	return r.resolve(ResolverKindFirewallPolicy, name)
+}
+
+func (r *Resolver) resolve(kind, name string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	idx, ok := r.cache[kind]
+	if !ok || (r.ttl > 0 && !r.now().Before(idx.expires)) {
+		ids, err := r.list(kind)
+		if err != nil {
+			return "", err
+		}
+
+		idx = resolverIndex{ids: ids, expires: r.now().Add(r.ttl)}
+		r.cache[kind] = idx
+	}
+
+	switch ids := idx.ids[name]; len(ids) {
+	case 0:
+		return "", NameNotFoundError{Kind: kind, Name: name}
+	case 1:
+		return ids[0], nil
+	default:
+		return "", AmbiguousNameError{Kind: kind, Name: name, IDs: ids}
+	}
+}
+
+// list lists objects of the kind and indexes their IDs by names.
+func (r *Resolver) list(kind string) (map[string][]string, error) {
+	ids := map[string][]string{}
+	add := func(name, ID string) {
+		ids[name] = append(ids[name], ID)
+	}
+
+	switch kind {
+	case ResolverKindZone:
+		resp, err := r.client.ListFirewallZones()
+		if err != nil {
+			return nil, fmt.Errorf("cannot list firewall zones: %v", err)
+		}
+		for _, z := range resp.Zones {
+			add(z.Name, z.ID)
+		}
+	case ResolverKindService:
+		resp, err := r.client.ListFirewallServices()
+		if err != nil {
+			return nil, fmt.Errorf("cannot list firewall services: %v", err)
+		}
+		for _, s := range resp.Services {
+			add(s.Name, s.ID)
+		}
+	case ResolverKindInterface:
+		resp, err := r.client.ListFirewallInterfaces()
+		if err != nil {
+			return nil, fmt.Errorf("cannot list firewall interfaces: %v", err)
+		}
+		for _, i := range resp.Interfaces {
+			add(i.Name, i.ID)
+		}
+	case ResolverKindServerGroup:
+		resp, err := r.client.ListServerGroups()
+		if err != nil {
+			return nil, fmt.Errorf("cannot list server groups: %v", err)
+		}
+		for _, g := range resp.Groups {
+			add(g.Name, g.ID)
+		}
+	case ResolverKindFirewallPolicy:
+		resp, err := r.client.ListFirewallPolicies()
+		if err != nil {
+			return nil, fmt.Errorf("cannot list firewall policies: %v", err)
+		}
+		for _, p := range resp.Policies {
+			add(p.Name, p.ID)
+		}
+	default:
+		return nil, fmt.Errorf("unknown kind %s", kind)
+	}
+
+	return ids, nil
+}
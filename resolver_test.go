@@ -0,0 +1,211 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// resolverTestServer serves listings from fixtures and counts them by URI.
+func resolverTestServer(t *testing.T, lists map[string]int) *httptest.Server {
+	count := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lists[r.RequestURI]++
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	return httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/firewall_zones":                                     count(jsonResponseTestHandler(t, "firewall_zones_list", http.StatusOK)),
+			"GET /v1/firewall_services":                                  count(jsonResponseTestHandler(t, "firewall_services_list", http.StatusOK)),
+			"GET /v1/firewall_interfaces":                                count(jsonResponseTestHandler(t, "firewall_interfaces_list", http.StatusOK)),
+			"GET /v1/groups":                                             count(jsonResponseTestHandler(t, "server_groups_list", http.StatusOK)),
+			"GET /v1/firewall_policies":                                  count(jsonResponseTestHandler(t, "firewall_policies_list", http.StatusOK)),
+			"DELETE /v1/firewall_zones/ea81ec609956012ee2db40989asd0980": jsonResponseTestHandler(t, "", http.StatusNoContent),
+		}),
+	)
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	var err error
+
+	lists := map[string]int{}
+	ts := resolverTestServer(t, lists)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	r := NewResolver(client, DefaultResolverTTL)
+
+	tests := []struct {
+		resolve func(string) (string, error)
+		name    string
+		ID      string
+	}{
+		{r.ZoneID, "any", "ea81ec609956012ee2db40989asd0980"},
+		{r.ServiceID, "ssh", "ea3fe1309956012ee2989ea98as989as"},
+		{r.ServiceID, "https", "ea4b81f09956012ee2989ea98as989as"},
+		{r.InterfaceID, "eth0", "eab30a009956012ee2db4087123ad87s"},
+		{r.ServerGroupID, "graphite", "9981f162c2d611e680b17f1fb185c564"},
+		{r.FirewallPolicyID, "subpolicy", "1efa203cee5ckka8906e5fe9c3ed9996"},
+	}
+
+	for _, tt := range tests {
+		ID, err := tt.resolve(tt.name)
+
+		if err != nil {
+			t.Errorf("cannot resolve %s: %v", tt.name, err)
+		}
+
+		if ID != tt.ID {
+			t.Errorf("expected %s to resolve to %s; got %s", tt.name, tt.ID, ID)
+		}
+	}
+
+	_, err = r.ServiceID("telnet")
+
+	if _, ok := err.(NameNotFoundError); !ok {
+		t.Errorf("expected not found error; got %v", err)
+	}
+
+	expected := map[string]int{
+		"/v1/firewall_zones":      1,
+		"/v1/firewall_services":   1,
+		"/v1/firewall_interfaces": 1,
+		"/v1/groups":              1,
+		"/v1/firewall_policies":   1,
+	}
+
+	if !reflect.DeepEqual(lists, expected) {
+		t.Errorf("expected each kind to be listed once; got %v", lists)
+	}
+}
+
+func TestResolver_Invalidation(t *testing.T) {
+	var err error
+
+	lists := map[string]int{}
+	ts := resolverTestServer(t, lists)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewResolver(client, time.Minute)
+	r.now = func() time.Time { return now }
+
+	resolveAll := func() {
+		for _, resolve := range []func(string) (string, error){r.ZoneID, r.ServiceID} {
+			if _, err := resolve("any"); err != nil {
+				if _, ok := err.(NameNotFoundError); !ok {
+					t.Fatalf("cannot resolve: %v", err)
+				}
+			}
+		}
+	}
+
+	resolveAll()
+	now = now.Add(59 * time.Second)
+	resolveAll()
+
+	if lists["/v1/firewall_zones"] != 1 || lists["/v1/firewall_services"] != 1 {
+		t.Errorf("expected objects to be cached within TTL; got %v", lists)
+	}
+
+	if err := client.DeleteFirewallZone("ea81ec609956012ee2db40989asd0980"); err != nil {
+		t.Fatalf("cannot delete zone: %v", err)
+	}
+	resolveAll()
+
+	if lists["/v1/firewall_zones"] != 2 || lists["/v1/firewall_services"] != 1 {
+		t.Errorf("expected only zones to be listed again after zone write; got %v", lists)
+	}
+
+	now = now.Add(time.Minute)
+	resolveAll()
+
+	if lists["/v1/firewall_zones"] != 3 || lists["/v1/firewall_services"] != 2 {
+		t.Errorf("expected objects to be listed again after TTL; got %v", lists)
+	}
+
+	r.Invalidate()
+	resolveAll()
+
+	if lists["/v1/firewall_zones"] != 4 || lists["/v1/firewall_services"] != 3 {
+		t.Errorf("expected objects to be listed again after invalidation; got %v", lists)
+	}
+
+	r.Close()
+
+	if len(client.writeHooks) != 0 {
+		t.Errorf("expected closed resolver to unregister its hook; got %d hooks", len(client.writeHooks))
+	}
+
+	if err := client.DeleteFirewallZone("ea81ec609956012ee2db40989asd0980"); err != nil {
+		t.Fatalf("cannot delete zone: %v", err)
+	}
+	resolveAll()
+
+	if lists["/v1/firewall_zones"] != 4 {
+		t.Errorf("expected closed resolver to keep objects after zone write; got %v", lists)
+	}
+}
+
+func TestResolver_Ambiguous(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/firewall_services": http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode(ListFirewallServicesResponse{
+					Count: 3,
+					Services: []FirewallService{
+						{ID: "s1", Name: "web"},
+						{ID: "s2", Name: "web"},
+						{ID: "s3", Name: "ssh"},
+					},
+				})
+			}),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	r := NewResolver(client, 0)
+
+	_, err = r.ServiceID("web")
+
+	ambiguous, ok := err.(AmbiguousNameError)
+	if !ok {
+		t.Fatalf("expected ambiguous name error; got %v", err)
+	}
+
+	if !reflect.DeepEqual(ambiguous.IDs, []string{"s1", "s2"}) {
+		t.Errorf("expected ambiguous IDs s1 and s2; got %v", ambiguous.IDs)
+	}
+
+	if ID, err := r.ServiceID("ssh"); err != nil || ID != "s3" {
+		t.Errorf("expected ssh to resolve to s3; got %s, %v", ID, err)
+	}
+}
@@ -0,0 +1,79 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// UpdateServerGroupAlertProfilesRequest represents a CPHalo server group alert profiles update request.
+//
+// Alert profile IDs are sent even when there are none, so the last alert
+// profile can be detached.
+type UpdateServerGroupAlertProfilesRequest struct {
+	Group struct {
+		AlertProfileIDs []string `json:"alert_profile_ids"`
+	} `json:"group"`
+}
+
+// AttachServerGroupAlertProfile adds the alert profile to AlertProfileIDs of
+// the server group.
+//
+// Attaching an already attached alert profile is a no-op.
+func (c *Client) AttachServerGroupAlertProfile(groupID, profileID string) error {
+	resp, err := c.GetServerGroup(groupID)
+	if err != nil {
+		return fmt.Errorf("cannot get server group %s: %v", groupID, err)
+	}
+
+	for _, id := range resp.Group.AlertProfileIDs {
+		if id == profileID {
+			return nil
+		}
+	}
+
+	return c.updateServerGroupAlertProfileIDs(groupID, append(resp.Group.AlertProfileIDs, profileID))
+}
+
+// DetachServerGroupAlertProfile removes the alert profile from AlertProfileIDs
+// of the server group.
+//
+// Detaching a not attached alert profile is a no-op.
+func (c *Client) DetachServerGroupAlertProfile(groupID, profileID string) error {
+	resp, err := c.GetServerGroup(groupID)
+	if err != nil {
+		return fmt.Errorf("cannot get server group %s: %v", groupID, err)
+	}
+
+	ids := []string{}
+	for _, id := range resp.Group.AlertProfileIDs {
+		if id != profileID {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == len(resp.Group.AlertProfileIDs) {
+		return nil
+	}
+
+	return c.updateServerGroupAlertProfileIDs(groupID, ids)
+}
+
+// updateServerGroupAlertProfileIDs replaces AlertProfileIDs of the server group.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#update-server-group-attributes
+func (c *Client) updateServerGroupAlertProfileIDs(groupID string, ids []string) error {
+	reqData := UpdateServerGroupAlertProfilesRequest{}
+	reqData.Group.AlertProfileIDs = ids
+
+	req, err := c.newRequest(http.MethodPut, "groups/"+groupID, nil, reqData)
+	if err != nil {
+		return fmt.Errorf("cannot create new update request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute update request: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestClient_GetServerGroup_AlertProfileIDs(t *testing.T) {
+	var (
+		err         error
+		expectedIDs = []string{"0226a27af95c11e5a92a471a4310f7c2", "c25d8d42012211e6a3e669ed2e08b040"}
+	)
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "server_group_alert_profiles_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/groups/group-id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.GetServerGroup("group-id")
+
+	if err != nil {
+		t.Fatalf("server groups get failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(resp.Group.AlertProfileIDs, expectedIDs) {
+		t.Errorf("expected group to have alert profile IDs %v; got %v", expectedIDs, resp.Group.AlertProfileIDs)
+	}
+}
+
+func TestClient_AttachServerGroupAlertProfile(t *testing.T) {
+	var err error
+	reqBody := UpdateServerGroupAlertProfilesRequest{}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/groups/group-id": jsonResponseTestHandler(t, "server_group_alert_profiles_get", http.StatusOK),
+			"PUT /v1/groups/group-id": bodyRecorderTestHandler(t, jsonResponseTestHandler(t, "", http.StatusNoContent), &reqBody),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.AttachServerGroupAlertProfile("group-id", "c25d8d42012211e6a3e669ed2e08b040")
+
+	if err != nil {
+		t.Fatalf("attaching attached alert profile failed: %v", err)
+	}
+
+	if reqBody.Group.AlertProfileIDs != nil {
+		t.Errorf("expected no update when attaching attached alert profile; got %v", reqBody.Group.AlertProfileIDs)
+	}
+
+	err = client.AttachServerGroupAlertProfile("group-id", "new-profile")
+
+	if err != nil {
+		t.Fatalf("attaching alert profile failed: %v", err)
+	}
+
+	expected := []string{"0226a27af95c11e5a92a471a4310f7c2", "c25d8d42012211e6a3e669ed2e08b040", "new-profile"}
+	if !reflect.DeepEqual(reqBody.Group.AlertProfileIDs, expected) {
+		t.Errorf("expected request to have alert profile IDs %v; got %v", expected, reqBody.Group.AlertProfileIDs)
+	}
+}
+
+func TestClient_DetachServerGroupAlertProfile(t *testing.T) {
+	var err error
+	reqBody := UpdateServerGroupAlertProfilesRequest{}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/groups/group-id": jsonResponseTestHandler(t, "server_group_alert_profiles_get", http.StatusOK),
+			"PUT /v1/groups/group-id": bodyRecorderTestHandler(t, jsonResponseTestHandler(t, "", http.StatusNoContent), &reqBody),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.DetachServerGroupAlertProfile("group-id", "missing-profile")
+
+	if err != nil {
+		t.Fatalf("detaching not attached alert profile failed: %v", err)
+	}
+
+	if reqBody.Group.AlertProfileIDs != nil {
+		t.Errorf("expected no update when detaching not attached alert profile; got %v", reqBody.Group.AlertProfileIDs)
+	}
+
+	err = client.DetachServerGroupAlertProfile("group-id", "0226a27af95c11e5a92a471a4310f7c2")
+
+	if err != nil {
+		t.Fatalf("detaching alert profile failed: %v", err)
+	}
+
+	expected := []string{"c25d8d42012211e6a3e669ed2e08b040"}
+	if !reflect.DeepEqual(reqBody.Group.AlertProfileIDs, expected) {
+		t.Errorf("expected request to have alert profile IDs %v; got %v", expected, reqBody.Group.AlertProfileIDs)
+	}
+}
+
+func TestClient_DetachServerGroupAlertProfile_Last(t *testing.T) {
+	var err error
+	reqBody := map[string]map[string]json.RawMessage{}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"GET /v1/groups/group-id": jsonResponseTestHandler(t, "server_group_alert_profiles_get_single", http.StatusOK),
+			"PUT /v1/groups/group-id": bodyRecorderTestHandler(t, jsonResponseTestHandler(t, "", http.StatusNoContent), &reqBody),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.DetachServerGroupAlertProfile("group-id", "0226a27af95c11e5a92a471a4310f7c2")
+
+	if err != nil {
+		t.Fatalf("detaching alert profile failed: %v", err)
+	}
+
+	if ids, ok := reqBody["group"]["alert_profile_ids"]; !ok || string(ids) != "[]" {
+		t.Errorf("expected request to contain empty alert profile IDs; got %s", ids)
+	}
+}
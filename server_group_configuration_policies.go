@@ -0,0 +1,68 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ServerGroupConfigurationPolicy represents configuration policies for a CPHalo server group.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#object-representation-1
+type ServerGroupConfigurationPolicy struct {
+	GroupID          string   `json:"-"`
+	PolicyIDs        []string `json:"policy_ids"`
+	WindowsPolicyIDs []string `json:"windows_policy_ids"`
+}
+
+// GetServerGroupConfigurationPolicyResponse represents a CPHalo server group configuration policies get response.
+type GetServerGroupConfigurationPolicyResponse struct {
+	Group ServerGroupConfigurationPolicy `json:"group"`
+}
+
+// UpdateServerGroupConfigurationPolicyRequest represents a CPHalo server group configuration policies update request.
+type UpdateServerGroupConfigurationPolicyRequest = GetServerGroupConfigurationPolicyResponse
+
+// GetServerGroupConfigurationPolicy return information describing configuration policies for a single group.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#get-a-single-server-group
+func (c *Client) GetServerGroupConfigurationPolicy(ID string) (response GetServerGroupConfigurationPolicyResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "groups/"+ID, nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, err
+	}
+
+	response.Group.GroupID = ID
+
+	return response, nil
+}
+
+// UpdateServerGroupConfigurationPolicy updates configuration policies for a server group.
+//
+// Both Linux and Windows policy lists are replaced, nil lists unassign all policies.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#assign-configuration-policies-to-the-server-group
+func (c *Client) UpdateServerGroupConfigurationPolicy(group ServerGroupConfigurationPolicy) error {
+	if group.PolicyIDs == nil {
+		group.PolicyIDs = []string{}
+	}
+	if group.WindowsPolicyIDs == nil {
+		group.WindowsPolicyIDs = []string{}
+	}
+
+	req, err := c.newRequest(http.MethodPut, "groups/"+group.GroupID, nil, UpdateServerGroupConfigurationPolicyRequest{Group: group})
+	if err != nil {
+		return fmt.Errorf("cannot create new update request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute update request: %v", err)
+	}
+
+	return nil
+}
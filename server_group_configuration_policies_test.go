@@ -0,0 +1,99 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestClient_GetServerGroupConfigurationPolicy(t *testing.T) {
+	var (
+		err                error
+		expectedIDs        = []string{"7e1210aa8e5311e5a17769a1b748c836", "38c89b381f6611e59cb40db4c6c0bae5"}
+		expectedWindowsIDs = []string{"d7c20c76609111e5863e116ca1d550c2", "9683b6a0af01013295f406ba9a9c633c"}
+		groupID            = "random-group-id"
+	)
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "server_groups_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			fmt.Sprintf("/v1/groups/%s", groupID),
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.GetServerGroupConfigurationPolicy(groupID)
+
+	if err != nil {
+		t.Fatalf("server groups get failed: %v", err)
+	}
+
+	if resp.Group.GroupID != groupID {
+		t.Errorf("expected group to have ID %s; got %s", groupID, resp.Group.GroupID)
+	}
+
+	if !reflect.DeepEqual(resp.Group.PolicyIDs, expectedIDs) {
+		t.Errorf("expected group to have policy IDs %v; got %v", expectedIDs, resp.Group.PolicyIDs)
+	}
+
+	if !reflect.DeepEqual(resp.Group.WindowsPolicyIDs, expectedWindowsIDs) {
+		t.Errorf("expected group to have windows policy IDs %v; got %v", expectedWindowsIDs, resp.Group.WindowsPolicyIDs)
+	}
+}
+
+func TestClient_UpdateServerGroupConfigurationPolicy(t *testing.T) {
+	var (
+		err       error
+		reqBody   = UpdateServerGroupConfigurationPolicyRequest{}
+		policyIDs = []string{"random-policy-id"}
+		groupID   = "group-id"
+	)
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodPut,
+			fmt.Sprintf("/v1/groups/%s", groupID),
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.UpdateServerGroupConfigurationPolicy(ServerGroupConfigurationPolicy{
+		GroupID:   groupID,
+		PolicyIDs: policyIDs,
+	})
+
+	if err != nil {
+		t.Fatalf("server group configuration policy update failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(reqBody.Group.PolicyIDs, policyIDs) {
+		t.Errorf("expected request to have policy IDs %v; got %v", policyIDs, reqBody.Group.PolicyIDs)
+	}
+
+	if reqBody.Group.WindowsPolicyIDs == nil || len(reqBody.Group.WindowsPolicyIDs) != 0 {
+		t.Errorf("expected request to have empty windows policy IDs; got %v", reqBody.Group.WindowsPolicyIDs)
+	}
+}
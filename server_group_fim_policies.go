@@ -0,0 +1,68 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ServerGroupFIMPolicy represents FIM policies for a CPHalo server group.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#object-representation-1
+type ServerGroupFIMPolicy struct {
+	GroupID             string   `json:"-"`
+	FIMPolicyIDs        []string `json:"fim_policy_ids"`
+	WindowsFIMPolicyIDs []string `json:"windows_fim_policy_ids"`
+}
+
+// GetServerGroupFIMPolicyResponse represents a CPHalo server group FIM policies get response.
+type GetServerGroupFIMPolicyResponse struct {
+	Group ServerGroupFIMPolicy `json:"group"`
+}
+
+// UpdateServerGroupFIMPolicyRequest represents a CPHalo server group FIM policies update request.
+type UpdateServerGroupFIMPolicyRequest = GetServerGroupFIMPolicyResponse
+
+// GetServerGroupFIMPolicy return information describing FIM policies for a single group.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#get-a-single-server-group
+func (c *Client) GetServerGroupFIMPolicy(ID string) (response GetServerGroupFIMPolicyResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "groups/"+ID, nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, err
+	}
+
+	response.Group.GroupID = ID
+
+	return response, nil
+}
+
+// UpdateServerGroupFIMPolicy updates FIM policies for a server group.
+//
+// Both Linux and Windows policy lists are replaced, nil lists unassign all policies.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#assign-fim-policies-to-the-server-group
+func (c *Client) UpdateServerGroupFIMPolicy(group ServerGroupFIMPolicy) error {
+	if group.FIMPolicyIDs == nil {
+		group.FIMPolicyIDs = []string{}
+	}
+	if group.WindowsFIMPolicyIDs == nil {
+		group.WindowsFIMPolicyIDs = []string{}
+	}
+
+	req, err := c.newRequest(http.MethodPut, "groups/"+group.GroupID, nil, UpdateServerGroupFIMPolicyRequest{Group: group})
+	if err != nil {
+		return fmt.Errorf("cannot create new update request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute update request: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestClient_GetServerGroupFIMPolicy(t *testing.T) {
+	var (
+		err                error
+		expectedIDs        = []string{"ab50af103f8211e5a1e7193241212e97", "9a14e404ae0011e5ac3b6f9396d4f39a"}
+		expectedWindowsIDs = []string{"0aea4620b3b60132962206ba9a9c633c"}
+		groupID            = "random-group-id"
+	)
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "server_groups_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			fmt.Sprintf("/v1/groups/%s", groupID),
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.GetServerGroupFIMPolicy(groupID)
+
+	if err != nil {
+		t.Fatalf("server groups get failed: %v", err)
+	}
+
+	if resp.Group.GroupID != groupID {
+		t.Errorf("expected group to have ID %s; got %s", groupID, resp.Group.GroupID)
+	}
+
+	if !reflect.DeepEqual(resp.Group.FIMPolicyIDs, expectedIDs) {
+		t.Errorf("expected group to have FIM policy IDs %v; got %v", expectedIDs, resp.Group.FIMPolicyIDs)
+	}
+
+	if !reflect.DeepEqual(resp.Group.WindowsFIMPolicyIDs, expectedWindowsIDs) {
+		t.Errorf("expected group to have windows FIM policy IDs %v; got %v", expectedWindowsIDs, resp.Group.WindowsFIMPolicyIDs)
+	}
+}
+
+func TestClient_UpdateServerGroupFIMPolicy(t *testing.T) {
+	var (
+		err       error
+		reqBody   = UpdateServerGroupFIMPolicyRequest{}
+		policyIDs = []string{"random-policy-id"}
+		groupID   = "group-id"
+	)
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodPut,
+			fmt.Sprintf("/v1/groups/%s", groupID),
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.UpdateServerGroupFIMPolicy(ServerGroupFIMPolicy{
+		GroupID:      groupID,
+		FIMPolicyIDs: policyIDs,
+	})
+
+	if err != nil {
+		t.Fatalf("server group FIM policy update failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(reqBody.Group.FIMPolicyIDs, policyIDs) {
+		t.Errorf("expected request to have FIM policy IDs %v; got %v", policyIDs, reqBody.Group.FIMPolicyIDs)
+	}
+
+	if reqBody.Group.WindowsFIMPolicyIDs == nil || len(reqBody.Group.WindowsFIMPolicyIDs) != 0 {
+		t.Errorf("expected request to have empty windows FIM policy IDs; got %v", reqBody.Group.WindowsFIMPolicyIDs)
+	}
+}
@@ -7,10 +7,14 @@ import (
 
 // ServerGroupFirewallPolicy represents firewall policies for a CPHalo server group.
 //
+// The Windows policy is sent only when set, so updating the Linux policy
+// keeps the assigned Windows one, see UnassignServerGroupWindowsFirewallPolicy.
+//
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#object-representation-1
 type ServerGroupFirewallPolicy struct {
-	GroupID               string
-	LinuxFirewallPolicyID NullableString `json:"linux_firewall_policy_id"`
+	GroupID                 string
+	LinuxFirewallPolicyID   NullableString `json:"linux_firewall_policy_id"`
+	WindowsFirewallPolicyID NullableString `json:"windows_firewall_policy_id,omitempty"`
 }
 
 // GetServerGroupFirewallPolicyResponse represents a CPHalo server group firewall policies get response.
@@ -21,6 +25,13 @@ type GetServerGroupFirewallPolicyResponse struct {
 // UpdateServerGroupFirewallPolicyRequest represents a CPHalo server group firewall policies update request.
 type UpdateServerGroupFirewallPolicyRequest = GetServerGroupFirewallPolicyResponse
 
+// UnassignServerGroupWindowsFirewallPolicyRequest represents a CPHalo server group Windows firewall policy unassign request.
+type UnassignServerGroupWindowsFirewallPolicyRequest struct {
+	Group struct {
+		WindowsFirewallPolicyID NullableString `json:"windows_firewall_policy_id"`
+	} `json:"group"`
+}
+
 // GetServerGroupFirewallPolicy return information describing firewall policies for a single group.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#get-a-single-server-group
@@ -56,3 +67,21 @@ func (c *Client) UpdateServerGroupFirewallPolicy(group ServerGroupFirewallPolicy
 
 	return nil
 }
+
+// UnassignServerGroupWindowsFirewallPolicy unassigns the Windows firewall
+// policy from a server group, the Linux one is kept.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#assign-a-firewall-policy-to-the-server-group
+func (c *Client) UnassignServerGroupWindowsFirewallPolicy(groupID string) error {
+	req, err := c.newRequest(http.MethodPut, "groups/"+groupID, nil, UnassignServerGroupWindowsFirewallPolicyRequest{})
+	if err != nil {
+		return fmt.Errorf("cannot create new update request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute update request: %v", err)
+	}
+
+	return nil
+}
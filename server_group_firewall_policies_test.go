@@ -1,6 +1,7 @@
 package cphalo
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -42,6 +43,10 @@ func TestClient_GetServerGroupFirewallPolicy(t *testing.T) {
 	if resp.Group.LinuxFirewallPolicyID != expectedID {
 		t.Errorf("expected group to have ID %s; got %s", expectedID, resp.Group.LinuxFirewallPolicyID)
 	}
+
+	if resp.Group.WindowsFirewallPolicyID != "291f1420abe7013295e406ba9a9c633c" {
+		t.Errorf("expected group to have windows policy ID 291f1420abe7013295e406ba9a9c633c; got %s", resp.Group.WindowsFirewallPolicyID)
+	}
 }
 
 func TestClient_UpdateServerGroupFirewallPolicy(t *testing.T) {
@@ -83,3 +88,62 @@ func TestClient_UpdateServerGroupFirewallPolicy(t *testing.T) {
 		t.Errorf("expected request to have linux firewall policy id %s; got %s", policyID, reqBody.Group.LinuxFirewallPolicyID)
 	}
 }
+
+func TestClient_UpdateServerGroupFirewallPolicy_LinuxOnly(t *testing.T) {
+	var err error
+	reqBody := map[string]map[string]json.RawMessage{}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"PUT /v1/groups/group-id": bodyRecorderTestHandler(t, jsonResponseTestHandler(t, "", http.StatusNoContent), &reqBody),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.UpdateServerGroupFirewallPolicy(ServerGroupFirewallPolicy{
+		GroupID:               "group-id",
+		LinuxFirewallPolicyID: "random-policy-id",
+	})
+
+	if err != nil {
+		t.Fatalf("server group firewall policy updating failed: %v", err)
+	}
+
+	if value, ok := reqBody["group"]["windows_firewall_policy_id"]; ok {
+		t.Errorf("expected request to keep windows firewall policy; got %q", value)
+	}
+}
+
+func TestClient_UnassignServerGroupWindowsFirewallPolicy(t *testing.T) {
+	var err error
+	reqBody := map[string]map[string]json.RawMessage{}
+
+	ts := httptest.NewServer(
+		routesTestHandler(t, map[string]http.Handler{
+			"PUT /v1/groups/group-id": bodyRecorderTestHandler(t, jsonResponseTestHandler(t, "", http.StatusNoContent), &reqBody),
+		}),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	if err := client.UnassignServerGroupWindowsFirewallPolicy("group-id"); err != nil {
+		t.Fatalf("server group windows firewall policy unassigning failed: %v", err)
+	}
+
+	if len(reqBody["group"]) != 1 || string(reqBody["group"]["windows_firewall_policy_id"]) != "null" {
+		t.Errorf("expected request to unassign only windows firewall policy; got %v", reqBody)
+	}
+}
@@ -0,0 +1,64 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ServerGroupLIDSPolicy represents LIDS policies for a CPHalo server group.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#object-representation-1
+type ServerGroupLIDSPolicy struct {
+	GroupID       string   `json:"-"`
+	LIDSPolicyIDs []string `json:"lids_policy_ids"`
+}
+
+// GetServerGroupLIDSPolicyResponse represents a CPHalo server group LIDS policies get response.
+type GetServerGroupLIDSPolicyResponse struct {
+	Group ServerGroupLIDSPolicy `json:"group"`
+}
+
+// UpdateServerGroupLIDSPolicyRequest represents a CPHalo server group LIDS policies update request.
+type UpdateServerGroupLIDSPolicyRequest = GetServerGroupLIDSPolicyResponse
+
+// GetServerGroupLIDSPolicy return information describing LIDS policies for a single group.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#get-a-single-server-group
+func (c *Client) GetServerGroupLIDSPolicy(ID string) (response GetServerGroupLIDSPolicyResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "groups/"+ID, nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, err
+	}
+
+	response.Group.GroupID = ID
+
+	return response, nil
+}
+
+// UpdateServerGroupLIDSPolicy updates LIDS policies for a server group.
+//
+// The policy list is replaced, nil list unassigns all policies.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#assign-lids-policies-to-the-server-group
+func (c *Client) UpdateServerGroupLIDSPolicy(group ServerGroupLIDSPolicy) error {
+	if group.LIDSPolicyIDs == nil {
+		group.LIDSPolicyIDs = []string{}
+	}
+
+	req, err := c.newRequest(http.MethodPut, "groups/"+group.GroupID, nil, UpdateServerGroupLIDSPolicyRequest{Group: group})
+	if err != nil {
+		return fmt.Errorf("cannot create new update request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute update request: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestClient_GetServerGroupLIDSPolicy(t *testing.T) {
+	var (
+		err         error
+		expectedIDs = []string{"f3541070d5820132c0df06bf4fdcb353"}
+		groupID     = "random-group-id"
+	)
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "server_groups_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			fmt.Sprintf("/v1/groups/%s", groupID),
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.GetServerGroupLIDSPolicy(groupID)
+
+	if err != nil {
+		t.Fatalf("server groups get failed: %v", err)
+	}
+
+	if resp.Group.GroupID != groupID {
+		t.Errorf("expected group to have ID %s; got %s", groupID, resp.Group.GroupID)
+	}
+
+	if !reflect.DeepEqual(resp.Group.LIDSPolicyIDs, expectedIDs) {
+		t.Errorf("expected group to have LIDS policy IDs %v; got %v", expectedIDs, resp.Group.LIDSPolicyIDs)
+	}
+
+}
+
+func TestClient_UpdateServerGroupLIDSPolicy(t *testing.T) {
+	var (
+		err       error
+		reqBody   = UpdateServerGroupLIDSPolicyRequest{}
+		policyIDs = []string{"random-policy-id"}
+		groupID   = "group-id"
+	)
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodPut,
+			fmt.Sprintf("/v1/groups/%s", groupID),
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.UpdateServerGroupLIDSPolicy(ServerGroupLIDSPolicy{
+		GroupID:       groupID,
+		LIDSPolicyIDs: policyIDs,
+	})
+
+	if err != nil {
+		t.Fatalf("server group LIDS policy update failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(reqBody.Group.LIDSPolicyIDs, policyIDs) {
+		t.Errorf("expected request to have LIDS policy IDs %v; got %v", policyIDs, reqBody.Group.LIDSPolicyIDs)
+	}
+
+	err = client.UpdateServerGroupLIDSPolicy(ServerGroupLIDSPolicy{GroupID: groupID})
+
+	if err != nil {
+		t.Fatalf("server group LIDS policy update failed: %v", err)
+	}
+
+	if reqBody.Group.LIDSPolicyIDs == nil || len(reqBody.Group.LIDSPolicyIDs) != 0 {
+		t.Errorf("expected request to have empty LIDS policy IDs; got %v", reqBody.Group.LIDSPolicyIDs)
+	}
+}
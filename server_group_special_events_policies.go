@@ -0,0 +1,62 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ServerGroupSpecialEventsPolicy represents special events policy for a CPHalo server group.
+//
+// CPHalo calls the assignment server_events_policy_id.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#object-representation-1
+type ServerGroupSpecialEventsPolicy struct {
+	GroupID               string         `json:"-"`
+	SpecialEventsPolicyID NullableString `json:"server_events_policy_id"`
+}
+
+// GetServerGroupSpecialEventsPolicyResponse represents a CPHalo server group special events policy get response.
+type GetServerGroupSpecialEventsPolicyResponse struct {
+	Group ServerGroupSpecialEventsPolicy `json:"group"`
+}
+
+// UpdateServerGroupSpecialEventsPolicyRequest represents a CPHalo server group special events policy update request.
+type UpdateServerGroupSpecialEventsPolicyRequest = GetServerGroupSpecialEventsPolicyResponse
+
+// GetServerGroupSpecialEventsPolicy return information describing special events policy for a single group.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#get-a-single-server-group
+func (c *Client) GetServerGroupSpecialEventsPolicy(ID string) (response GetServerGroupSpecialEventsPolicyResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "groups/"+ID, nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, err
+	}
+
+	response.Group.GroupID = ID
+
+	return response, nil
+}
+
+// UpdateServerGroupSpecialEventsPolicy updates special events policy for a server group.
+//
+// Empty SpecialEventsPolicyID unassigns the policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#assign-a-special-events-policy-to-the-server-group
+func (c *Client) UpdateServerGroupSpecialEventsPolicy(group ServerGroupSpecialEventsPolicy) error {
+	req, err := c.newRequest(http.MethodPut, "groups/"+group.GroupID, nil, UpdateServerGroupSpecialEventsPolicyRequest{Group: group})
+	if err != nil {
+		return fmt.Errorf("cannot create new update request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute update request: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClient_GetServerGroupSpecialEventsPolicy(t *testing.T) {
+	var (
+		err        error
+		expectedID = NullableString("a4be30d4c4a611e69bce7b2e5a1cd3f1")
+		groupID    = "random-group-id"
+	)
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "server_group_special_events_policies_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			fmt.Sprintf("/v1/groups/%s", groupID),
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.GetServerGroupSpecialEventsPolicy(groupID)
+
+	if err != nil {
+		t.Fatalf("server groups get failed: %v", err)
+	}
+
+	if resp.Group.SpecialEventsPolicyID != expectedID {
+		t.Errorf("expected group to have ID %s; got %s", expectedID, resp.Group.SpecialEventsPolicyID)
+	}
+}
+
+func TestClient_UpdateServerGroupSpecialEventsPolicy(t *testing.T) {
+	var (
+		err      error
+		reqBody  = UpdateServerGroupSpecialEventsPolicyRequest{}
+		policyID = NullableString("random-policy-id")
+		groupID  = "group-id"
+	)
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodPut,
+			fmt.Sprintf("/v1/groups/%s", groupID),
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.UpdateServerGroupSpecialEventsPolicy(ServerGroupSpecialEventsPolicy{
+		GroupID:               groupID,
+		SpecialEventsPolicyID: policyID,
+	})
+
+	if err != nil {
+		t.Fatalf("server group special events policy update failed: %v", err)
+	}
+
+	if reqBody.Group.SpecialEventsPolicyID != policyID {
+		t.Errorf("expected request to have special events policy id %s; got %s", policyID, reqBody.Group.SpecialEventsPolicyID)
+	}
+}
@@ -0,0 +1,281 @@
+package cphalo
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SkipServerGroupChildren is returned by the walk function to skip children
+// of the group being walked.
+var SkipServerGroupChildren = errors.New("skip server group children")
+
+// ServerGroupTree represent the hierarchy of CPHalo server groups.
+type ServerGroupTree struct {
+	Roots []*ServerGroupNode
+
+	nodes map[string]*ServerGroupNode
+}
+
+// ServerGroupNode represent a server group in the tree.
+type ServerGroupNode struct {
+	Group    ServerGroup
+	Parent   *ServerGroupNode
+	Children []*ServerGroupNode
+}
+
+// Path returns names of the group and its ancestors separated by slashes,
+// the same way as Server.GroupPath.
+func (n *ServerGroupNode) Path() string {
+	if n.Parent == nil {
+		return n.Group.Name
+	}
+
+	return n.Parent.Path() + "/" + n.Group.Name
+}
+
+// ServerGroupTree returns the tree of all server groups.
+func (c *Client) ServerGroupTree() (*ServerGroupTree, error) {
+	resp, err := c.ListServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list server groups: %v", err)
+	}
+
+	return NewServerGroupTree(resp.Groups), nil
+}
+
+// NewServerGroupTree builds the tree of the groups. Groups with parents
+// missing in the groups are roots. Children are ordered by name.
+func NewServerGroupTree(groups []ServerGroup) *ServerGroupTree {
+	t := &ServerGroupTree{nodes: map[string]*ServerGroupNode{}}
+
+	for _, g := range groups {
+		t.nodes[g.ID] = &ServerGroupNode{Group: g}
+	}
+
+	for _, g := range groups {
+		n := t.nodes[g.ID]
+		if parent, ok := t.nodes[g.ParentID]; ok && g.ParentID != g.ID {
+			n.Parent = parent
+			parent.Children = append(parent.Children, n)
+		} else {
+			t.Roots = append(t.Roots, n)
+		}
+	}
+
+	sortServerGroupNodes(t.Roots)
+	for _, n := range t.nodes {
+		sortServerGroupNodes(n.Children)
+	}
+
+	return t
+}
+
+func sortServerGroupNodes(nodes []*ServerGroupNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Group.Name != nodes[j].Group.Name {
+			return nodes[i].Group.Name < nodes[j].Group.Name
+		}
+		return nodes[i].Group.ID < nodes[j].Group.ID
+	})
+}
+
+// Walk calls fn for every group, parents before their children. Children
+// of a group are skipped when fn returns SkipServerGroupChildren, any other
+// error stops the walk and is returned.
+func (t *ServerGroupTree) Walk(fn func(node *ServerGroupNode) error) error {
+	return walkServerGroupNodes(t.Roots, fn)
+}
+
+func walkServerGroupNodes(nodes []*ServerGroupNode, fn func(node *ServerGroupNode) error) error {
+	for _, n := range nodes {
+		err := fn(n)
+		if err == SkipServerGroupChildren {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := walkServerGroupNodes(n.Children, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Group returns the group with the ID.
+func (t *ServerGroupTree) Group(ID string) (*ServerGroupNode, bool) {
+	n, ok := t.nodes[ID]
+	return n, ok
+}
+
+// Lookup returns the group at the path, such as "example.com/appservers".
+// The first of sibling groups with the same name is returned.
+func (t *ServerGroupTree) Lookup(path string) (*ServerGroupNode, bool) {
+	nodes := t.Roots
+	var found *ServerGroupNode
+
+	for _, name := range splitServerGroupPath(path) {
+		found = nil
+		for _, n := range nodes {
+			if n.Group.Name == name {
+				found = n
+				break
+			}
+		}
+
+		if found == nil {
+			return nil, false
+		}
+		nodes = found.Children
+	}
+
+	return found, found != nil
+}
+
+// Ancestors returns ancestors of the group from its root to its parent.
+func (t *ServerGroupTree) Ancestors(ID string) []ServerGroup {
+	n, ok := t.nodes[ID]
+	if !ok {
+		return nil
+	}
+
+	var ancestors []ServerGroup
+	for p := n.Parent; p != nil; p = p.Parent {
+		ancestors = append([]ServerGroup{p.Group}, ancestors...)
+	}
+
+	return ancestors
+}
+
+// Descendants returns all descendants of the group, parents before their children.
+func (t *ServerGroupTree) Descendants(ID string) []ServerGroup {
+	n, ok := t.nodes[ID]
+	if !ok {
+		return nil
+	}
+
+	var descendants []ServerGroup
+	_ = walkServerGroupNodes(n.Children, func(node *ServerGroupNode) error {
+		descendants = append(descendants, node.Group)
+		return nil
+	})
+
+	return descendants
+}
+
+func splitServerGroupPath(path string) []string {
+	var names []string
+	for _, name := range strings.Split(path, "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// EnsureGroupPath returns the group at the path, creating the missing groups
+// along the path like mkdir -p. The root group has to exist.
+func (c *Client) EnsureGroupPath(path string) (group ServerGroup, err error) {
+	names := splitServerGroupPath(path)
+	if len(names) == 0 {
+		return group, fmt.Errorf("empty server group path")
+	}
+
+	tree, err := c.ServerGroupTree()
+	if err != nil {
+		return group, err
+	}
+
+	root, ok := tree.Lookup(names[0])
+	if !ok {
+		return group, fmt.Errorf("root server group %s not found", names[0])
+	}
+
+	node := root
+	for i, name := range names[1:] {
+		var child *ServerGroupNode
+		for _, n := range node.Children {
+			if n.Group.Name == name {
+				child = n
+				break
+			}
+		}
+
+		if child == nil {
+			resp, err := c.CreateServerGroup(ServerGroup{Name: name, ParentID: node.Group.ID})
+			if err != nil {
+				return group, fmt.Errorf("cannot create server group %s: %v", strings.Join(names[:i+2], "/"), err)
+			}
+			child = &ServerGroupNode{Group: resp.Group, Parent: node}
+		}
+
+		node = child
+	}
+
+	return node.Group, nil
+}
+
+// DeleteServerGroupRecursive deletes the server group with all its descendants,
+// children before their parents.
+//
+// Servers of the deleted groups are moved to the group with moveToID first,
+// or retired when moveToID is empty. The group to move to cannot be deleted.
+func (c *Client) DeleteServerGroupRecursive(ID, moveToID string) error {
+	tree, err := c.ServerGroupTree()
+	if err != nil {
+		return err
+	}
+
+	n, ok := tree.Group(ID)
+	if !ok {
+		return fmt.Errorf("server group %s not found", ID)
+	}
+
+	deleted := map[string]bool{ID: true}
+	for _, g := range tree.Descendants(ID) {
+		deleted[g.ID] = true
+	}
+
+	if deleted[moveToID] {
+		return fmt.Errorf("cannot move servers to server group %s, which is deleted", moveToID)
+	}
+
+	servers, err := c.ListServerGroupMembers(ID, ListServerGroupMembersOptions{Descendants: true})
+	if err != nil {
+		return fmt.Errorf("cannot list servers of server group %s: %v", ID, err)
+	}
+
+	for _, s := range servers.Servers {
+		if moveToID != "" {
+			if err := c.MoveServer(s.ID, moveToID); err != nil {
+				return fmt.Errorf("cannot move server %s: %v", s.ID, err)
+			}
+			continue
+		}
+
+		if err := c.RetireServer(s.ID); err != nil {
+			return fmt.Errorf("cannot retire server %s: %v", s.ID, err)
+		}
+	}
+
+	return c.deleteServerGroupNode(n)
+}
+
+func (c *Client) deleteServerGroupNode(n *ServerGroupNode) error {
+	for _, child := range n.Children {
+		if err := c.deleteServerGroupNode(child); err != nil {
+			return err
+		}
+	}
+
+	if err := c.DeleteServerGroup(n.Group.ID); err != nil {
+		return fmt.Errorf("cannot delete server group %s: %v", n.Path(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,278 @@
+package cphalo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testServerGroups returns groups forming the tree:
+//
+//	example.com
+//	├── appservers
+//	│   ├── api
+//	│   └── web
+//	│       └── canary
+//	└── db
+func testServerGroups() []ServerGroup {
+	return []ServerGroup{
+		{ID: "web", Name: "web", ParentID: "app"},
+		{ID: "root", Name: "example.com"},
+		{ID: "db", Name: "db", ParentID: "root"},
+		{ID: "canary", Name: "canary", ParentID: "web"},
+		{ID: "app", Name: "appservers", ParentID: "root"},
+		{ID: "api", Name: "api", ParentID: "app"},
+	}
+}
+
+func TestNewServerGroupTree(t *testing.T) {
+	tree := NewServerGroupTree(testServerGroups())
+
+	var paths []string
+	err := tree.Walk(func(n *ServerGroupNode) error {
+		paths = append(paths, n.Path())
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+
+	expected := []string{
+		"example.com",
+		"example.com/appservers",
+		"example.com/appservers/api",
+		"example.com/appservers/web",
+		"example.com/appservers/web/canary",
+		"example.com/db",
+	}
+
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected paths %v; got %v", expected, paths)
+	}
+
+	paths = nil
+	_ = tree.Walk(func(n *ServerGroupNode) error {
+		paths = append(paths, n.Group.ID)
+		if n.Group.ID == "app" {
+			return SkipServerGroupChildren
+		}
+		return nil
+	})
+
+	if expected := []string{"root", "app", "db"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected children of app to be skipped; got %v", paths)
+	}
+}
+
+func TestServerGroupTree_Lookup(t *testing.T) {
+	tree := NewServerGroupTree(testServerGroups())
+
+	if n, ok := tree.Lookup("example.com/appservers/web"); !ok || n.Group.ID != "web" {
+		t.Errorf("expected web group; got %+v", n)
+	}
+
+	if n, ok := tree.Lookup("/example.com/db/"); !ok || n.Group.ID != "db" {
+		t.Errorf("expected db group; got %+v", n)
+	}
+
+	for _, path := range []string{"", "example.com/web", "appservers"} {
+		if n, ok := tree.Lookup(path); ok {
+			t.Errorf("expected %q not to be found; got %+v", path, n)
+		}
+	}
+
+	if n, ok := tree.Group("canary"); !ok || n.Path() != "example.com/appservers/web/canary" {
+		t.Errorf("expected canary group; got %+v", n)
+	}
+}
+
+func TestServerGroupTree_AncestorsDescendants(t *testing.T) {
+	tree := NewServerGroupTree(testServerGroups())
+
+	ids := func(groups []ServerGroup) []string {
+		var ids []string
+		for _, g := range groups {
+			ids = append(ids, g.ID)
+		}
+		return ids
+	}
+
+	if ancestors := ids(tree.Ancestors("canary")); !reflect.DeepEqual(ancestors, []string{"root", "app", "web"}) {
+		t.Errorf("expected ancestors root, app, web; got %v", ancestors)
+	}
+
+	if ancestors := tree.Ancestors("root"); len(ancestors) != 0 {
+		t.Errorf("expected no ancestors of root; got %v", ancestors)
+	}
+
+	if descendants := ids(tree.Descendants("app")); !reflect.DeepEqual(descendants, []string{"api", "web", "canary"}) {
+		t.Errorf("expected descendants api, web, canary; got %v", descendants)
+	}
+
+	if descendants := tree.Descendants("missing"); descendants != nil {
+		t.Errorf("expected no descendants of missing group; got %v", descendants)
+	}
+}
+
+// serverGroupTreeTestServer keeps groups and servers, recording the writes.
+type serverGroupTreeTestServer struct {
+	t       *testing.T
+	groups  []ServerGroup
+	servers []Server
+	writes  []string
+}
+
+func (s *serverGroupTreeTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := r.Method + " " + r.RequestURI
+
+	switch {
+	case route == "GET /v1/groups":
+		_ = json.NewEncoder(w).Encode(ListServerGroupsResponse{Count: len(s.groups), Groups: s.groups})
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/servers") && r.URL.Query().Get("descendants") == "true":
+		tree := NewServerGroupTree(s.groups)
+		groupID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/groups/"), "/servers")
+		members := map[string]bool{groupID: true}
+		for _, g := range tree.Descendants(groupID) {
+			members[g.ID] = true
+		}
+
+		var resp ListServersResponse
+		for _, server := range s.servers {
+			if members[server.GroupID] {
+				resp.Servers = append(resp.Servers, server)
+			}
+		}
+		resp.Count = len(resp.Servers)
+		_ = json.NewEncoder(w).Encode(resp)
+	case route == "POST /v1/groups":
+		var req CreateServerGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.t.Fatalf("unmarshalling body failed: %v", err)
+		}
+		req.Group.ID = "new-" + req.Group.Name
+		s.groups = append(s.groups, req.Group)
+		s.writes = append(s.writes, "create "+req.Group.Name+" in "+req.Group.ParentID)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(req)
+	case r.Method == http.MethodPut && strings.HasPrefix(r.RequestURI, "/v1/servers/"):
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			s.t.Fatalf("reading body failed: %v", err)
+		}
+		s.writes = append(s.writes, "update "+strings.TrimPrefix(r.RequestURI, "/v1/servers/")+" "+string(b))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.RequestURI, "/v1/groups/"):
+		s.writes = append(s.writes, "delete "+strings.TrimPrefix(r.RequestURI, "/v1/groups/"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.t.Errorf("unexpected request %s", route)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func serverGroupTreeTestClient(t *testing.T) (*Client, *serverGroupTreeTestServer, func()) {
+	s := &serverGroupTreeTestServer{
+		t:      t,
+		groups: testServerGroups(),
+		servers: []Server{
+			{ID: "s1", GroupID: "web"},
+			{ID: "s2", GroupID: "db"},
+			{ID: "s3", GroupID: "canary"},
+		},
+	}
+	ts := httptest.NewServer(authTestHandler(s, t))
+
+	var err error
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	return client, s, ts.Close
+}
+
+func TestClient_EnsureGroupPath(t *testing.T) {
+	client, s, done := serverGroupTreeTestClient(t)
+	defer done()
+
+	group, err := client.EnsureGroupPath("example.com/appservers/web")
+
+	if err != nil {
+		t.Fatalf("cannot ensure existing path: %v", err)
+	}
+
+	if group.ID != "web" || len(s.writes) != 0 {
+		t.Errorf("expected existing group without writes; got %+v, %v", group, s.writes)
+	}
+
+	group, err = client.EnsureGroupPath("example.com/appservers/web/blue/v2")
+
+	if err != nil {
+		t.Fatalf("cannot ensure path: %v", err)
+	}
+
+	if group.ID != "new-v2" {
+		t.Errorf("expected created group new-v2; got %+v", group)
+	}
+
+	if expected := []string{"create blue in web", "create v2 in new-blue"}; !reflect.DeepEqual(s.writes, expected) {
+		t.Errorf("expected writes %v; got %v", expected, s.writes)
+	}
+
+	if _, err := client.EnsureGroupPath("other.com/appservers"); err == nil {
+		t.Errorf("expected error for missing root group")
+	}
+}
+
+func TestClient_DeleteServerGroupRecursive(t *testing.T) {
+	client, s, done := serverGroupTreeTestClient(t)
+	defer done()
+
+	if err := client.DeleteServerGroupRecursive("web", "db"); err != nil {
+		t.Fatalf("cannot delete group: %v", err)
+	}
+
+	expected := []string{
+		`update s1 {"server":{"group_id":"db"}}`,
+		`update s3 {"server":{"group_id":"db"}}`,
+		"delete canary",
+		"delete web",
+	}
+
+	if !reflect.DeepEqual(s.writes, expected) {
+		t.Errorf("expected writes %v; got %v", expected, s.writes)
+	}
+
+	s.writes = nil
+
+	if err := client.DeleteServerGroupRecursive("app", ""); err != nil {
+		t.Fatalf("cannot delete group: %v", err)
+	}
+
+	expected = []string{
+		`update s1 {"server":{"retire":true}}`,
+		`update s3 {"server":{"retire":true}}`,
+		"delete api",
+		"delete canary",
+		"delete web",
+		"delete app",
+	}
+
+	if !reflect.DeepEqual(s.writes, expected) {
+		t.Errorf("expected writes %v; got %v", expected, s.writes)
+	}
+
+	s.writes = nil
+
+	if err := client.DeleteServerGroupRecursive("app", "canary"); err == nil || len(s.writes) != 0 {
+		t.Errorf("expected error moving servers into deleted group; got %v, %v", err, s.writes)
+	}
+}
@@ -3,6 +3,7 @@ package cphalo
 import (
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // ServerGroup represent a CPHalo server group.
@@ -39,6 +40,14 @@ type CreateServerGroupRequest = GetServerGroupResponse
 // UpdateServerGroupRequest represent a CPHalo server group update request.
 type UpdateServerGroupRequest = GetServerGroupResponse
 
+// ListServerGroupMembersOptions represent filters of server group members.
+type ListServerGroupMembersOptions struct {
+	// Descendants includes servers of all descendant groups.
+	Descendants bool
+	// States filters servers by states, see ServerState constants.
+	States []string
+}
+
 // ListServerGroups lists all server groups.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#list-server-groups
@@ -73,6 +82,31 @@ func (c *Client) GetServerGroup(ID string) (response GetServerGroupResponse, err
 	return response, nil
 }
 
+// ListServerGroupMembers lists servers of the server group.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#list-servers-in-a-group
+func (c *Client) ListServerGroupMembers(ID string, opts ListServerGroupMembersOptions) (response ListServersResponse, err error) {
+	params := map[string]string{}
+	if opts.Descendants {
+		params["descendants"] = "true"
+	}
+	if len(opts.States) > 0 {
+		params["state"] = strings.Join(opts.States, ",")
+	}
+
+	req, err := c.newRequest(http.MethodGet, "groups/"+ID+"/servers", params, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute request: %v", err)
+	}
+
+	return response, nil
+}
+
 // CreateServerGroup creates new server group.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#create-a-new-server-group
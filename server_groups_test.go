@@ -49,6 +49,58 @@ func TestClient_ListServerGroups(t *testing.T) {
 	}
 }
 
+func TestClient_ListServerGroupMembers(t *testing.T) {
+	tests := []struct {
+		opts ListServerGroupMembersOptions
+		uri  string
+	}{
+		{ListServerGroupMembersOptions{}, "/v1/groups/de218efef44f11e6bb816d29f4f59c91/servers"},
+		{ListServerGroupMembersOptions{Descendants: true}, "/v1/groups/de218efef44f11e6bb816d29f4f59c91/servers?descendants=true"},
+		{
+			ListServerGroupMembersOptions{Descendants: true, States: []string{ServerStateActive, ServerStateMissing}},
+			"/v1/groups/de218efef44f11e6bb816d29f4f59c91/servers?descendants=true&state=active%2Cmissing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			var err error
+
+			ts := httptest.NewServer(
+				requestValidatorTestHandler(
+					jsonResponseTestHandler(t, "servers_list", http.StatusOK),
+					t,
+					http.MethodGet,
+					tt.uri,
+					nil,
+				),
+			)
+			defer ts.Close()
+
+			client := NewClient("", "", nil)
+			client.baseURL, err = url.Parse(ts.URL)
+
+			if err != nil {
+				t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+			}
+
+			resp, err := client.ListServerGroupMembers("de218efef44f11e6bb816d29f4f59c91", tt.opts)
+
+			if err != nil {
+				t.Fatalf("server group members list failed: %v", err)
+			}
+
+			if resp.Count != 1 || len(resp.Servers) != 1 {
+				t.Fatalf("expected 1 server; got %d", len(resp.Servers))
+			}
+
+			if resp.Servers[0].GroupPath != "example.com/appservers" {
+				t.Errorf("expected server in group example.com/appservers; got %s", resp.Servers[0].GroupPath)
+			}
+		})
+	}
+}
+
 func TestClient_GetServerGroup(t *testing.T) {
 	var err error
 	expectedID := "0962bfa087bc01323e360670140ec224"
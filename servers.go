@@ -3,6 +3,7 @@ package cphalo
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -30,11 +31,23 @@ type Server struct {
 	ConnectingIPFQDN       string    `json:"connecting_ip_fqdn"`
 	LastStateChange        time.Time `json:"last_state_change"`
 	DockerInspection       string    `json:"docker_inspection"`
+	CSPProvider            string    `json:"csp_provider"`
+	CSPAccountID           string    `json:"csp_account_id"`
+	CSPInstanceID          string    `json:"csp_instance_id"`
+	CSPRegion              string    `json:"csp_region"`
 	GroupID                string    `json:"group_id"`
 	GroupName              string    `json:"group_name"`
 	GroupPath              string    `json:"group_path"`
 }
 
+// Server states, which servers can be filtered by.
+const (
+	ServerStateActive      = "active"
+	ServerStateMissing     = "missing"
+	ServerStateDeactivated = "deactivated"
+	ServerStateRetired     = "retired"
+)
+
 // ListServersResponse represent a CPHalo server list response.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#list-servers
@@ -68,11 +81,31 @@ type MoveServerRequest struct {
 	} `json:"server"`
 }
 
-// ListServers lists all servers.
+// ListServersOptions represent filters of servers.
+type ListServersOptions struct {
+	// States filters servers by states, see ServerState constants. CPHalo
+	// lists only active servers when empty.
+	States []string
+}
+
+// ListServers lists all servers. CPHalo lists only active servers, servers
+// in other states are listed by ListServersWithOptions.
 //
 // CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#list-servers
 func (c *Client) ListServers() (response ListServersResponse, err error) {
-	req, err := c.newRequest(http.MethodGet, "servers", nil, nil)
+	return c.ListServersWithOptions(ListServersOptions{})
+}
+
+// ListServersWithOptions lists servers filtered by the options.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#list-servers
+func (c *Client) ListServersWithOptions(opts ListServersOptions) (response ListServersResponse, err error) {
+	params := map[string]string{}
+	if len(opts.States) > 0 {
+		params["state"] = strings.Join(opts.States, ",")
+	}
+
+	req, err := c.newRequest(http.MethodGet, "servers", params, nil)
 	if err != nil {
 		return response, fmt.Errorf("cannot create new request: %v", err)
 	}
@@ -49,6 +49,50 @@ func TestClient_ListServers(t *testing.T) {
 	}
 }
 
+func TestClient_ListServersWithOptions(t *testing.T) {
+	tests := []struct {
+		opts ListServersOptions
+		uri  string
+	}{
+		{ListServersOptions{}, "/v1/servers"},
+		{ListServersOptions{States: []string{ServerStateMissing, ServerStateDeactivated}}, "/v1/servers?state=missing%2Cdeactivated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			var err error
+
+			ts := httptest.NewServer(
+				requestValidatorTestHandler(
+					jsonResponseTestHandler(t, "servers_list", http.StatusOK),
+					t,
+					http.MethodGet,
+					tt.uri,
+					nil,
+				),
+			)
+			defer ts.Close()
+
+			client := NewClient("", "", nil)
+			client.baseURL, err = url.Parse(ts.URL)
+
+			if err != nil {
+				t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+			}
+
+			resp, err := client.ListServersWithOptions(tt.opts)
+
+			if err != nil {
+				t.Fatalf("servers list failed: %v", err)
+			}
+
+			if len(resp.Servers) != 1 {
+				t.Errorf("expected 1 server; got %d", len(resp.Servers))
+			}
+		})
+	}
+}
+
 func TestClient_GetServer(t *testing.T) {
 	var err error
 	expectedID := "3958fe0c08e511e7819335b35e8ba368"
@@ -85,6 +129,10 @@ func TestClient_GetServer(t *testing.T) {
 	if resp.Server.GroupID != expectedSGID {
 		t.Errorf("expected server to be in ServerGroupID %s; got %s", expectedID, expectedSGID)
 	}
+
+	if resp.Server.CSPProvider != "aws_ec2" || resp.Server.CSPAccountID != "550529930677" || resp.Server.CSPInstanceID != "i-02d3196d963de03ab" {
+		t.Errorf("expected server in aws_ec2 account 550529930677; got %+v", resp.Server)
+	}
 }
 
 func TestClient_DeleteServer(t *testing.T) {
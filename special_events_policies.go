@@ -0,0 +1,133 @@
+package cphalo
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// SpecialEvent represent a CPHalo special event and how it is reported.
+type SpecialEvent struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Active   bool   `json:"active"`
+	Critical bool   `json:"critical"`
+	Alert    bool   `json:"alert"`
+}
+
+// SpecialEventsPolicy represent a CPHalo special events policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#special-events-policies
+type SpecialEventsPolicy struct {
+	ID          string         `json:"id,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Platform    string         `json:"platform,omitempty"`
+	Shared      StringableBool `json:"shared"`
+	Events      []SpecialEvent `json:"special_events,omitempty"`
+}
+
+// ListSpecialEventsPoliciesResponse represent a list of special events policies response.
+type ListSpecialEventsPoliciesResponse struct {
+	Count    int                   `json:"count"`
+	Policies []SpecialEventsPolicy `json:"special_events_policies"`
+}
+
+// GetSpecialEventsPolicyResponse represent a get special events policy response.
+type GetSpecialEventsPolicyResponse struct {
+	Policy SpecialEventsPolicy `json:"special_events_policy"`
+}
+
+// CreateSpecialEventsPolicyResponse represent a create special events policy response.
+type CreateSpecialEventsPolicyResponse = GetSpecialEventsPolicyResponse
+
+// CreateSpecialEventsPolicyRequest represent a create special events policy request.
+type CreateSpecialEventsPolicyRequest = GetSpecialEventsPolicyResponse
+
+// UpdateSpecialEventsPolicyRequest represent a update special events policy request.
+type UpdateSpecialEventsPolicyRequest = GetSpecialEventsPolicyResponse
+
+// ListSpecialEventsPolicies lists all special events policies.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#list-special-events-policies
+func (c *Client) ListSpecialEventsPolicies() (response ListSpecialEventsPoliciesResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "special_events_policies", nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute request: %v", err)
+	}
+
+	return response, nil
+}
+
+// GetSpecialEventsPolicy returns details of the special events policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#get-special-events-policy-details
+func (c *Client) GetSpecialEventsPolicy(ID string) (response GetSpecialEventsPolicyResponse, err error) {
+	req, err := c.newRequest(http.MethodGet, "special_events_policies/"+ID, nil, nil)
+	if err != nil {
+		return response, fmt.Errorf("cannot create new request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+// CreateSpecialEventsPolicy creates a new special events policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#create-special-events-policy
+func (c *Client) CreateSpecialEventsPolicy(policy SpecialEventsPolicy) (response CreateSpecialEventsPolicyResponse, err error) {
+	req, err := c.newRequest(http.MethodPost, "special_events_policies", nil, CreateSpecialEventsPolicyRequest{Policy: policy})
+	if err != nil {
+		return response, fmt.Errorf("cannot create new create request: %v", err)
+	}
+
+	_, err = c.Do(req, &response)
+	if err != nil {
+		return response, fmt.Errorf("cannot execute create request: %v", err)
+	}
+
+	return response, nil
+}
+
+// UpdateSpecialEventsPolicy updates special events policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#update-special-events-policy
+func (c *Client) UpdateSpecialEventsPolicy(policy SpecialEventsPolicy) error {
+	req, err := c.newRequest(http.MethodPut, "special_events_policies/"+policy.ID, nil, UpdateSpecialEventsPolicyRequest{Policy: policy})
+	if err != nil {
+		return fmt.Errorf("cannot create new update request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute update request: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteSpecialEventsPolicy deletes a special events policy.
+//
+// CPHalo API Docs: https://library.cloudpassage.com/help/article/link/cloudpassage-api-documentation#delete-special-events-policy
+func (c *Client) DeleteSpecialEventsPolicy(ID string) error {
+	req, err := c.newRequest(http.MethodDelete, "special_events_policies/"+ID, nil, nil)
+	if err != nil {
+		return fmt.Errorf("cannot create new delete request: %v", err)
+	}
+
+	_, err = c.Do(req, nil)
+	if err != nil {
+		return fmt.Errorf("cannot execute delete request: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package cphalo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestClient_ListSpecialEventsPolicies(t *testing.T) {
+	var err error
+	expectedResults := 2
+	expectedID := "0972b4f087bc01323e360670140ec224"
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "special_events_policies_list", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/special_events_policies",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.ListSpecialEventsPolicies()
+
+	if err != nil {
+		t.Fatalf("special events policies list failed: %v", err)
+	}
+
+	if resp.Count != expectedResults {
+		t.Errorf("expected count to be %d; got %d", expectedResults, resp.Count)
+	}
+
+	if len(resp.Policies) != expectedResults {
+		t.Errorf("expected %d special events policies; got %d", expectedResults, len(resp.Policies))
+	}
+
+	if resp.Policies[0].ID != expectedID {
+		t.Errorf("expected special events policy 0 to have ID %s; got %s", expectedID, resp.Policies[0].ID)
+	}
+}
+
+func TestClient_GetSpecialEventsPolicy(t *testing.T) {
+	var err error
+	expectedID := "0972b4f087bc01323e360670140ec224"
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "special_events_policies_get", http.StatusOK),
+			t,
+			http.MethodGet,
+			"/v1/special_events_policies/id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	resp, err := client.GetSpecialEventsPolicy("id")
+
+	if err != nil {
+		t.Fatalf("special events policy get failed: %v", err)
+	}
+
+	if resp.Policy.ID != expectedID {
+		t.Errorf("expected special events policy to have ID %s; got %s", expectedID, resp.Policy.ID)
+	}
+
+	if len(resp.Policy.Events) != 2 {
+		t.Fatalf("expected special events policy to have 2 events; got %d", len(resp.Policy.Events))
+	}
+
+	if resp.Policy.Events[0].Type != "server_missing" {
+		t.Errorf("expected event 0 to have Type=server_missing; got %s", resp.Policy.Events[0].Type)
+	}
+
+	if !resp.Policy.Events[0].Alert {
+		t.Errorf("expected event 0 to alert")
+	}
+}
+
+func TestClient_CreateSpecialEventsPolicy(t *testing.T) {
+	var err error
+	reqBody := CreateSpecialEventsPolicyRequest{}
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "special_events_policies_get", http.StatusCreated),
+			t,
+			http.MethodPost,
+			"/v1/special_events_policies",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	policy := SpecialEventsPolicy{
+		Name:     "hello",
+		Platform: "linux",
+		Events: []SpecialEvent{
+			{Name: "Server missing", Type: "server_missing", Active: true, Critical: true, Alert: true},
+		},
+	}
+
+	resp, err := client.CreateSpecialEventsPolicy(policy)
+
+	if err != nil {
+		t.Fatalf("special events policy creating failed: %v", err)
+	}
+
+	expectedID := "0972b4f087bc01323e360670140ec224"
+	if resp.Policy.ID != expectedID {
+		t.Errorf("expected response to contain ID=%s; got %s", expectedID, resp.Policy.ID)
+	}
+
+	if len(reqBody.Policy.Events) != 1 {
+		t.Fatalf("expected request to contain 1 event; got %d", len(reqBody.Policy.Events))
+	}
+
+	if reqBody.Policy.Events[0] != policy.Events[0] {
+		t.Errorf("expected request to contain event %+v; got %+v", policy.Events[0], reqBody.Policy.Events[0])
+	}
+}
+
+func TestClient_UpdateSpecialEventsPolicy(t *testing.T) {
+	var err error
+	reqBody := UpdateSpecialEventsPolicyRequest{}
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodPut,
+			"/v1/special_events_policies/id",
+			&reqBody,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	policy := SpecialEventsPolicy{ID: "id", Name: "hello", Description: "hai"}
+
+	err = client.UpdateSpecialEventsPolicy(policy)
+
+	if err != nil {
+		t.Fatalf("special events policy updating failed: %v", err)
+	}
+
+	if reqBody.Policy.Description != policy.Description {
+		t.Errorf("expected request to contain Description=%s; got %s", policy.Description, reqBody.Policy.Description)
+	}
+}
+
+func TestClient_DeleteSpecialEventsPolicy(t *testing.T) {
+	var err error
+
+	ts := httptest.NewServer(
+		requestValidatorTestHandler(
+			jsonResponseTestHandler(t, "", http.StatusNoContent),
+			t,
+			http.MethodDelete,
+			"/v1/special_events_policies/id",
+			nil,
+		),
+	)
+	defer ts.Close()
+
+	client := NewClient("", "", nil)
+	client.baseURL, err = url.Parse(ts.URL)
+
+	if err != nil {
+		t.Fatalf("cannot parse url %s: %v", ts.URL, err)
+	}
+
+	err = client.DeleteSpecialEventsPolicy("id")
+
+	if err != nil {
+		t.Fatalf("special events policy deletion failed: %v", err)
+	}
+}